@@ -0,0 +1,15 @@
+module github.com/sawdustofmind/adv-sync/cmd/ordermutexcheck
+
+go 1.24.4
+
+require (
+	github.com/sawdustofmind/adv-sync/pkg/ordermutex/ordermutexcheck v0.0.0
+	golang.org/x/tools v0.38.0
+)
+
+require (
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/sync v0.18.0 // indirect
+)
+
+replace github.com/sawdustofmind/adv-sync/pkg/ordermutex/ordermutexcheck => ../../pkg/ordermutex/ordermutexcheck
@@ -0,0 +1,11 @@
+// Command ordermutexcheck runs the ordermutexcheck analyzer standalone
+// or as a vet tool: go vet -vettool=$(which ordermutexcheck) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex/ordermutexcheck"
+)
+
+func main() { singlechecker.Main(ordermutexcheck.Analyzer) }
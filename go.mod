@@ -2,4 +2,9 @@ module github.com/sawdustofmind/adv-sync
 
 go 1.24.4
 
-require go.uber.org/atomic v1.11.0
+require (
+	go.uber.org/atomic v1.11.0
+	golang.org/x/sys v0.38.0
+)
+
+require github.com/stretchr/testify v1.11.1 // indirect
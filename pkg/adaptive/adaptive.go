@@ -0,0 +1,263 @@
+// Package adaptive provides a concurrency limiter whose in-flight limit
+// follows observed latency, in the style of Netflix's concurrency-limits.
+package adaptive
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Sample is the outcome of one request, fed to an Algorithm on Release.
+type Sample struct {
+	// RTT is how long the request held its slot.
+	RTT time.Duration
+	// InFlight is the number of requests in flight when it started.
+	InFlight int
+	// Dropped reports that the request failed from overload (timed out,
+	// was rejected downstream), which every algorithm treats as congestion.
+	Dropped bool
+}
+
+// Algorithm computes a new limit from the current one and a sample.
+// It is called with the limiter's lock held, one sample at a time.
+type Algorithm interface {
+	Update(limit float64, s Sample) float64
+}
+
+// Config configures a Limiter. Zero fields take the defaults.
+type Config struct {
+	// Initial is the starting limit; default 20.
+	Initial int
+	// Min and Max clamp the limit; default 1 and 1000.
+	Min, Max int
+	// Algorithm adjusts the limit; default AIMD{}.
+	Algorithm Algorithm
+	// OnLimitChange, if set, is called after the limit changes, outside the
+	// limiter's lock but in order.
+	OnLimitChange func(old, new int)
+}
+
+// Limiter admits up to its current limit of requests at once, queueing the
+// rest in arrival order. Every Acquire must be paired with one Release.
+type Limiter struct {
+	min, max int
+	alg      Algorithm
+	onChange func(old, new int)
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	waiters  list.List // of *waiter
+
+	notifyMu sync.Mutex // keeps OnLimitChange calls in order
+}
+
+type waiter struct {
+	ready    chan struct{} // closed when granted
+	inflight int
+}
+
+// Token is a granted slot, handed back with Release.
+type Token struct {
+	start    time.Time
+	inflight int
+}
+
+// New returns a limiter configured by cfg.
+func New(cfg Config) *Limiter {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 1000
+	}
+	if cfg.Initial <= 0 {
+		cfg.Initial = 20
+	}
+	if cfg.Min > cfg.Max {
+		panic("adaptive: Config.Min above Config.Max")
+	}
+	if cfg.Algorithm == nil {
+		cfg.Algorithm = AIMD{}
+	}
+	return &Limiter{
+		min:      cfg.Min,
+		max:      cfg.Max,
+		alg:      cfg.Algorithm,
+		onChange: cfg.OnLimitChange,
+		limit:    float64(min(max(cfg.Initial, cfg.Min), cfg.Max)),
+	}
+}
+
+// Acquire waits for a slot, behind earlier callers, until one is free or
+// ctx is done. On failure it returns ctx.Err().
+func (l *Limiter) Acquire(ctx context.Context) (Token, error) {
+	l.mu.Lock()
+	if l.waiters.Len() == 0 && l.inflight < l.cap() {
+		t := l.grant()
+		l.mu.Unlock()
+		return t, nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	e := l.waiters.PushBack(w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return Token{start: time.Now(), inflight: w.inflight}, nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	select {
+	case <-w.ready:
+		// Granted while giving up: pass the slot on.
+		l.inflight--
+	default:
+		l.waiters.Remove(e)
+	}
+	l.wake()
+	l.mu.Unlock()
+	return Token{}, ctx.Err()
+}
+
+// TryAcquire takes a slot if one is free and nobody is waiting.
+func (l *Limiter) TryAcquire() (Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.waiters.Len() == 0 && l.inflight < l.cap() {
+		return l.grant(), true
+	}
+	return Token{}, false
+}
+
+// Release frees t's slot and feeds its latency to the algorithm. dropped
+// marks a request that failed from overload.
+func (l *Limiter) Release(t Token, dropped bool) {
+	s := Sample{RTT: time.Since(t.start), InFlight: t.inflight, Dropped: dropped}
+
+	l.mu.Lock()
+	if l.inflight <= 0 {
+		l.mu.Unlock()
+		panic("adaptive: Release without Acquire")
+	}
+	l.inflight--
+	old := l.cap()
+	l.limit = min(max(l.alg.Update(l.limit, s), float64(l.min)), float64(l.max))
+	cur := l.cap()
+	l.wake()
+	if cur != old && l.onChange != nil {
+		// Take notifyMu before dropping mu so callbacks run in change order.
+		l.notifyMu.Lock()
+		l.mu.Unlock()
+		l.onChange(old, cur)
+		l.notifyMu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+}
+
+// grant counts a new request in flight; mu must be held.
+func (l *Limiter) grant() Token {
+	l.inflight++
+	return Token{start: time.Now(), inflight: l.inflight}
+}
+
+// wake grants waiters from the front while slots are free; mu must be held.
+func (l *Limiter) wake() {
+	for l.inflight < l.cap() {
+		e := l.waiters.Front()
+		if e == nil {
+			return
+		}
+		l.waiters.Remove(e)
+		l.inflight++
+		w := e.Value.(*waiter)
+		w.inflight = l.inflight
+		close(w.ready)
+	}
+}
+
+// cap returns the whole limit; mu must be held.
+func (l *Limiter) cap() int { return int(l.limit) }
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of slots currently held.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// AIMD grows the limit by one per successful sample while the limiter is at
+// least half used, and multiplies it by Backoff on a drop or a sample slower
+// than Timeout.
+type AIMD struct {
+	// Backoff is the decrease factor, in (0, 1); default 0.9.
+	Backoff float64
+	// Timeout, if positive, counts slower samples as drops.
+	Timeout time.Duration
+}
+
+func (a AIMD) Update(limit float64, s Sample) float64 {
+	if s.Dropped || a.Timeout > 0 && s.RTT > a.Timeout {
+		backoff := a.Backoff
+		if backoff <= 0 || backoff >= 1 {
+			backoff = 0.9
+		}
+		return limit * backoff
+	}
+	if float64(s.InFlight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// Vegas estimates the queue building up downstream from how far latency
+// rises above the lowest latency seen, and keeps it between Alpha and Beta
+// requests (scaled by log10 of the limit). A Vegas is stateful: use a new
+// one per Limiter.
+type Vegas struct {
+	// Alpha and Beta bound the estimated queue; default 3 and 6.
+	Alpha, Beta float64
+
+	minRTT time.Duration
+}
+
+func (v *Vegas) Update(limit float64, s Sample) float64 {
+	if s.RTT > 0 && (v.minRTT == 0 || s.RTT < v.minRTT) {
+		v.minRTT = s.RTT
+	}
+	step := max(math.Log10(limit), 1)
+	if s.Dropped {
+		return limit - step
+	}
+	if float64(s.InFlight)*2 < limit || v.minRTT == 0 || s.RTT <= 0 {
+		// Not loaded enough to tell.
+		return limit
+	}
+	alpha, beta := v.Alpha, v.Beta
+	if alpha <= 0 {
+		alpha = 3
+	}
+	if beta <= alpha {
+		beta = 2 * alpha
+	}
+	queue := limit * (1 - float64(v.minRTT)/float64(s.RTT))
+	switch {
+	case queue < alpha*step:
+		return limit + step
+	case queue > beta*step:
+		return limit - step
+	}
+	return limit
+}
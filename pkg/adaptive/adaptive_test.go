@@ -0,0 +1,92 @@
+package adaptive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+	var changes []string
+	l := New(Config{Initial: 4, Max: 6, OnLimitChange: func(old, new int) {
+		changes = append(changes, fmt.Sprintf("%d->%d", old, new))
+	}})
+
+	// Fully used, every success grows the limit up to Max.
+	for i := 0; i < 4; i++ {
+		var toks []Token
+		for j := 0; j < l.Limit(); j++ {
+			tok, ok := l.TryAcquire()
+			if !ok {
+				t.Fatalf("TryAcquire %d under limit %d failed", j, l.Limit())
+			}
+			toks = append(toks, tok)
+		}
+		if _, ok := l.TryAcquire(); ok {
+			t.Fatal("TryAcquire over the limit")
+		}
+		for _, tok := range toks {
+			l.Release(tok, false)
+		}
+	}
+	if l.Limit() != 6 {
+		t.Fatalf("limit %d, want 6", l.Limit())
+	}
+
+	tok, _ := l.TryAcquire()
+	l.Release(tok, true)
+	if l.Limit() != 5 {
+		t.Fatalf("limit after drop %d, want 5", l.Limit())
+	}
+	if got := fmt.Sprint(changes); got != "[4->5 5->6 6->5]" {
+		t.Fatalf("changes %s", got)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	l := New(Config{Initial: 1, Max: 1})
+	ctx := context.Background()
+	held, _ := l.Acquire(ctx)
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire over the limit = %v", err)
+	}
+
+	got := make(chan Token)
+	go func() {
+		tok, err := l.Acquire(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- tok
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatal("TryAcquire jumped the queue")
+	}
+	l.Release(held, false)
+	l.Release(<-got, false)
+	if l.InFlight() != 0 {
+		t.Fatalf("%d in flight", l.InFlight())
+	}
+}
+
+func TestVegas(t *testing.T) {
+	v := &Vegas{}
+	base := 10 * time.Millisecond
+	limit := v.Update(20, Sample{RTT: base, InFlight: 20})
+	if limit <= 20 {
+		t.Fatalf("no queueing, limit %v; want growth", limit)
+	}
+	// Latency doubled: about half the in-flight requests are queued.
+	if l := v.Update(limit, Sample{RTT: 2 * base, InFlight: 20}); l >= limit {
+		t.Fatalf("queueing, limit %v -> %v; want shrink", limit, l)
+	}
+	if l := v.Update(limit, Sample{RTT: base, InFlight: 2}); l != limit {
+		t.Fatalf("underused, limit %v -> %v; want unchanged", limit, l)
+	}
+}
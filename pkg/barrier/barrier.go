@@ -0,0 +1,118 @@
+// Package barrier provides a reusable barrier for a fixed number of parties.
+package barrier
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBroken is returned by Await when another party left the barrier, its
+// action failed, or it was Reset while parties were waiting.
+var ErrBroken = errors.New("barrier: broken")
+
+// Barrier makes a fixed number of parties wait for each other; once all have
+// arrived it optionally runs an action on the last arriver, releases them all
+// and resets for the next phase.
+//
+// If a party leaves early (its context is done) the current phase breaks:
+// every party waiting in it, and every party arriving later, gets ErrBroken
+// until Reset is called.
+type Barrier struct {
+	parties int
+	action  func() error
+
+	mu  sync.Mutex
+	gen *generation
+}
+
+type generation struct {
+	arrived int
+	done    chan struct{} // closed when the phase trips or breaks
+	broken  bool
+}
+
+// New returns a barrier for parties parties. A non-nil action runs on the last
+// party to arrive, before any is released; if it returns an error the phase
+// breaks and that party gets the error.
+func New(parties int, action func() error) *Barrier {
+	if parties <= 0 {
+		panic("barrier: New called with non-positive parties")
+	}
+	return &Barrier{parties: parties, action: action, gen: newGeneration()}
+}
+
+func newGeneration() *generation {
+	return &generation{done: make(chan struct{})}
+}
+
+// Await waits until all parties have arrived. It returns the party's arrival
+// index, parties-1 for the first to arrive down to 0 for the last.
+func (b *Barrier) Await(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	g := b.gen
+	if g.broken {
+		b.mu.Unlock()
+		return 0, ErrBroken
+	}
+	g.arrived++
+	index := b.parties - g.arrived
+
+	if index == 0 {
+		if b.action != nil {
+			if err := b.action(); err != nil {
+				b.breakLocked()
+				b.mu.Unlock()
+				return 0, err
+			}
+		}
+		close(g.done)
+		b.gen = newGeneration()
+		b.mu.Unlock()
+		return 0, nil
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-g.done:
+	case <-ctx.Done():
+		b.mu.Lock()
+		if b.gen == g && !g.broken {
+			b.breakLocked()
+			b.mu.Unlock()
+			return index, ctx.Err()
+		}
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if g.broken {
+		return index, ErrBroken
+	}
+	return index, nil
+}
+
+// Reset breaks the current phase, if any party is waiting in it, and starts a fresh one.
+func (b *Barrier) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.gen.arrived > 0 && !b.gen.broken {
+		b.breakLocked()
+	}
+	b.gen = newGeneration()
+}
+
+// Broken reports whether the current phase is broken.
+func (b *Barrier) Broken() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gen.broken
+}
+
+// breakLocked breaks the current phase and releases its waiters; mu must be held.
+func (b *Barrier) breakLocked() {
+	b.gen.broken = true
+	close(b.gen.done)
+}
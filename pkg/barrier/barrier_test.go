@@ -0,0 +1,136 @@
+package barrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	const parties, phases = 4, 5
+	var (
+		trips atomic.Int32
+		phase [parties]int
+	)
+	b := New(parties, func() error {
+		trips.Add(1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for p := 0; p < parties; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < phases; i++ {
+				phase[p] = i
+				if _, err := b.Await(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				// Every party finished phase i before anyone goes on.
+				for q := range phase {
+					if q != p && phase[q] < i {
+						t.Errorf("party %d in phase %d while %d is in %d", q, phase[q], p, i)
+					}
+				}
+				if _, err := b.Await(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if n := trips.Load(); n != 2*phases {
+		t.Fatalf("action ran %d times, want %d", n, 2*phases)
+	}
+}
+
+func TestBroken(t *testing.T) {
+	b := New(3, nil)
+
+	waited := make(chan error)
+	go func() {
+		_, err := b.Await(context.Background())
+		waited <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.Await(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("leaving party got %v", err)
+	}
+	if err := <-waited; !errors.Is(err, ErrBroken) {
+		t.Fatalf("waiting party got %v, want ErrBroken", err)
+	}
+	if _, err := b.Await(context.Background()); !errors.Is(err, ErrBroken) || !b.Broken() {
+		t.Fatalf("late party got %v, want ErrBroken", err)
+	}
+
+	b.Reset()
+	if b.Broken() {
+		t.Fatal("still broken after Reset")
+	}
+}
+
+func TestActionError(t *testing.T) {
+	errAction := errors.New("action failed")
+	b := New(2, func() error { return errAction })
+
+	waited := make(chan error)
+	go func() {
+		_, err := b.Await(context.Background())
+		waited <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := b.Await(context.Background()); !errors.Is(err, errAction) {
+		t.Fatalf("last party got %v", err)
+	}
+	if err := <-waited; !errors.Is(err, ErrBroken) {
+		t.Fatalf("waiting party got %v", err)
+	}
+}
+
+func TestPhaser(t *testing.T) {
+	var advanced []string
+	p := NewPhaser(0, func(phase, parties int) bool {
+		advanced = append(advanced, fmt.Sprintf("%d:%d", phase, parties))
+		return parties == 0
+	})
+	ctx := context.Background()
+
+	// Parties stay for 1, 2 and 2 phases, then leave during the next one.
+	var wg sync.WaitGroup
+	for _, phases := range []int{1, 2, 2} {
+		if _, err := p.Register(); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(phases int) {
+			defer wg.Done()
+			for i := 0; i < phases; i++ {
+				if _, err := p.ArriveAndAwaitAdvance(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if _, err := p.ArriveAndDeregister(); err != nil {
+				t.Error(err)
+			}
+		}(phases)
+	}
+	wg.Wait()
+
+	if got := fmt.Sprint(advanced); got != "[0:3 1:2 2:0]" {
+		t.Fatalf("advanced %s, want [0:3 1:2 2:0]", got)
+	}
+	if _, err := p.Register(); !errors.Is(err, ErrTerminated) {
+		t.Fatalf("Register after termination = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package barrier
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTerminated is returned by Phaser methods once the phaser has terminated.
+var ErrTerminated = errors.New("barrier: phaser terminated")
+
+// Phaser is a reusable barrier whose number of parties can change between
+// and during phases. A phase advances once every registered party has arrived;
+// parties may arrive without waiting, or deregister as they arrive.
+type Phaser struct {
+	onAdvance func(phase, parties int) (terminate bool)
+
+	mu         sync.Mutex
+	phase      int
+	parties    int
+	arrived    int
+	done       chan struct{} // closed when the current phase advances
+	terminated bool
+}
+
+// NewPhaser returns a phaser with parties registered parties. A non-nil
+// onAdvance runs on the last arriver of each phase, before waiters are
+// released, with the phase number and the parties registered for the next
+// phase; returning true terminates the phaser. Without it, the phaser
+// terminates when the last party deregisters.
+func NewPhaser(parties int, onAdvance func(phase, parties int) (terminate bool)) *Phaser {
+	if parties < 0 {
+		panic("barrier: NewPhaser called with negative parties")
+	}
+	return &Phaser{onAdvance: onAdvance, parties: parties, done: make(chan struct{})}
+}
+
+// Register adds a party, which takes part starting with the current phase,
+// and returns that phase.
+func (p *Phaser) Register() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.terminated {
+		return 0, ErrTerminated
+	}
+	p.parties++
+	return p.phase, nil
+}
+
+// Arrive records a party's arrival at the current phase without waiting for
+// the others and returns the phase arrived at.
+func (p *Phaser) Arrive() (int, error) {
+	return p.arrive(false)
+}
+
+// ArriveAndDeregister records a party's arrival and removes it from later phases.
+func (p *Phaser) ArriveAndDeregister() (int, error) {
+	return p.arrive(true)
+}
+
+// ArriveAndAwaitAdvance arrives and waits for the others, returning the new phase.
+func (p *Phaser) ArriveAndAwaitAdvance(ctx context.Context) (int, error) {
+	phase, err := p.Arrive()
+	if err != nil {
+		return phase, err
+	}
+	return p.AwaitAdvance(ctx, phase)
+}
+
+// AwaitAdvance waits until phase is over and returns the phase after it;
+// if the current phase is already past phase, it returns at once.
+// Waiting through the final phase of a terminating phaser succeeds.
+func (p *Phaser) AwaitAdvance(ctx context.Context, phase int) (int, error) {
+	p.mu.Lock()
+	if p.phase != phase {
+		cur := p.phase
+		p.mu.Unlock()
+		return cur, nil
+	}
+	if p.terminated {
+		p.mu.Unlock()
+		return phase, ErrTerminated
+	}
+	done := p.done
+	p.mu.Unlock()
+
+	select {
+	case <-done:
+		return phase + 1, nil
+	case <-ctx.Done():
+		return phase, ctx.Err()
+	}
+}
+
+// Phase returns the current phase number.
+func (p *Phaser) Phase() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phase
+}
+
+// Parties returns the number of registered parties.
+func (p *Phaser) Parties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parties
+}
+
+func (p *Phaser) arrive(deregister bool) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.terminated {
+		return p.phase, ErrTerminated
+	}
+	if p.arrived == p.parties {
+		panic("barrier: more Phaser arrivals than registered parties")
+	}
+	phase := p.phase
+	if deregister {
+		p.parties--
+	} else {
+		p.arrived++
+	}
+	if p.arrived == p.parties {
+		p.advance()
+	}
+	return phase, nil
+}
+
+// advance ends the current phase; mu must be held.
+func (p *Phaser) advance() {
+	var terminate bool
+	if p.onAdvance != nil {
+		terminate = p.onAdvance(p.phase, p.parties)
+	} else {
+		terminate = p.parties == 0
+	}
+	p.phase++
+	p.arrived = 0
+	close(p.done)
+	p.done = make(chan struct{})
+	p.terminated = terminate
+}
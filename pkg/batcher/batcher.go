@@ -0,0 +1,131 @@
+// Package batcher groups items into batches by size or age and delivers the
+// processed batches in order.
+package batcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Config configures a Batcher. Zero fields take the defaults.
+type Config struct {
+	// Size cuts a batch once it holds this many items; default 100.
+	Size int
+	// Interval cuts a non-empty batch this long after its first item;
+	// zero waits for Size.
+	Interval time.Duration
+	// Workers caps the batches in flight, being processed or awaiting
+	// delivery; Add blocks while the cap is reached. Default 1.
+	Workers int
+}
+
+// Batcher collects items from concurrent producers into batches. Each batch
+// is passed to process, batches possibly overlapping, and the results go to
+// sink one at a time in the order the batches were cut: every batch takes an
+// ordermutex ticket when it is cut and locks it to deliver.
+type Batcher[T, R any] struct {
+	cfg     Config
+	process func([]T) R
+	sink    func(R)
+
+	m     *ordermutex.Mutex
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	batch  []T
+	timer  *time.Timer
+	gen    uint64 // identifies the current batch for its timer
+	closed bool
+}
+
+// New returns a batcher feeding process and then sink.
+func New[T, R any](cfg Config, process func([]T) R, sink func(R)) *Batcher[T, R] {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	return &Batcher[T, R]{
+		cfg:     cfg,
+		process: process,
+		sink:    sink,
+		m:       ordermutex.NewMutex(),
+		slots:   make(chan struct{}, cfg.Workers),
+	}
+}
+
+// Add appends v to the current batch. It panics after Close.
+func (b *Batcher[T, R]) Add(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		panic("batcher: Add after Close")
+	}
+	b.batch = append(b.batch, v)
+	if len(b.batch) >= b.cfg.Size {
+		b.cut()
+		return
+	}
+	if len(b.batch) == 1 && b.cfg.Interval > 0 {
+		gen := b.gen
+		b.timer = time.AfterFunc(b.cfg.Interval, func() { b.expire(gen) })
+	}
+}
+
+func (b *Batcher[T, R]) expire(gen uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen == b.gen && len(b.batch) > 0 {
+		b.cut()
+	}
+}
+
+// Flush cuts the current batch, if any, without waiting for it to be delivered.
+func (b *Batcher[T, R]) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.batch) > 0 {
+		b.cut()
+	}
+}
+
+// cut sends the current batch off; mu must be held, so batches are cut and
+// ticketed one at a time. It blocks while Workers batches are in flight.
+func (b *Batcher[T, R]) cut() {
+	batch := b.batch
+	b.batch = nil
+	b.gen++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	b.slots <- struct{}{}
+	t := b.m.GetTicket()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		r := b.process(batch)
+		b.m.Lock(t)
+		b.sink(r)
+		b.m.Unlock(t)
+		<-b.slots
+	}()
+}
+
+// Close cuts the last batch and waits until every batch has been delivered.
+func (b *Batcher[T, R]) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		if len(b.batch) > 0 {
+			b.cut()
+		}
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
@@ -0,0 +1,77 @@
+package batcher
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	var (
+		delivered [][]int
+		next      int
+	)
+	b := New(Config{Size: 10, Workers: 4}, func(batch []int) []int {
+		// Later batches often finish processing first.
+		time.Sleep(time.Duration(rand.IntN(2000)) * time.Microsecond)
+		return batch
+	}, func(batch []int) {
+		delivered = append(delivered, batch)
+	})
+
+	var mu sync.Mutex // makes the value sequence match the Add order
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				mu.Lock()
+				b.Add(next)
+				next++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	b.Close()
+
+	want := 0
+	for _, batch := range delivered {
+		if len(batch) != 10 {
+			t.Fatalf("batch of %d", len(batch))
+		}
+		for _, v := range batch {
+			if v != want {
+				t.Fatalf("got %d, want %d", v, want)
+			}
+			want++
+		}
+	}
+	if want != 200 {
+		t.Fatalf("delivered %d items", want)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	got := make(chan int, 4)
+	b := New(Config{Size: 100, Interval: 10 * time.Millisecond}, func(batch []int) int { return len(batch) },
+		func(n int) { got <- n })
+	b.Add(1)
+	b.Add(2)
+	select {
+	case n := <-got:
+		if n != 2 {
+			t.Fatalf("batch of %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partial batch never flushed")
+	}
+
+	b.Add(3)
+	b.Close()
+	if n := <-got; n != 1 {
+		t.Fatalf("final batch of %d", n)
+	}
+}
@@ -0,0 +1,266 @@
+// Package breaker provides a circuit breaker with sliding-window failure
+// accounting and ordered half-open probes.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/semaphore"
+)
+
+// ErrOpen is returned while the breaker rejects calls.
+var ErrOpen = errors.New("breaker: open")
+
+// State is a breaker state.
+type State int
+
+const (
+	// Closed lets every call through and counts failures.
+	Closed State = iota
+	// Open rejects every call until OpenTimeout passes.
+	Open
+	// HalfOpen lets a few probe calls through, in arrival order, to decide
+	// whether to close again.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Config configures a Breaker. Zero fields take the defaults.
+type Config struct {
+	// Window is the span of recent calls the failure ratio is computed over,
+	// kept in Buckets slices; defaults 10s and 10.
+	Window  time.Duration
+	Buckets int
+	// MinRequests is how many calls the window must hold before the breaker
+	// may trip; default 10.
+	MinRequests int
+	// FailureRatio trips the breaker when reached; default 0.5.
+	FailureRatio float64
+	// OpenTimeout is how long the breaker stays open before probing; default 5s.
+	OpenTimeout time.Duration
+	// Probes is how many probe calls run at once while half-open, and how
+	// many must succeed to close; default 1.
+	Probes int
+	// OnStateChange, if set, is called after each transition, outside the
+	// breaker's lock but in order.
+	OnStateChange func(from, to State)
+}
+
+// Breaker is a concurrency-safe circuit breaker.
+type Breaker struct {
+	cfg Config
+
+	mu       sync.Mutex
+	state    State
+	gen      uint64 // bumped on every transition; stale results are ignored
+	window   window
+	openedAt time.Time
+	probes   *semaphore.Semaphore
+	passed   int // successful probes this half-open period
+
+	notifyMu sync.Mutex // keeps OnStateChange calls in order
+}
+
+// New returns a closed breaker configured by cfg.
+func New(cfg Config) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 10
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = 1
+	}
+	b := &Breaker{cfg: cfg}
+	b.window.init(cfg.Window, cfg.Buckets)
+	return b
+}
+
+// Allow admits a call, or returns ErrOpen. While half-open, callers beyond
+// the probe limit wait their turn in arrival order until ctx is done. The
+// caller must report the outcome through done.
+func (b *Breaker) Allow(ctx context.Context) (done func(success bool), err error) {
+	b.mu.Lock()
+	now := time.Now()
+	if b.state == Open && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.unlockNotify(b.setState(HalfOpen, now), HalfOpen)
+		b.mu.Lock()
+	}
+	switch b.state {
+	case Closed:
+		gen := b.gen
+		b.mu.Unlock()
+		return func(success bool) { b.report(gen, success, false) }, nil
+	case Open:
+		b.mu.Unlock()
+		return nil, ErrOpen
+	}
+
+	gen, probes := b.gen, b.probes
+	b.mu.Unlock()
+	if err := probes.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	stale := b.gen != gen
+	b.mu.Unlock()
+	if stale {
+		// The probes before us decided meanwhile; start over.
+		probes.Release(1)
+		return b.Allow(ctx)
+	}
+	return func(success bool) {
+		probes.Release(1)
+		b.report(gen, success, true)
+	}, nil
+}
+
+// Do runs fn if the breaker allows it and records its outcome.
+func (b *Breaker) Do(ctx context.Context, fn func() error) error {
+	done, err := b.Allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+func (b *Breaker) report(gen uint64, success, probe bool) {
+	b.mu.Lock()
+	if gen != b.gen {
+		b.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	var from State
+	changed := false
+	switch {
+	case probe && !success:
+		from, changed = b.setState(Open, now), true
+	case probe:
+		b.passed++
+		if b.passed >= b.cfg.Probes {
+			from, changed = b.setState(Closed, now), true
+		}
+	default:
+		b.window.add(now, success)
+		if s, f := b.window.counts(now); s+f >= b.cfg.MinRequests && float64(f)/float64(s+f) >= b.cfg.FailureRatio {
+			from, changed = b.setState(Open, now), true
+		}
+	}
+	if !changed {
+		b.mu.Unlock()
+		return
+	}
+	b.unlockNotify(from, b.state)
+}
+
+// unlockNotify releases mu and reports a transition; mu must be held.
+func (b *Breaker) unlockNotify(from, to State) {
+	if b.cfg.OnStateChange == nil {
+		b.mu.Unlock()
+		return
+	}
+	// Take notifyMu before dropping mu so callbacks run in transition order.
+	b.notifyMu.Lock()
+	b.mu.Unlock()
+	b.cfg.OnStateChange(from, to)
+	b.notifyMu.Unlock()
+}
+
+// setState moves to s and returns the previous state; mu must be held.
+func (b *Breaker) setState(s State, now time.Time) State {
+	from := b.state
+	b.state = s
+	b.gen++
+	switch s {
+	case Open:
+		b.openedAt = now
+	case HalfOpen:
+		b.probes = semaphore.New(int64(b.cfg.Probes))
+		b.passed = 0
+	case Closed:
+		b.window.reset()
+	}
+	return from
+}
+
+// State returns the current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// window counts outcomes in a ring of time buckets.
+type window struct {
+	width   time.Duration
+	buckets []bucket
+}
+
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+func (w *window) init(span time.Duration, n int) {
+	w.width = span / time.Duration(n)
+	w.buckets = make([]bucket, n)
+}
+
+func (w *window) add(now time.Time, success bool) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[int(start.UnixNano()/int64(w.width))%len(w.buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	if success {
+		b.successes++
+	} else {
+		b.failures++
+	}
+}
+
+func (w *window) counts(now time.Time) (successes, failures int) {
+	oldest := now.Truncate(w.width).Add(-w.width * time.Duration(len(w.buckets)-1))
+	for _, b := range w.buckets {
+		if !b.start.Before(oldest) {
+			successes += b.successes
+			failures += b.failures
+		}
+	}
+	return successes, failures
+}
+
+func (w *window) reset() {
+	clear(w.buckets)
+}
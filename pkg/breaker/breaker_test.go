@@ -0,0 +1,114 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrip(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		transitions []string
+	)
+	b := New(Config{MinRequests: 4, OpenTimeout: 20 * time.Millisecond, OnStateChange: func(from, to State) {
+		mu.Lock()
+		transitions = append(transitions, fmt.Sprintf("%v->%v", from, to))
+		mu.Unlock()
+	}})
+	ctx := context.Background()
+	fail := errors.New("fail")
+
+	for _, err := range []error{nil, fail, nil, fail} {
+		b.Do(ctx, func() error { return err })
+	}
+	if b.State() != Open {
+		t.Fatalf("state %v after 50%% failures", b.State())
+	}
+	if err := b.Do(ctx, func() error { return nil }); err != ErrOpen {
+		t.Fatalf("Do while open = %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after the open timeout", b.State())
+	}
+	b.Do(ctx, func() error { return fail }) // failed probe reopens
+	if b.State() != Open {
+		t.Fatalf("state %v after a failed probe", b.State())
+	}
+	time.Sleep(25 * time.Millisecond)
+	b.Do(ctx, func() error { return nil })
+	if b.State() != Closed {
+		t.Fatalf("state %v after a good probe", b.State())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "[closed->open open->half-open half-open->open open->half-open half-open->closed]"
+	if fmt.Sprint(transitions) != want {
+		t.Fatalf("transitions %v", transitions)
+	}
+}
+
+func TestProbeOrder(t *testing.T) {
+	b := New(Config{MinRequests: 1, OpenTimeout: time.Millisecond, Probes: 1})
+	ctx := context.Background()
+	b.Do(ctx, func() error { return errors.New("fail") })
+	time.Sleep(2 * time.Millisecond)
+
+	// The first caller probes; the rest queue behind it and go through in
+	// order once it closes the breaker.
+	probe, err := b.Allow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := b.Allow(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			done(true)
+		}()
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err := b.Allow(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Allow behind a probe = %v", err)
+	}
+	probe(true)
+	wg.Wait()
+	if fmt.Sprint(order) != "[0 1 2]" || b.State() != Closed {
+		t.Fatalf("order %v, state %v", order, b.State())
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := New(Config{Window: 20 * time.Millisecond, Buckets: 2, MinRequests: 3})
+	ctx := context.Background()
+	b.Do(ctx, func() error { return errors.New("fail") })
+	b.Do(ctx, func() error { return errors.New("fail") })
+	time.Sleep(30 * time.Millisecond)
+	// The old failures slid out of the window, or this would trip it.
+	b.Do(ctx, func() error { return nil })
+	if b.State() != Closed {
+		t.Fatalf("state %v", b.State())
+	}
+}
@@ -0,0 +1,132 @@
+// Package bulkhead isolates dependencies by giving each its own bounded
+// share of concurrency.
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/semaphore"
+)
+
+// ErrFull is returned when a compartment is running and queueing at capacity.
+var ErrFull = errors.New("bulkhead: compartment full")
+
+// Limits bound one compartment.
+type Limits struct {
+	// MaxConcurrent caps the calls running at once; it must be positive.
+	MaxConcurrent int
+	// MaxQueue caps the calls waiting for a slot; beyond it calls fail with
+	// ErrFull. Zero rejects as soon as every slot is taken.
+	MaxQueue int
+}
+
+// Bulkhead is a set of named compartments. A compartment is created with
+// the default limits the first time it is used, unless configured before.
+type Bulkhead struct {
+	defaults Limits
+
+	mu    sync.Mutex
+	comps map[string]*compartment
+}
+
+type compartment struct {
+	limits Limits
+	sem    *semaphore.Semaphore // FIFO, so queued calls run in arrival order
+
+	mu      sync.Mutex
+	running int
+	queued  int
+}
+
+// New returns a bulkhead whose compartments default to defaults.
+func New(defaults Limits) *Bulkhead {
+	if defaults.MaxConcurrent <= 0 {
+		panic("bulkhead: New called with non-positive MaxConcurrent")
+	}
+	return &Bulkhead{defaults: defaults, comps: make(map[string]*compartment)}
+}
+
+// Configure sets name's limits. It panics if name is already in use.
+func (b *Bulkhead) Configure(name string, l Limits) {
+	if l.MaxConcurrent <= 0 {
+		panic("bulkhead: Configure called with non-positive MaxConcurrent")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.comps[name]; ok {
+		panic("bulkhead: Configure called for compartment " + name + " already in use")
+	}
+	b.comps[name] = newCompartment(l)
+}
+
+func newCompartment(l Limits) *compartment {
+	return &compartment{limits: l, sem: semaphore.New(int64(l.MaxConcurrent))}
+}
+
+func (b *Bulkhead) get(name string) *compartment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.comps[name]
+	if !ok {
+		c = newCompartment(b.defaults)
+		b.comps[name] = c
+	}
+	return c
+}
+
+// Acquire takes a slot in name, queueing for one if the queue has room,
+// until ctx is done. The caller must call release when finished.
+func (b *Bulkhead) Acquire(ctx context.Context, name string) (release func(), err error) {
+	c := b.get(name)
+	c.mu.Lock()
+	if c.sem.TryAcquire(1) {
+		c.running++
+		c.mu.Unlock()
+		return c.release, nil
+	}
+	if c.queued >= c.limits.MaxQueue {
+		c.mu.Unlock()
+		return nil, ErrFull
+	}
+	c.queued++
+	c.mu.Unlock()
+
+	err = c.sem.Acquire(ctx, 1)
+	c.mu.Lock()
+	c.queued--
+	if err == nil {
+		c.running++
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return c.release, nil
+}
+
+func (c *compartment) release() {
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	c.sem.Release(1)
+}
+
+// Do runs fn in a slot of name.
+func (b *Bulkhead) Do(ctx context.Context, name string, fn func() error) error {
+	release, err := b.Acquire(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Stats returns how many calls are running and queued in name.
+func (b *Bulkhead) Stats(name string) (running, queued int) {
+	c := b.get(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running, c.queued
+}
@@ -0,0 +1,60 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsolation(t *testing.T) {
+	b := New(Limits{MaxConcurrent: 1})
+	b.Configure("slow", Limits{MaxConcurrent: 2, MaxQueue: 1})
+	ctx := context.Background()
+
+	// Fill slow: two running, one queued.
+	r1, _ := b.Acquire(ctx, "slow")
+	r2, _ := b.Acquire(ctx, "slow")
+	queued := make(chan error)
+	go func() {
+		release, err := b.Acquire(ctx, "slow")
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+	for _, q := b.Stats("slow"); q == 0; _, q = b.Stats("slow") {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.Acquire(ctx, "slow"); err != ErrFull {
+		t.Fatalf("Acquire past the queue = %v", err)
+	}
+
+	// Other compartments are unaffected.
+	if err := b.Do(ctx, "fast", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	r1()
+	if err := <-queued; err != nil {
+		t.Fatal(err)
+	}
+	r2()
+	if running, queued := b.Stats("slow"); running != 0 || queued != 0 {
+		t.Fatalf("running %d queued %d", running, queued)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	b := New(Limits{MaxConcurrent: 1, MaxQueue: 4})
+	release, _ := b.Acquire(context.Background(), "x")
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := b.Acquire(ctx, "x"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("queued Acquire = %v", err)
+	}
+	if _, queued := b.Stats("x"); queued != 0 {
+		t.Fatalf("%d still queued", queued)
+	}
+}
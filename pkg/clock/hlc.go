@@ -0,0 +1,144 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrClockSkew is returned by HLC.Update for a remote timestamp too far ahead
+// of the local wall clock.
+var ErrClockSkew = errors.New("clock: remote timestamp beyond max skew")
+
+// Timestamp is a hybrid logical clock timestamp: wall time in milliseconds
+// since the Unix epoch in the high 48 bits and a logical counter in the low
+// 16. Timestamps order as plain integers, so they can serve directly as
+// ticket IDs.
+type Timestamp uint64
+
+const logicalBits = 16
+
+// NewTimestamp returns the timestamp for wall milliseconds and a logical count.
+func NewTimestamp(wall int64, logical uint16) Timestamp {
+	return Timestamp(uint64(wall)<<logicalBits | uint64(logical))
+}
+
+// Wall returns the wall time part in milliseconds since the Unix epoch.
+func (t Timestamp) Wall() int64 { return int64(t >> logicalBits) }
+
+// Logical returns the logical counter.
+func (t Timestamp) Logical() uint16 { return uint16(t) }
+
+// Time returns the wall time part as a time.Time.
+func (t Timestamp) Time() time.Time { return time.UnixMilli(t.Wall()) }
+
+func (t Timestamp) String() string {
+	return fmt.Sprintf("%d.%d", t.Wall(), t.Logical())
+}
+
+// HLCConfig configures an HLC.
+type HLCConfig struct {
+	// Now reads the wall clock; nil means time.Now.
+	Now func() time.Time
+	// MaxSkew bounds how far a remote timestamp may be ahead of the local wall
+	// clock; zero disables the check.
+	MaxSkew time.Duration
+	// Start is a lower bound for issued timestamps, typically the last value
+	// Persist saved before a restart.
+	Start Timestamp
+	// Persist, if set, is called to durably record an upper bound for the
+	// timestamps about to be issued, before any of them is. Restarting with
+	// that bound as Start keeps the clock monotonic even if the wall clock
+	// went back across the restart.
+	Persist func(Timestamp) error
+	// PersistAhead is how far past the issued timestamps each persisted bound
+	// reaches, trading persist calls for wall time skipped after a crash.
+	// Zero means one second.
+	PersistAhead time.Duration
+}
+
+// HLC is a hybrid logical clock. It tracks the wall clock but never goes back
+// and, like a Lamport clock, moves past every timestamp it observes.
+type HLC struct {
+	now     func() time.Time
+	maxSkew int64
+	persist func(Timestamp) error
+	ahead   int64
+
+	mu        sync.Mutex
+	last      Timestamp
+	persisted Timestamp
+}
+
+// NewHLC returns a clock configured by cfg.
+func NewHLC(cfg HLCConfig) *HLC {
+	c := &HLC{
+		now:       cfg.Now,
+		maxSkew:   cfg.MaxSkew.Milliseconds(),
+		persist:   cfg.Persist,
+		ahead:     cfg.PersistAhead.Milliseconds(),
+		last:      cfg.Start,
+		persisted: cfg.Start,
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	if c.ahead <= 0 {
+		c.ahead = time.Second.Milliseconds()
+	}
+	return c
+}
+
+// Now returns a timestamp for a local event or a send, greater than every
+// timestamp issued or observed so far. It fails only if Persist does.
+func (c *HLC) Now() (Timestamp, error) {
+	pt := c.now().UnixMilli()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ts := NewTimestamp(pt, 0)
+	if ts <= c.last {
+		ts = c.last + 1 // a full logical counter carries into the wall part
+	}
+	return ts, c.issue(ts)
+}
+
+// Update merges a timestamp received from a peer and returns the timestamp
+// of the receive event. A remote timestamp more than MaxSkew ahead of the
+// wall clock is refused with ErrClockSkew and leaves the clock unchanged.
+func (c *HLC) Update(remote Timestamp) (Timestamp, error) {
+	pt := c.now().UnixMilli()
+	if c.maxSkew > 0 && remote.Wall()-pt > c.maxSkew {
+		return 0, ErrClockSkew
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ts := NewTimestamp(pt, 0)
+	if m := max(c.last, remote); ts <= m {
+		ts = m + 1
+	}
+	return ts, c.issue(ts)
+}
+
+// Last returns the most recent timestamp issued.
+func (c *HLC) Last() Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// issue records ts as issued, persisting a new bound first if ts reaches the
+// old one; mu must be held.
+func (c *HLC) issue(ts Timestamp) error {
+	if c.persist != nil && ts >= c.persisted {
+		bound := NewTimestamp(ts.Wall()+c.ahead, 0)
+		if err := c.persist(bound); err != nil {
+			return err
+		}
+		c.persisted = bound
+	}
+	c.last = ts
+	return nil
+}
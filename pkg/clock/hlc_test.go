@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// manualTime is a wall clock moved by hand.
+type manualTime struct{ ms int64 }
+
+func (m *manualTime) now() time.Time { return time.UnixMilli(m.ms) }
+
+func TestHLC(t *testing.T) {
+	wall := &manualTime{ms: 1000}
+	c := NewHLC(HLCConfig{Now: wall.now, MaxSkew: 100 * time.Millisecond})
+
+	step := func(ts Timestamp, err error, wantWall int64, wantLogical uint16) {
+		t.Helper()
+		if err != nil || ts.Wall() != wantWall || ts.Logical() != wantLogical {
+			t.Fatalf("got %v, %v; want %d.%d", ts, err, wantWall, wantLogical)
+		}
+	}
+	ts, err := c.Now()
+	step(ts, err, 1000, 0)
+	ts, err = c.Now()
+	step(ts, err, 1000, 1)
+
+	// The wall clock going back does not move the clock back.
+	wall.ms = 900
+	ts, err = c.Now()
+	step(ts, err, 1000, 2)
+
+	wall.ms = 1050
+	ts, err = c.Update(NewTimestamp(1040, 7))
+	step(ts, err, 1050, 0)
+	ts, err = c.Update(NewTimestamp(1100, 7))
+	step(ts, err, 1100, 8)
+	if _, err := c.Update(NewTimestamp(1200, 0)); err != ErrClockSkew {
+		t.Fatalf("Update beyond max skew = %v", err)
+	}
+	if c.Last() != NewTimestamp(1100, 8) {
+		t.Fatal("refused update moved the clock")
+	}
+
+	// A full logical counter carries into the wall part.
+	c = NewHLC(HLCConfig{Now: wall.now, Start: NewTimestamp(1050, 1<<16-1)})
+	ts, err = c.Now()
+	step(ts, err, 1051, 0)
+}
+
+func TestHLCPersist(t *testing.T) {
+	wall := &manualTime{ms: 1000}
+	var saved []Timestamp
+	fail := false
+	cfg := HLCConfig{
+		Now: wall.now,
+		Persist: func(ts Timestamp) error {
+			if fail {
+				return errors.New("disk full")
+			}
+			saved = append(saved, ts)
+			return nil
+		},
+		PersistAhead: 50 * time.Millisecond,
+	}
+	c := NewHLC(cfg)
+	c.Now()
+	wall.ms = 1049
+	c.Now()
+	if len(saved) != 1 || saved[0] != NewTimestamp(1050, 0) {
+		t.Fatalf("saved %v, want one bound at 1050", saved)
+	}
+	wall.ms = 1050
+	c.Now()
+	if len(saved) != 2 {
+		t.Fatalf("bound not extended: %v", saved)
+	}
+
+	// After a restart with the wall clock behind, issue past the saved bound.
+	wall.ms = 500
+	cfg.Start = saved[len(saved)-1]
+	c = NewHLC(cfg)
+	if ts, _ := c.Now(); ts <= cfg.Start {
+		t.Fatalf("Now after restart = %v, want past %v", ts, cfg.Start)
+	}
+
+	fail = true
+	wall.ms = 5000
+	last := c.Last()
+	if _, err := c.Now(); err == nil || c.Last() != last {
+		t.Fatal("timestamp issued without persisting its bound")
+	}
+}
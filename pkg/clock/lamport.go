@@ -0,0 +1,60 @@
+// Package clock provides logical clocks for ordering events across processes:
+// Lamport clocks, vector clocks and hybrid logical clocks.
+package clock
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrInvalidEncoding is returned when decoding malformed clock data.
+var ErrInvalidEncoding = errors.New("clock: invalid encoding")
+
+// Lamport is a Lamport logical clock. The zero value is a clock at time 0,
+// safe for concurrent use.
+type Lamport struct {
+	t atomic.Uint64
+}
+
+// Tick advances the clock for a local event or a send and returns the new time.
+func (c *Lamport) Tick() uint64 {
+	return c.t.Add(1)
+}
+
+// Observe merges a time received from a peer and returns the time of the
+// receive event, which is past both remote and every earlier local time.
+func (c *Lamport) Observe(remote uint64) uint64 {
+	for {
+		cur := c.t.Load()
+		next := max(cur, remote) + 1
+		if c.t.CompareAndSwap(cur, next) {
+			return next
+		}
+	}
+}
+
+// Now returns the current time without advancing it.
+func (c *Lamport) Now() uint64 {
+	return c.t.Load()
+}
+
+// MarshalBinary encodes the current time as 8 big-endian bytes.
+func (c *Lamport) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, c.Now()), nil
+}
+
+// UnmarshalBinary restores a time encoded by MarshalBinary. The clock only
+// moves forward: a time behind the current one is ignored.
+func (c *Lamport) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return ErrInvalidEncoding
+	}
+	t := binary.BigEndian.Uint64(data)
+	for {
+		cur := c.t.Load()
+		if t <= cur || c.t.CompareAndSwap(cur, t) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLamport(t *testing.T) {
+	var c Lamport
+	if c.Tick() != 1 || c.Tick() != 2 {
+		t.Fatal("Tick did not count up from zero")
+	}
+	if got := c.Observe(10); got != 11 {
+		t.Fatalf("Observe(10) = %d, want 11", got)
+	}
+	if got := c.Observe(3); got != 12 {
+		t.Fatalf("Observe(3) = %d, want 12", got)
+	}
+
+	data, _ := c.MarshalBinary()
+	var d Lamport
+	if err := d.UnmarshalBinary(data); err != nil || d.Now() != 12 {
+		t.Fatalf("round trip = %d, %v", d.Now(), err)
+	}
+	d.Tick()
+	if d.UnmarshalBinary(data); d.Now() != 13 {
+		t.Fatal("UnmarshalBinary moved the clock back")
+	}
+	if d.UnmarshalBinary(data[:3]) != ErrInvalidEncoding {
+		t.Fatal("short data accepted")
+	}
+}
+
+func TestLamportConcurrent(t *testing.T) {
+	var (
+		c    Lamport
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[uint64]bool{}
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				var v uint64
+				if j%2 == 0 {
+					v = c.Tick()
+				} else {
+					v = c.Observe(uint64(j))
+				}
+				mu.Lock()
+				if seen[v] {
+					t.Errorf("time %d handed out twice", v)
+				}
+				seen[v] = true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,148 @@
+package clock
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// Ordering is the causal relation between two vector clocks.
+type Ordering int
+
+const (
+	Equal      Ordering = iota
+	Before              // happened before the other clock
+	After               // happened after the other clock
+	Concurrent          // neither happened before the other
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	case Concurrent:
+		return "concurrent"
+	}
+	return "unknown"
+}
+
+// Vector is a vector clock mapping node IDs to counters; missing nodes count
+// as 0. It is a plain map and needs external locking for concurrent use.
+type Vector map[string]uint64
+
+// Tick advances node's counter for a local event and returns the new value.
+func (v Vector) Tick(node string) uint64 {
+	v[node]++
+	return v[node]
+}
+
+// Merge raises every counter in v to at least its value in o.
+func (v Vector) Merge(o Vector) {
+	for n, c := range o {
+		if c > v[n] {
+			v[n] = c
+		}
+	}
+}
+
+// Clone returns a copy of v.
+func (v Vector) Clone() Vector {
+	c := make(Vector, len(v))
+	for n, t := range v {
+		c[n] = t
+	}
+	return c
+}
+
+// Compare reports how v relates causally to o.
+func (v Vector) Compare(o Vector) Ordering {
+	less, greater := false, false
+	for n, c := range v {
+		switch oc := o[n]; {
+		case c < oc:
+			less = true
+		case c > oc:
+			greater = true
+		}
+	}
+	for n, oc := range o {
+		if _, ok := v[n]; !ok && oc > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Equal
+}
+
+// HappenedBefore reports whether v causally precedes o.
+func (v Vector) HappenedBefore(o Vector) bool {
+	return v.Compare(o) == Before
+}
+
+// Prune drops the counters of departed nodes. Every replica must prune the
+// same nodes, or clocks that differ only in them compare wrongly.
+func (v Vector) Prune(nodes ...string) {
+	for _, n := range nodes {
+		delete(v, n)
+	}
+}
+
+// MarshalBinary encodes v compactly: a varint entry count, then for each
+// node in sorted order its varint-prefixed ID and varint counter. Zero
+// counters are omitted.
+func (v Vector) MarshalBinary() ([]byte, error) {
+	nodes := make([]string, 0, len(v))
+	for n, c := range v {
+		if c > 0 {
+			nodes = append(nodes, n)
+		}
+	}
+	slices.Sort(nodes)
+	b := binary.AppendUvarint(nil, uint64(len(nodes)))
+	for _, n := range nodes {
+		b = binary.AppendUvarint(b, uint64(len(n)))
+		b = append(b, n...)
+		b = binary.AppendUvarint(b, v[n])
+	}
+	return b, nil
+}
+
+// UnmarshalBinary replaces the contents of v with a clock encoded by
+// MarshalBinary.
+func (v *Vector) UnmarshalBinary(data []byte) error {
+	count, k := binary.Uvarint(data)
+	if k <= 0 || count > uint64(len(data)) {
+		return ErrInvalidEncoding
+	}
+	data = data[k:]
+	out := make(Vector, count)
+	for i := uint64(0); i < count; i++ {
+		l, k := binary.Uvarint(data)
+		if k <= 0 || l > uint64(len(data)-k) {
+			return ErrInvalidEncoding
+		}
+		n := string(data[k : k+int(l)])
+		data = data[k+int(l):]
+		c, k := binary.Uvarint(data)
+		if k <= 0 {
+			return ErrInvalidEncoding
+		}
+		data = data[k:]
+		out[n] = c
+	}
+	if len(data) != 0 || uint64(len(out)) != count {
+		return ErrInvalidEncoding
+	}
+	*v = out
+	return nil
+}
@@ -0,0 +1,53 @@
+package clock
+
+import "testing"
+
+func TestVectorCompare(t *testing.T) {
+	a := Vector{}
+	a.Tick("a")
+	b := a.Clone()
+	if a.Compare(b) != Equal {
+		t.Fatal("clone not equal")
+	}
+	b.Tick("b")
+	if a.Compare(b) != Before || b.Compare(a) != After || !a.HappenedBefore(b) {
+		t.Fatalf("a %v vs b %v: %v", a, b, a.Compare(b))
+	}
+	a.Tick("a")
+	if a.Compare(b) != Concurrent || b.Compare(a) != Concurrent {
+		t.Fatalf("a %v vs b %v: %v", a, b, a.Compare(b))
+	}
+	a.Merge(b)
+	if a.Compare(b) != After || a["a"] != 2 || a["b"] != 1 {
+		t.Fatalf("merged %v", a)
+	}
+	if (Vector{"x": 0}).Compare(Vector{}) != Equal {
+		t.Fatal("zero counter differs from a missing one")
+	}
+
+	a.Prune("b")
+	b.Prune("b")
+	if a.Compare(b) != After || len(a) != 1 {
+		t.Fatalf("pruned %v vs %v", a, b)
+	}
+}
+
+func TestVectorEncoding(t *testing.T) {
+	v := Vector{"node-1": 3, "node-2": 1 << 40, "idle": 0}
+	data, _ := v.MarshalBinary()
+	var got Vector
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got.Compare(v) != Equal {
+		t.Fatalf("round trip %v, want %v", got, v)
+	}
+	if again, _ := got.MarshalBinary(); string(again) != string(data) {
+		t.Fatal("encoding is not deterministic")
+	}
+	for i := range data {
+		if got.UnmarshalBinary(data[:i]) == nil {
+			t.Fatalf("truncated encoding of %d bytes accepted", i)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package combining implements flat combining: goroutines publish operations
+// on a shared state, and whichever of them gets the lock runs every pending
+// operation in one batch, so the state stays hot in one core's cache and the
+// lock changes hands once per batch instead of once per operation.
+package combining
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Combiner serializes operations of type func(*S) R on a state S.
+// Operations run in publication order, one at a time, on whichever
+// goroutine is combining; they must not call back into the Combiner.
+type Combiner[S, R any] struct {
+	state S
+
+	mu      sync.Mutex // held by the combining goroutine
+	pending atomic.Pointer[Future[R]]
+}
+
+// Future is the result of a submitted operation.
+type Future[R any] struct {
+	op   func() R
+	next *Future[R] // publication stack link
+	done chan struct{}
+
+	res      R
+	panicked any
+}
+
+// New returns a Combiner owning state.
+func New[S, R any](state S) *Combiner[S, R] {
+	return &Combiner[S, R]{state: state}
+}
+
+// Do runs op on the state and returns its result.
+func (c *Combiner[S, R]) Do(op func(*S) R) R {
+	return c.Submit(op).Wait()
+}
+
+// Submit publishes op and returns its future. If no batch is running, the
+// calling goroutine runs one, which includes op, before Submit returns.
+func (c *Combiner[S, R]) Submit(op func(*S) R) *Future[R] {
+	f := &Future[R]{
+		op:   func() R { return op(&c.state) },
+		done: make(chan struct{}),
+	}
+	for {
+		head := c.pending.Load()
+		f.next = head
+		if c.pending.CompareAndSwap(head, f) {
+			break
+		}
+	}
+	c.combine()
+	return f
+}
+
+// combine runs pending operations while there are any and nobody else is combining.
+// Checking again after unlocking closes the window where an operation is
+// published just as the previous combiner gives up the lock.
+func (c *Combiner[S, R]) combine() {
+	for c.pending.Load() != nil && c.mu.TryLock() {
+		for {
+			batch := c.pending.Swap(nil)
+			if batch == nil {
+				break
+			}
+			// The stack holds the newest first; run in publication order.
+			var ordered []*Future[R]
+			for f := batch; f != nil; f = f.next {
+				ordered = append(ordered, f)
+			}
+			for i := len(ordered) - 1; i >= 0; i-- {
+				ordered[i].run()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (f *Future[R]) run() {
+	defer close(f.done)
+	defer func() {
+		if p := recover(); p != nil {
+			f.panicked = p
+		}
+	}()
+	f.res = f.op()
+}
+
+// Wait blocks until the operation has run and returns its result.
+// If the operation panicked, Wait panics with the same value.
+func (f *Future[R]) Wait() R {
+	<-f.done
+	if f.panicked != nil {
+		panic(f.panicked)
+	}
+	return f.res
+}
+
+// Done returns a channel closed once the operation has run.
+func (f *Future[R]) Done() <-chan struct{} { return f.done }
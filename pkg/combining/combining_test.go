@@ -0,0 +1,71 @@
+package combining
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCombiner(t *testing.T) {
+	c := New[[]int, int](nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			prev := -1
+			for i := 0; i < 500; i++ {
+				n := c.Do(func(s *[]int) int {
+					*s = append(*s, g)
+					return len(*s)
+				})
+				if n <= prev {
+					t.Errorf("goroutine %d saw length %d after %d", g, n, prev)
+				}
+				prev = n
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := c.Do(func(s *[]int) int { return len(*s) }); n != 4000 {
+		t.Fatalf("len %d, want 4000", n)
+	}
+}
+
+func TestSubmitOrder(t *testing.T) {
+	c := New[[]int, int](nil)
+	var fs []*Future[int]
+	for i := 0; i < 10; i++ {
+		fs = append(fs, c.Submit(func(s *[]int) int {
+			*s = append(*s, i)
+			return i
+		}))
+	}
+	for i, f := range fs {
+		if got := f.Wait(); got != i {
+			t.Fatalf("future %d returned %d", i, got)
+		}
+	}
+	got := c.Do(func(s *[]int) int {
+		for i, v := range *s {
+			if v != i {
+				return -1
+			}
+		}
+		return len(*s)
+	})
+	if got != 10 {
+		t.Fatal("operations ran out of publication order")
+	}
+}
+
+func TestPanic(t *testing.T) {
+	c := New[int, int](0)
+	f := c.Submit(func(*int) int { panic("boom") })
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("Wait panicked with %v, want the operation's value", r)
+		}
+	}()
+	f.Wait()
+}
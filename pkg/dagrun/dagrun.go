@@ -0,0 +1,241 @@
+// Package dagrun runs tasks with dependencies between them, each as soon as
+// the tasks it depends on have completed.
+//
+// Where an ordermutex runs critical sections in one line, a dagrun Graph
+// runs them in a partial order: tasks unrelated by their dependencies run
+// concurrently, up to a parallelism limit.
+package dagrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrDuplicate is returned when adding a task name already in use.
+	ErrDuplicate = errors.New("dagrun: duplicate task")
+	// ErrUnknownDependency is returned by Run when a task depends on a task
+	// that was never added.
+	ErrUnknownDependency = errors.New("dagrun: unknown dependency")
+	// ErrCycle is returned by Run when dependencies form a cycle.
+	ErrCycle = errors.New("dagrun: dependency cycle")
+	// ErrSkipped is the result of a task not run because a dependency
+	// failed or was skipped, or because the run was stopped.
+	ErrSkipped = errors.New("dagrun: skipped")
+)
+
+// Func is a task's work. deps holds the values of the task's dependencies,
+// by name.
+type Func[T any] func(ctx context.Context, deps map[string]T) (T, error)
+
+// Graph is a set of tasks producing values of type T. The zero value is an
+// empty graph. A Graph may be run any number of times, but not concurrently
+// with Add.
+type Graph[T any] struct {
+	tasks []task[T]
+	index map[string]int
+}
+
+type task[T any] struct {
+	name string
+	deps []string
+	fn   Func[T]
+}
+
+// Add adds a task named name that runs fn after every task in deps.
+// Dependencies may be added after their dependents.
+func (g *Graph[T]) Add(name string, deps []string, fn Func[T]) error {
+	if _, dup := g.index[name]; dup {
+		return fmt.Errorf("%w: %q", ErrDuplicate, name)
+	}
+	if g.index == nil {
+		g.index = make(map[string]int)
+	}
+	g.index[name] = len(g.tasks)
+	g.tasks = append(g.tasks, task[T]{name: name, deps: append([]string(nil), deps...), fn: fn})
+	return nil
+}
+
+// Result is the outcome of one task.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Config configures a run. The zero value runs every ready task at once
+// and keeps going past failures.
+type Config struct {
+	// Parallel, if positive, caps the tasks running at once.
+	Parallel int
+	// FailFast stops the run at the first failure: the context of running
+	// tasks is canceled and tasks not started are skipped.
+	FailFast bool
+}
+
+// Run runs every task, each once all its dependencies have succeeded; the
+// dependents of a failed task are skipped. If ctx is done, tasks not
+// started are skipped and the running ones are waited for.
+//
+// Run returns every task's result by name, and an error joining the failed
+// tasks' errors, or ctx.Err() if ctx stopped the run. It checks the graph
+// first and returns ErrUnknownDependency or ErrCycle without running
+// anything.
+func (g *Graph[T]) Run(ctx context.Context, cfg Config) (map[string]Result[T], error) {
+	deps, dependents, err := g.check()
+	if err != nil {
+		return nil, err
+	}
+
+	type done struct {
+		i int
+		Result[T]
+	}
+	finished := make(chan done)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*Result[T], len(g.tasks))
+	var ready []int
+	for i, n := range deps {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+	start := func(i int) {
+		t := g.tasks[i]
+		in := make(map[string]T, len(t.deps))
+		for _, d := range t.deps {
+			in[d] = results[g.index[d]].Value
+		}
+		go func() {
+			v, err := t.fn(runCtx, in)
+			finished <- done{i, Result[T]{v, err}}
+		}()
+	}
+	// skip marks i and everything behind it skipped.
+	var skip func(i int)
+	skip = func(i int) {
+		if results[i] != nil {
+			return
+		}
+		results[i] = &Result[T]{Err: ErrSkipped}
+		for _, d := range dependents[i] {
+			skip(d)
+		}
+	}
+
+	var (
+		running int
+		errs    []error
+		stopped bool
+	)
+	for {
+		if ctx.Err() != nil {
+			stopped = true
+		}
+		for !stopped && len(ready) > 0 && (cfg.Parallel <= 0 || running < cfg.Parallel) {
+			i := ready[0]
+			ready = ready[1:]
+			start(i)
+			running++
+		}
+		if running == 0 {
+			break
+		}
+		var d done
+		select {
+		case d = <-finished:
+		case <-ctx.Done():
+			stopped = true
+			d = <-finished
+		}
+		running--
+		results[d.i] = &d.Result
+		if d.Err != nil {
+			errs = append(errs, fmt.Errorf("dagrun: task %q: %w", g.tasks[d.i].name, d.Err))
+			for _, dep := range dependents[d.i] {
+				skip(dep)
+			}
+			if cfg.FailFast {
+				stopped = true
+				cancel()
+			}
+			continue
+		}
+		for _, dep := range dependents[d.i] {
+			if deps[dep]--; deps[dep] == 0 && results[dep] == nil {
+				ready = append(ready, dep)
+			}
+		}
+	}
+
+	out := make(map[string]Result[T], len(g.tasks))
+	for i, t := range g.tasks {
+		if results[i] == nil {
+			results[i] = &Result[T]{Err: ErrSkipped}
+		}
+		out[t.name] = *results[i]
+	}
+	if err := ctx.Err(); err != nil {
+		return out, err
+	}
+	return out, errors.Join(errs...)
+}
+
+// check returns each task's number of dependencies and the tasks depending
+// on it, checking that the dependencies exist and are acyclic.
+func (g *Graph[T]) check() (deps []int, dependents [][]int, err error) {
+	deps = make([]int, len(g.tasks))
+	dependents = make([][]int, len(g.tasks))
+	for i, t := range g.tasks {
+		for _, name := range t.deps {
+			j, ok := g.index[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: %q needs %q", ErrUnknownDependency, t.name, name)
+			}
+			deps[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	// Depth-first search, reporting the first cycle found as a path.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.tasks))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != g.tasks[i].name {
+				start++
+			}
+			cycle := append(path[start:], g.tasks[i].name)
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> "))
+		}
+		state[i] = visiting
+		path = append(path, g.tasks[i].name)
+		for _, name := range g.tasks[i].deps {
+			if err := visit(g.index[name]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range g.tasks {
+		if err := visit(i); err != nil {
+			return nil, nil, err
+		}
+	}
+	return deps, dependents, nil
+}
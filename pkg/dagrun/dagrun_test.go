@@ -0,0 +1,127 @@
+package dagrun
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var g Graph[int]
+	var running, peak atomic.Int32
+	leaf := func(v int) Func[int] {
+		return func(ctx context.Context, _ map[string]int) (int, error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return v, nil
+		}
+	}
+	sum := func(ctx context.Context, deps map[string]int) (int, error) {
+		total := 0
+		for _, v := range deps {
+			total += v
+		}
+		return total, nil
+	}
+	// Dependents may be added before their dependencies.
+	must(t, g.Add("total", []string{"ab", "c"}, sum))
+	must(t, g.Add("ab", []string{"a", "b"}, sum))
+	for name, v := range map[string]int{"a": 1, "b": 2, "c": 4} {
+		must(t, g.Add(name, nil, leaf(v)))
+	}
+
+	res, err := g.Run(context.Background(), Config{Parallel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res["total"].Value; got != 7 {
+		t.Fatalf("total = %d, want 7", got)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("peak parallelism %d, want 2", p)
+	}
+}
+
+func TestFailureSkipsDependents(t *testing.T) {
+	var g Graph[string]
+	boom := errors.New("boom")
+	ok := func(context.Context, map[string]string) (string, error) { return "ok", nil }
+	must(t, g.Add("bad", nil, func(context.Context, map[string]string) (string, error) { return "", boom }))
+	must(t, g.Add("child", []string{"bad"}, ok))
+	must(t, g.Add("grandchild", []string{"child"}, ok))
+	must(t, g.Add("other", nil, ok))
+
+	res, err := g.Run(context.Background(), Config{})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Run = %v, want boom", err)
+	}
+	for _, name := range []string{"child", "grandchild"} {
+		if !errors.Is(res[name].Err, ErrSkipped) {
+			t.Fatalf("%s: %v, want ErrSkipped", name, res[name].Err)
+		}
+	}
+	if res["other"].Value != "ok" {
+		t.Fatalf("independent task result %+v", res["other"])
+	}
+}
+
+func TestCancel(t *testing.T) {
+	var g Graph[int]
+	ctx, cancel := context.WithCancel(context.Background())
+	must(t, g.Add("first", nil, func(ctx context.Context, _ map[string]int) (int, error) {
+		cancel()
+		<-ctx.Done()
+		return 1, nil
+	}))
+	must(t, g.Add("second", []string{"first"}, func(context.Context, map[string]int) (int, error) {
+		t.Error("task ran after cancellation")
+		return 2, nil
+	}))
+	res, err := g.Run(ctx, Config{})
+	if err != context.Canceled {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+	if !errors.Is(res["second"].Err, ErrSkipped) {
+		t.Fatalf("second: %v, want ErrSkipped", res["second"].Err)
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	var g Graph[int]
+	nop := func(context.Context, map[string]int) (int, error) { return 0, nil }
+	must(t, g.Add("a", []string{"c"}, nop))
+	must(t, g.Add("b", []string{"a"}, nop))
+	must(t, g.Add("c", []string{"b"}, nop))
+	if err := g.Add("a", nil, nop); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Add = %v, want ErrDuplicate", err)
+	}
+	_, err := g.Run(context.Background(), Config{})
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("Run = %v, want ErrCycle", err)
+	}
+	if want := "dagrun: dependency cycle: a -> c -> b -> a"; err.Error() != want {
+		t.Fatalf("error %q, want %q", err, want)
+	}
+
+	var g2 Graph[int]
+	must(t, g2.Add("a", []string{"missing"}, nop))
+	if _, err := g2.Run(context.Background(), Config{}); !errors.Is(err, ErrUnknownDependency) {
+		t.Fatalf("Run = %v, want ErrUnknownDependency", err)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package debounce coalesces bursts of calls into one.
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer calls its target once a burst of Calls has been quiet for the
+// wait period, with the last value passed. Target calls never overlap.
+type Debouncer[T any] struct {
+	wait, maxWait time.Duration
+	fn            func(T)
+
+	mu      sync.Mutex
+	pending bool
+	v       T
+	first   time.Time // when the pending burst started
+	timer   *time.Timer
+	gen     uint64 // identifies the live timer
+
+	callMu sync.Mutex // serializes fn
+}
+
+// New returns a debouncer calling fn after wait of quiet. If maxWait is
+// positive, a burst that never goes quiet is still flushed maxWait after it
+// started.
+func New[T any](wait, maxWait time.Duration, fn func(T)) *Debouncer[T] {
+	if wait <= 0 {
+		panic("debounce: New called with non-positive wait")
+	}
+	return &Debouncer[T]{wait: wait, maxWait: maxWait, fn: fn}
+}
+
+// Call records v and restarts the quiet period.
+func (d *Debouncer[T]) Call(v T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	d.v = v
+	if !d.pending {
+		d.pending = true
+		d.first = now
+	}
+	delay := d.wait
+	if d.maxWait > 0 {
+		delay = min(delay, d.first.Add(d.maxWait).Sub(now))
+	}
+	d.schedule(delay)
+}
+
+// schedule replaces the timer; mu must be held.
+func (d *Debouncer[T]) schedule(delay time.Duration) {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = time.AfterFunc(delay, func() { d.fire(gen) })
+}
+
+func (d *Debouncer[T]) fire(gen uint64) {
+	d.mu.Lock()
+	if gen != d.gen || !d.pending {
+		d.mu.Unlock()
+		return
+	}
+	v := d.take()
+	// Take callMu first so a later burst can't overtake this call.
+	d.callMu.Lock()
+	d.mu.Unlock()
+	defer d.callMu.Unlock()
+	d.fn(v)
+}
+
+// take clears the pending burst and returns its value; mu must be held.
+func (d *Debouncer[T]) take() T {
+	v := d.v
+	var zero T
+	d.v = zero
+	d.pending = false
+	d.timer = nil
+	return v
+}
+
+// Flush calls the target now with the pending value, if any, and reports
+// whether there was one.
+func (d *Debouncer[T]) Flush() bool {
+	d.mu.Lock()
+	if !d.pending {
+		d.mu.Unlock()
+		return false
+	}
+	d.timer.Stop()
+	d.gen++
+	v := d.take()
+	d.callMu.Lock()
+	d.mu.Unlock()
+	defer d.callMu.Unlock()
+	d.fn(v)
+	return true
+}
+
+// Cancel drops the pending call, if any, and reports whether there was one.
+func (d *Debouncer[T]) Cancel() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.pending {
+		return false
+	}
+	d.timer.Stop()
+	d.gen++
+	d.take()
+	return true
+}
@@ -0,0 +1,71 @@
+package debounce
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (r *recorder) fn(v int) {
+	r.mu.Lock()
+	r.got = append(r.got, v)
+	r.mu.Unlock()
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.got)
+}
+
+func TestDebounce(t *testing.T) {
+	var r recorder
+	d := New(10*time.Millisecond, 0, r.fn)
+	for i := 0; i < 5; i++ {
+		d.Call(i)
+		time.Sleep(2 * time.Millisecond)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if r.String() != "[4]" {
+		t.Fatalf("calls %s, want [4]", r.String())
+	}
+}
+
+func TestMaxWait(t *testing.T) {
+	var r recorder
+	d := New(10*time.Millisecond, 25*time.Millisecond, r.fn)
+	// Never quiet for 10ms, but flushed every 25ms.
+	for i := 0; i < 20; i++ {
+		d.Call(i)
+		time.Sleep(3 * time.Millisecond)
+	}
+	d.Cancel()
+	r.mu.Lock()
+	n := len(r.got)
+	r.mu.Unlock()
+	if n < 1 || n > 3 {
+		t.Fatalf("calls %s, want one per 25ms", r.String())
+	}
+}
+
+func TestFlushCancel(t *testing.T) {
+	var r recorder
+	d := New(time.Hour, 0, r.fn)
+	d.Call(1)
+	if !d.Flush() || d.Flush() {
+		t.Fatal("Flush reported the wrong pending state")
+	}
+	d.Call(2)
+	if !d.Cancel() || d.Cancel() {
+		t.Fatal("Cancel reported the wrong pending state")
+	}
+	if r.String() != "[1]" {
+		t.Fatalf("calls %s, want [1]", r.String())
+	}
+}
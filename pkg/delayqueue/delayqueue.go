@@ -0,0 +1,134 @@
+// Package delayqueue provides a queue whose items can only be taken once
+// their ready time has come.
+package delayqueue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/notify"
+)
+
+// Queue is an unbounded delay queue. Items come out in ready-time order,
+// ties in the order they were put. Waiting takers sleep on one timer for the
+// earliest item, whatever the number of items queued. The zero value is
+// ready to use.
+type Queue[T any] struct {
+	mu    sync.Mutex
+	items items[T]
+	seq   uint64
+
+	head notify.Notifier // fired when the earliest ready time moves up
+}
+
+type item[T any] struct {
+	v   T
+	at  time.Time
+	seq uint64
+}
+
+// Put adds v, ready at at.
+func (q *Queue[T]) Put(v T, at time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	heap.Push(&q.items, item[T]{v: v, at: at, seq: q.seq})
+	q.seq++
+	if q.items[0].seq == q.seq-1 {
+		// New earliest item: takers must re-arm their timers.
+		q.head.Broadcast()
+	}
+}
+
+// PutAfter adds v, ready after d.
+func (q *Queue[T]) PutAfter(v T, d time.Duration) {
+	q.Put(v, time.Now().Add(d))
+}
+
+// Take removes and returns the earliest item, waiting until it is ready or
+// ctx is done.
+func (q *Queue[T]) Take(ctx context.Context) (T, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		q.mu.Lock()
+		v, wait, ok := q.take(time.Now())
+		version := q.head.Version()
+		q.mu.Unlock()
+		if ok {
+			return v, nil
+		}
+
+		var ready <-chan time.Time
+		if wait > 0 {
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			ready = timer.C
+		}
+		s := q.head.SubscribeSince(version)
+		select {
+		case <-ready:
+		case <-s.C():
+		case <-ctx.Done():
+			s.Cancel()
+			var zero T
+			return zero, ctx.Err()
+		}
+		s.Cancel()
+	}
+}
+
+// TryTake removes and returns the earliest item if it is ready.
+func (q *Queue[T]) TryTake() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	v, _, ok := q.take(time.Now())
+	return v, ok
+}
+
+// take pops the earliest item if it is ready by now; otherwise it returns
+// how long until it is, or 0 if the queue is empty. mu must be held.
+func (q *Queue[T]) take(now time.Time) (T, time.Duration, bool) {
+	var zero T
+	if len(q.items) == 0 {
+		return zero, 0, false
+	}
+	if d := q.items[0].at.Sub(now); d > 0 {
+		return zero, d, false
+	}
+	return heap.Pop(&q.items).(item[T]).v, 0, true
+}
+
+// Len returns the number of queued items, ready or not.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+type items[T any] []item[T]
+
+func (h items[T]) Len() int { return len(h) }
+func (h items[T]) Less(i, j int) bool {
+	if !h[i].at.Equal(h[j].at) {
+		return h[i].at.Before(h[j].at)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h items[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *items[T]) Push(x any)   { *h = append(*h, x.(item[T])) }
+func (h *items[T]) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	old[len(old)-1] = item[T]{}
+	*h = old[:len(old)-1]
+	return it
+}
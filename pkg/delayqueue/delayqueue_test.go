@@ -0,0 +1,69 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	var q Queue[string]
+	q.PutAfter("c", 30*time.Millisecond)
+	q.PutAfter("a", 10*time.Millisecond)
+	q.PutAfter("b", 20*time.Millisecond)
+	if _, ok := q.TryTake(); ok {
+		t.Fatal("TryTake returned an item before it was ready")
+	}
+
+	start := time.Now()
+	var got []string
+	for i := 0; i < 3; i++ {
+		v, err := q.Take(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if fmt.Sprint(got) != "[a b c]" {
+		t.Fatalf("order %v", got)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("all taken after %v, want >= 30ms", d)
+	}
+}
+
+func TestEarlierPut(t *testing.T) {
+	var q Queue[string]
+	q.PutAfter("late", time.Hour)
+
+	// A taker sleeping for the hour-long item wakes for an earlier one.
+	got := make(chan string)
+	go func() {
+		v, err := q.Take(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		got <- v
+	}()
+	time.Sleep(5 * time.Millisecond)
+	q.PutAfter("soon", 5*time.Millisecond)
+	select {
+	case v := <-got:
+		if v != "soon" {
+			t.Fatalf("Take = %q", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("taker missed the earlier item")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := q.Take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Take = %v", err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", q.Len())
+	}
+}
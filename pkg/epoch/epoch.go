@@ -0,0 +1,126 @@
+// Package epoch implements epoch-based reclamation: readers pin the current
+// epoch around read-side critical sections, and objects retired by writers
+// are reclaimed only once every reader that might still observe them has unpinned.
+//
+// Reclamation here means running the callback passed to Retire, typically to
+// return a node to a pool or release an off-heap resource; the garbage
+// collector still owns ordinary memory.
+package epoch
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// collectEvery is how many retirements trigger an automatic reclamation attempt.
+const collectEvery = 64
+
+// Domain is a reclamation domain shared by the readers and writers of one
+// data structure.
+type Domain struct {
+	epoch atomic.Uint64
+
+	mu      sync.Mutex
+	parts   []*Participant
+	retired []retiredFn
+}
+
+type retiredFn struct {
+	epoch uint64
+	fn    func()
+}
+
+// Participant is one reader's handle on a Domain. It must not be used by
+// several goroutines at once; give each reading goroutine its own.
+type Participant struct {
+	d     *Domain
+	state atomic.Uint64 // epoch<<1 | pinned
+}
+
+// Register adds a participant to d.
+func (d *Domain) Register() *Participant {
+	p := &Participant{d: d}
+	d.mu.Lock()
+	d.parts = append(d.parts, p)
+	d.mu.Unlock()
+	return p
+}
+
+// Unregister removes p from its domain; p must not be pinned.
+func (p *Participant) Unregister() {
+	d := p.d
+	d.mu.Lock()
+	d.parts = slices.DeleteFunc(d.parts, func(x *Participant) bool { return x == p })
+	d.mu.Unlock()
+}
+
+// Pin enters a read-side critical section. Objects reachable from the
+// structure while pinned stay valid until Unpin. Pins don't nest.
+func (p *Participant) Pin() {
+	p.state.Store(p.d.epoch.Load()<<1 | 1)
+}
+
+// Unpin leaves the read-side critical section.
+func (p *Participant) Unpin() {
+	p.state.Store(p.state.Load() &^ 1)
+}
+
+// Retire schedules fn to run once no participant pinned now, or pinned
+// before the object was unlinked, can still observe it. The caller must have
+// unlinked the object from the structure first.
+func (d *Domain) Retire(fn func()) {
+	d.mu.Lock()
+	d.retired = append(d.retired, retiredFn{epoch: d.epoch.Load(), fn: fn})
+	n := len(d.retired)
+	d.mu.Unlock()
+
+	if n%collectEvery == 0 {
+		d.Collect()
+	}
+}
+
+// Collect tries to advance the epoch and runs the retired callbacks that have
+// become safe. It reports how many ran.
+func (d *Domain) Collect() int {
+	d.mu.Lock()
+	d.tryAdvance()
+	// Objects retired in epoch e may be seen by readers pinned in e or e+1
+	// (pinned just before the epoch moved); from e+2 on nobody can see them.
+	e := d.epoch.Load()
+	var ready []func()
+	keep := d.retired[:0]
+	for _, r := range d.retired {
+		if r.epoch+2 <= e {
+			ready = append(ready, r.fn)
+		} else {
+			keep = append(keep, r)
+		}
+	}
+	clear(d.retired[len(keep):])
+	d.retired = keep
+	d.mu.Unlock()
+
+	for _, fn := range ready {
+		fn()
+	}
+	return len(ready)
+}
+
+// Pending returns the number of retired callbacks not run yet.
+func (d *Domain) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.retired)
+}
+
+// tryAdvance bumps the epoch if every pinned participant has seen the current one; mu must be held.
+func (d *Domain) tryAdvance() {
+	e := d.epoch.Load()
+	for _, p := range d.parts {
+		if s := p.state.Load(); s&1 != 0 && s>>1 != e {
+			return
+		}
+	}
+	d.epoch.Store(e + 1)
+}
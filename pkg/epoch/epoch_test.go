@@ -0,0 +1,69 @@
+package epoch
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPinBlocksReclamation(t *testing.T) {
+	var d Domain
+	r := d.Register()
+	defer r.Unregister()
+
+	r.Pin()
+	freed := false
+	d.Retire(func() { freed = true })
+	for i := 0; i < 5; i++ {
+		d.Collect()
+	}
+	if freed {
+		t.Fatal("reclaimed while a reader that may see it is pinned")
+	}
+
+	r.Unpin()
+	d.Collect()
+	d.Collect()
+	if !freed || d.Pending() != 0 {
+		t.Fatalf("not reclaimed after unpin (pending %d)", d.Pending())
+	}
+}
+
+type node struct {
+	val  int
+	dead atomic.Bool
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	var (
+		d    Domain
+		head atomic.Pointer[node]
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	head.Store(&node{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := d.Register()
+			defer p.Unregister()
+			for !stop.Load() {
+				p.Pin()
+				if n := head.Load(); n.dead.Load() {
+					t.Error("reader saw a reclaimed node")
+				}
+				p.Unpin()
+			}
+		}()
+	}
+	for i := 1; i <= 1000; i++ {
+		old := head.Swap(&node{val: i})
+		d.Retire(func() { old.dead.Store(true) })
+	}
+	stop.Store(true)
+	wg.Wait()
+	for d.Pending() > 0 {
+		d.Collect()
+	}
+}
@@ -0,0 +1,148 @@
+// Package event provides reusable manual-reset and auto-reset events.
+package event
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Manual is a manual-reset event: once Set, every Wait returns at once until
+// Reset. The zero value is an unset event.
+type Manual struct {
+	mu  sync.Mutex
+	set bool
+	ch  chan struct{} // closed when set; nil until first needed
+}
+
+// NewManual returns an event, set if set is true.
+func NewManual(set bool) *Manual {
+	return &Manual{set: set}
+}
+
+// Set sets the event, releasing every waiter.
+func (e *Manual) Set() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.set {
+		return
+	}
+	e.set = true
+	if e.ch != nil {
+		close(e.ch)
+		e.ch = nil
+	}
+}
+
+// Reset unsets the event; later Waits block until the next Set.
+func (e *Manual) Reset() {
+	e.mu.Lock()
+	e.set = false
+	e.mu.Unlock()
+}
+
+// IsSet reports whether the event is set.
+func (e *Manual) IsSet() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set
+}
+
+// Wait blocks until the event is set or ctx is done, returning ctx.Err()
+// in the latter case.
+func (e *Manual) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	if e.set {
+		e.mu.Unlock()
+		return nil
+	}
+	if e.ch == nil {
+		e.ch = make(chan struct{})
+	}
+	ch := e.ch
+	e.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Auto is an auto-reset event: each Set releases exactly one waiter, in
+// arrival order. A Set with nobody waiting leaves the event set until the
+// next Wait consumes it; Sets don't accumulate. The zero value is an unset
+// event.
+type Auto struct {
+	mu      sync.Mutex
+	set     bool
+	waiters list.List // of chan struct{}, closed when released
+}
+
+// NewAuto returns an event, set if set is true.
+func NewAuto(set bool) *Auto {
+	return &Auto{set: set}
+}
+
+// Set releases the oldest waiter, or sets the event if nobody waits.
+func (e *Auto) Set() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.signal()
+}
+
+// signal releases one waiter or sets the event; mu must be held.
+func (e *Auto) signal() {
+	if f := e.waiters.Front(); f != nil {
+		e.waiters.Remove(f)
+		close(f.Value.(chan struct{}))
+		return
+	}
+	e.set = true
+}
+
+// Reset unsets the event.
+func (e *Auto) Reset() {
+	e.mu.Lock()
+	e.set = false
+	e.mu.Unlock()
+}
+
+// IsSet reports whether the event is set, that is, whether the next Wait
+// would return at once.
+func (e *Auto) IsSet() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set
+}
+
+// Wait blocks until a Set releases it, consuming the signal, or until ctx is
+// done. A waiter that gives up after being released passes the signal on.
+func (e *Auto) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	if e.set {
+		e.set = false
+		e.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	el := e.waiters.PushBack(ch)
+	e.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	select {
+	case <-ch:
+		e.signal()
+	default:
+		e.waiters.Remove(el)
+	}
+	return ctx.Err()
+}
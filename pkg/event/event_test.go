@@ -0,0 +1,80 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	var e Manual
+	short := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		return e.Wait(ctx)
+	}
+	if err := short(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on unset event = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.Wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	e.Set()
+	wg.Wait()
+	if err := short(); err != nil || !e.IsSet() {
+		t.Fatalf("Wait on set event = %v", err)
+	}
+
+	e.Reset()
+	if err := short(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait after Reset = %v", err)
+	}
+}
+
+func TestAuto(t *testing.T) {
+	e := NewAuto(false)
+	var released atomic.Int32
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e.Wait(ctx) == nil {
+				released.Add(1)
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	e.Set()
+	e.Set()
+	time.Sleep(5 * time.Millisecond)
+	if n := released.Load(); n != 2 {
+		t.Fatalf("2 Sets released %d waiters", n)
+	}
+	cancel()
+	wg.Wait()
+
+	// With nobody waiting the signal is kept, once.
+	e.Set()
+	e.Set()
+	if !e.IsSet() {
+		t.Fatal("Set with no waiters was lost")
+	}
+	if err := e.Wait(context.Background()); err != nil || e.IsSet() {
+		t.Fatalf("Wait = %v, still set %v", err, e.IsSet())
+	}
+}
@@ -0,0 +1,46 @@
+// Package fairmutex provides a mutual exclusion lock that grants the lock in
+// strict FIFO order of Lock calls, with no barging.
+package fairmutex
+
+import (
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Mutex is a FIFO-fair sync.Locker built on an ordermutex: each Lock takes the
+// next ticket and waits for its turn. The zero value is an unlocked mutex.
+//
+// Unlike sync.Mutex, a goroutine releasing the lock can't get it back ahead of
+// the goroutines already waiting, which bounds every waiter's latency by the
+// critical sections queued in front of it, at the cost of throughput under contention.
+type Mutex struct {
+	once sync.Once
+	om   *ordermutex.Mutex
+	held ordermutex.Ticket // guarded by the lock itself
+}
+
+var _ sync.Locker = (*Mutex)(nil)
+
+func (m *Mutex) init() {
+	m.once.Do(func() { m.om = ordermutex.NewMutex() })
+}
+
+// Lock locks m, waiting behind every earlier Lock call.
+func (m *Mutex) Lock() {
+	m.init()
+	t := m.om.GetTicket()
+	m.om.Lock(t)
+	m.held = t
+}
+
+// Unlock unlocks m and hands it to the oldest waiter.
+// As with sync.Mutex, any goroutine may unlock a locked Mutex.
+func (m *Mutex) Unlock() {
+	t := m.held
+	if t == nil {
+		panic("fairmutex: unlock of unlocked mutex")
+	}
+	m.held = nil
+	m.om.Unlock(t)
+}
@@ -0,0 +1,74 @@
+package fairmutex
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFIFO(t *testing.T) {
+	var m Mutex
+	m.Lock()
+
+	const n = 10
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Lock()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			m.Unlock()
+		}(i)
+		time.Sleep(2 * time.Millisecond) // let goroutine i queue up first
+	}
+	m.Unlock()
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("grant order %v, want Lock call order", order)
+		}
+	}
+}
+
+func TestUnlockOfUnlocked(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Unlock of an unlocked mutex did not panic")
+		}
+	}()
+	var m Mutex
+	m.Lock()
+	m.Unlock()
+	m.Unlock()
+}
+
+func TestMutualExclusion(t *testing.T) {
+	var (
+		m       Mutex
+		wg      sync.WaitGroup
+		counter int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Lock()
+				counter++
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 5000 {
+		t.Fatalf("counter %d, want 5000", counter)
+	}
+}
@@ -0,0 +1,206 @@
+// Package future provides a write-once Promise and the Future it completes,
+// with chaining and combinators.
+package future
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrNoFutures is the failure of FirstSuccess called with no futures.
+var ErrNoFutures = errors.New("future: no futures")
+
+// PanicError is the failure of a Go or Then function that panicked.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("future: function panicked: %v", e.Value) }
+
+// Future is a value of type T, or an error, that becomes available once.
+type Future[T any] struct {
+	settled atomic.Bool
+	done    chan struct{}
+	val     T
+	err     error
+}
+
+// Promise is the writing side of a Future.
+type Promise[T any] struct {
+	f *Future[T]
+}
+
+// NewPromise returns an unsettled promise.
+func NewPromise[T any]() *Promise[T] {
+	return &Promise[T]{f: &Future[T]{done: make(chan struct{})}}
+}
+
+// Future returns the future p settles.
+func (p *Promise[T]) Future() *Future[T] { return p.f }
+
+// Complete settles the future with v. It reports false, changing nothing,
+// if the future was already settled.
+func (p *Promise[T]) Complete(v T) bool { return p.f.settle(v, nil) }
+
+// Fail settles the future with err. It reports false, changing nothing,
+// if the future was already settled.
+func (p *Promise[T]) Fail(err error) bool {
+	if err == nil {
+		panic("future: Fail called with nil error")
+	}
+	var zero T
+	return p.f.settle(zero, err)
+}
+
+func (f *Future[T]) settle(v T, err error) bool {
+	if !f.settled.CompareAndSwap(false, true) {
+		return false
+	}
+	f.val, f.err = v, err
+	close(f.done)
+	return true
+}
+
+// Completed returns a future already settled with v.
+func Completed[T any](v T) *Future[T] {
+	p := NewPromise[T]()
+	p.Complete(v)
+	return p.f
+}
+
+// Failed returns a future already settled with err.
+func Failed[T any](err error) *Future[T] {
+	p := NewPromise[T]()
+	p.Fail(err)
+	return p.f
+}
+
+// Go runs fn in a new goroutine and returns a future of its result.
+// A panic in fn fails the future with a *PanicError.
+func Go[T any](fn func() (T, error)) *Future[T] {
+	p := NewPromise[T]()
+	go p.run(fn)
+	return p.f
+}
+
+func (p *Promise[T]) run(fn func() (T, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.Fail(&PanicError{Value: r})
+		}
+	}()
+	v, err := fn()
+	if err != nil {
+		p.Fail(err)
+	} else {
+		p.Complete(v)
+	}
+}
+
+// Done returns a channel closed once f is settled.
+func (f *Future[T]) Done() <-chan struct{} { return f.done }
+
+// Get waits until f is settled or ctx is done, and returns f's value and
+// error, or ctx.Err().
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Result returns f's value and error without waiting; ok is false if f is
+// not settled yet.
+func (f *Future[T]) Result() (v T, err error, ok bool) {
+	select {
+	case <-f.done:
+		return f.val, f.err, true
+	default:
+		return v, nil, false
+	}
+}
+
+// Then returns a future of fn applied to f's value. If f fails, the result
+// fails with the same error and fn is not called.
+func Then[T, U any](f *Future[T], fn func(T) (U, error)) *Future[U] {
+	p := NewPromise[U]()
+	go func() {
+		<-f.done
+		if f.err != nil {
+			p.Fail(f.err)
+			return
+		}
+		p.run(func() (U, error) { return fn(f.val) })
+	}()
+	return p.f
+}
+
+// All returns a future of every value, in argument order. It fails with the
+// first error as soon as any future fails.
+func All[T any](fs ...*Future[T]) *Future[[]T] {
+	p := NewPromise[[]T]()
+	var left atomic.Int64
+	left.Store(int64(len(fs)))
+	vals := make([]T, len(fs))
+	if len(fs) == 0 {
+		p.Complete(vals)
+	}
+	for i, f := range fs {
+		go func() {
+			<-f.done
+			if f.err != nil {
+				p.Fail(f.err)
+				return
+			}
+			vals[i] = f.val
+			if left.Add(-1) == 0 {
+				p.Complete(vals)
+			}
+		}()
+	}
+	return p.f
+}
+
+// Any returns a future settled like the first of fs to settle, whether it
+// succeeded or failed. With no futures it never settles.
+func Any[T any](fs ...*Future[T]) *Future[T] {
+	p := NewPromise[T]()
+	for _, f := range fs {
+		go func() {
+			<-f.done
+			p.f.settle(f.val, f.err)
+		}()
+	}
+	return p.f
+}
+
+// FirstSuccess returns a future of the first value among fs to succeed. If
+// all fail, it fails with their errors joined in argument order.
+func FirstSuccess[T any](fs ...*Future[T]) *Future[T] {
+	p := NewPromise[T]()
+	if len(fs) == 0 {
+		p.Fail(ErrNoFutures)
+	}
+	var left atomic.Int64
+	left.Store(int64(len(fs)))
+	errs := make([]error, len(fs))
+	for i, f := range fs {
+		go func() {
+			<-f.done
+			if f.err == nil {
+				p.Complete(f.val)
+				return
+			}
+			errs[i] = f.err
+			if left.Add(-1) == 0 {
+				p.Fail(errors.Join(errs...))
+			}
+		}()
+	}
+	return p.f
+}
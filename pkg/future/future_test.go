@@ -0,0 +1,91 @@
+package future
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPromise(t *testing.T) {
+	p := NewPromise[int]()
+	f := p.Future()
+	if _, _, ok := f.Result(); ok {
+		t.Fatal("unsettled future has a result")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := f.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get before settling = %v", err)
+	}
+
+	if !p.Complete(7) || p.Complete(8) || p.Fail(errors.New("late")) {
+		t.Fatal("a promise settled more than once")
+	}
+	if v, err := f.Get(context.Background()); v != 7 || err != nil {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+}
+
+func TestThen(t *testing.T) {
+	ctx := context.Background()
+	f := Then(Go(func() (int, error) { return 21, nil }), func(v int) (string, error) {
+		return strconv.Itoa(v * 2), nil
+	})
+	if v, err := f.Get(ctx); v != "42" || err != nil {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+
+	boom := errors.New("boom")
+	called := false
+	g := Then(Failed[int](boom), func(int) (int, error) { called = true; return 0, nil })
+	if _, err := g.Get(ctx); err != boom || called {
+		t.Fatalf("failure not propagated: %v, called %v", err, called)
+	}
+
+	h := Then(Completed(1), func(int) (int, error) { panic("oops") })
+	var pe *PanicError
+	if _, err := h.Get(ctx); !errors.As(err, &pe) || pe.Value != "oops" {
+		t.Fatalf("panic not captured: %v", err)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	ctx := context.Background()
+	slow := func(v int, d time.Duration, err error) *Future[int] {
+		return Go(func() (int, error) {
+			time.Sleep(d)
+			return v, err
+		})
+	}
+	boom := errors.New("boom")
+
+	all, err := All(slow(1, 10*time.Millisecond, nil), Completed(2), slow(3, time.Millisecond, nil)).Get(ctx)
+	if fmt.Sprint(all) != "[1 2 3]" || err != nil {
+		t.Fatalf("All = %v, %v", all, err)
+	}
+	if _, err := All(slow(1, time.Second, nil), Failed[int](boom)).Get(ctx); err != boom {
+		t.Fatalf("All with a failure = %v", err)
+	}
+	if v, err := All[int]().Get(ctx); len(v) != 0 || err != nil {
+		t.Fatalf("empty All = %v, %v", v, err)
+	}
+
+	if _, err := Any(slow(1, time.Second, nil), slow(0, time.Millisecond, boom)).Get(ctx); err != boom {
+		t.Fatalf("Any = %v, want the first to settle", err)
+	}
+
+	if v, err := FirstSuccess(Failed[int](boom), slow(2, 5*time.Millisecond, nil)).Get(ctx); v != 2 || err != nil {
+		t.Fatalf("FirstSuccess = %v, %v", v, err)
+	}
+	other := errors.New("other")
+	if _, err := FirstSuccess(Failed[int](boom), Failed[int](other)).Get(ctx); !errors.Is(err, boom) || !errors.Is(err, other) {
+		t.Fatalf("FirstSuccess of failures = %v", err)
+	}
+	if _, err := FirstSuccess[int]().Get(ctx); err != ErrNoFutures {
+		t.Fatalf("empty FirstSuccess = %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package httpserial provides net/http middleware that handles requests with
+// the same key one at a time, in arrival order, such as webhook deliveries
+// for one resource.
+//
+//	mux.Handle("POST /hooks/{account}", httpserial.New(httpserial.Config{
+//		Key:      httpserial.PathValue("account"),
+//		MaxQueue: 64,
+//	}, hooks))
+package httpserial
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/keyedmutex"
+)
+
+// Config configures a Handler.
+type Config struct {
+	// Key returns the key of a request; requests with an empty key are
+	// handled without waiting.
+	Key func(*http.Request) string
+	// MaxQueue caps the requests per key being handled or waiting;
+	// zero means no cap. Requests over it get Overflow.
+	MaxQueue int
+	// Overflow handles requests over MaxQueue. Nil means a 503 response with
+	// a Retry-After of RetryAfter, if set.
+	Overflow   http.Handler
+	RetryAfter time.Duration
+}
+
+// Header returns a Key func reading the named request header.
+func Header(name string) func(*http.Request) string {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// PathValue returns a Key func reading the named wildcard of the route
+// pattern the request matched.
+func PathValue(name string) func(*http.Request) string {
+	return func(r *http.Request) string { return r.PathValue(name) }
+}
+
+// Handler serializes requests per key in front of another handler.
+type Handler struct {
+	cfg  Config
+	next http.Handler
+	m    keyedmutex.OrderMutex
+}
+
+// New returns a handler passing requests to next, one at a time per key.
+func New(cfg Config, next http.Handler) *Handler {
+	if cfg.Key == nil {
+		panic("httpserial: New called without a Key func")
+	}
+	if cfg.Overflow == nil {
+		cfg.Overflow = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int((cfg.RetryAfter+time.Second-1)/time.Second)))
+			}
+			http.Error(w, "too many queued requests for this key", http.StatusServiceUnavailable)
+		})
+	}
+	return &Handler{cfg: cfg, next: next}
+}
+
+// Middleware returns New as middleware.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler { return New(cfg, next) }
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := h.cfg.Key(r)
+	if key == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	t, ok := h.m.TryGetTicket(key, h.cfg.MaxQueue)
+	if !ok {
+		h.cfg.Overflow.ServeHTTP(w, r)
+		return
+	}
+	// A client that gives up while queued leaves the queue and is not handled.
+	if err := h.m.LockContext(r.Context(), key, t); err != nil {
+		return
+	}
+	defer h.m.Unlock(key, t)
+	h.next.ServeHTTP(w, r)
+}
+
+// Queued returns the number of requests for key being handled or waiting.
+func (h *Handler) Queued(key string) int {
+	return h.m.Outstanding(key)
+}
@@ -0,0 +1,126 @@
+package httpserial
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		order   = map[string][]string{}
+		running = map[string]bool{}
+	)
+	release := make(chan struct{})
+	h := New(Config{Key: Header("X-Account"), MaxQueue: 3, RetryAfter: 1500 * time.Millisecond},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-Account")
+			mu.Lock()
+			if running[key] {
+				t.Errorf("requests for %s overlapped", key)
+			}
+			running[key] = true
+			order[key] = append(order[key], r.URL.Query().Get("n"))
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running[key] = false
+			mu.Unlock()
+		}))
+
+	serve := func(key string, n int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", fmt.Sprintf("/hook?n=%d", n), nil)
+		r.Header.Set("X-Account", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 3; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve("a", n)
+		}()
+		for h.Queued("a") != n+1 { // arrive in order
+			time.Sleep(time.Millisecond)
+		}
+	}
+	w := serve("a", 3)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("overflow got %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Other keys are independent.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve("b", 0)
+	}()
+	close(release)
+	wg.Wait()
+
+	if got := fmt.Sprint(order["a"]); got != "[0 1 2]" {
+		t.Fatalf("handled in order %s", got)
+	}
+	if h.Queued("a") != 0 {
+		t.Fatal("queue not drained")
+	}
+}
+
+func TestPathValue(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("POST /hooks/{account}", Middleware(Config{Key: PathValue("account"), MaxQueue: 1})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.PathValue("account"))
+		})))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/hooks/acme", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "acme" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	release := make(chan struct{})
+	var handled []string
+	h := New(Config{Key: Header("X-Account")},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = append(handled, r.URL.Query().Get("n"))
+			<-release
+		}))
+	serve := func(ctx context.Context, n int) {
+		r := httptest.NewRequestWithContext(ctx, "POST", fmt.Sprintf("/hook?n=%d", n), nil)
+		r.Header.Set("X-Account", "a")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		serve(context.Background(), 0)
+		close(done)
+	}()
+	for h.Queued("a") != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A queued request whose client disconnects returns without waiting
+	// for its turn.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	serve(ctx, 1)
+	if n := h.Queued("a"); n != 1 {
+		t.Fatalf("Queued = %d after the client left, want 1", n)
+	}
+	close(release)
+	<-done
+	serve(context.Background(), 2)
+	if got := fmt.Sprint(handled); got != "[0 2]" {
+		t.Fatalf("handled %s, want [0 2]", got)
+	}
+}
@@ -0,0 +1,177 @@
+// Package jetstreamorder consumes a NATS JetStream stream with a pool of
+// workers while applying and acking messages in stream-sequence order.
+//
+// A pull consumer delivers new messages in stream order, possibly with gaps
+// where messages were deleted or filtered out by subject. Tickets are issued
+// in delivery order rather than derived from sequence numbers, so a gap needs
+// no handling. Sequence numbers only serve to recognize redeliveries of
+// messages already queued or applied, which are burned instead of being
+// processed twice.
+//
+// The JetStream client is wrapped by a Fetcher, so any client version can be
+// used.
+package jetstreamorder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Message is a delivered JetStream message.
+type Message interface {
+	// Sequence returns the message's stream sequence number.
+	Sequence() uint64
+	Data() []byte
+	Ack() error
+}
+
+// Fetcher adapts a pull consumer. It is only called from the Run goroutine.
+type Fetcher interface {
+	// Fetch returns the next batch of messages in delivery order.
+	Fetch(ctx context.Context) ([]Message, error)
+}
+
+// Processor processes messages.
+type Processor[R any] struct {
+	// Workers is the number of messages handled at once.
+	Workers int
+	// Handle does the concurrent part of the work on a message.
+	Handle func(ctx context.Context, m Message) (R, error)
+	// Apply, if set, runs for each handled message in stream-sequence order,
+	// before the message is acked.
+	Apply func(ctx context.Context, m Message, result R) error
+	// OnRedelivery, if set, is called for each redelivered message burned
+	// because an earlier delivery is queued or was already applied.
+	OnRedelivery func(m Message)
+}
+
+type job struct {
+	m Message
+	t ordermutex.Ticket
+}
+
+// Run consumes until ctx is done or a message fails, and returns the first
+// error. A failed message, one left unhandled because ctx was done, and
+// everything after it stay unacked, so JetStream redelivers them to the next Run.
+func (p *Processor[R]) Run(ctx context.Context, f Fetcher) error {
+	if p.Workers <= 0 {
+		panic("jetstreamorder: Run called with non-positive Workers")
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		om = ordermutex.NewMutex()
+
+		mu      sync.Mutex
+		failed  bool
+		stopped bool                // a message was not acked; guarded by om's turn
+		applied uint64              // highest sequence acked in order
+		queued  = map[uint64]bool{} // sequences with a ticket
+		last    uint64              // highest sequence queued
+	)
+	fail := func(err error) {
+		mu.Lock()
+		failed = true
+		mu.Unlock()
+		cancel(err)
+	}
+
+	jobs := make(chan job, p.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var (
+					res     R
+					err     error
+					handled bool
+				)
+				if ctx.Err() == nil {
+					if res, err = p.Handle(ctx, j.m); err != nil {
+						fail(err)
+					} else {
+						handled = true
+					}
+				}
+
+				om.Lock(j.t)
+				mu.Lock()
+				ok := handled && !stopped && !failed
+				mu.Unlock()
+				if ok && p.Apply != nil {
+					err = p.Apply(ctx, j.m, res)
+				}
+				if ok && err == nil {
+					err = j.m.Ack()
+				}
+				if ok && err != nil {
+					fail(err)
+					ok = false
+				}
+				seq := j.m.Sequence()
+				mu.Lock()
+				delete(queued, seq)
+				if ok && !failed {
+					applied = seq
+				}
+				mu.Unlock()
+				stopped = stopped || !ok
+				om.Unlock(j.t)
+			}
+		}()
+	}
+
+	// redelivered reports whether m is a redelivery to burn, acking it again
+	// if it was applied: the first ack may have been lost.
+	redelivered := func(m Message) bool {
+		seq := m.Sequence()
+		mu.Lock()
+		dup := seq <= last
+		done := seq <= applied && !queued[seq]
+		mu.Unlock()
+		if !dup {
+			return false
+		}
+		if done {
+			m.Ack()
+		}
+		if p.OnRedelivery != nil {
+			p.OnRedelivery(m)
+		}
+		return true
+	}
+
+fetch:
+	for ctx.Err() == nil {
+		msgs, err := f.Fetch(ctx)
+		if err != nil {
+			cancel(err)
+			break
+		}
+		for _, m := range msgs {
+			if redelivered(m) {
+				continue
+			}
+			mu.Lock()
+			last = m.Sequence()
+			queued[last] = true
+			mu.Unlock()
+
+			t := om.GetTicket()
+			select {
+			case jobs <- job{m, t}:
+			case <-ctx.Done():
+				om.ReturnTicket(t)
+				break fetch
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return context.Cause(ctx)
+}
@@ -0,0 +1,176 @@
+package jetstreamorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type msg struct {
+	seq  uint64
+	acks *atomic.Int32
+}
+
+func (m msg) Sequence() uint64 { return m.seq }
+func (m msg) Data() []byte     { return nil }
+func (m msg) Ack() error       { m.acks.Add(1); return nil }
+
+// fakeFetcher serves batches, then waits for more or cancellation.
+type fakeFetcher struct {
+	batches chan []Message
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context) ([]Message, error) {
+	select {
+	case b := <-f.batches:
+		return b, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestRun(t *testing.T) {
+	f := &fakeFetcher{batches: make(chan []Message, 64)}
+	acks := map[uint64]*atomic.Int32{}
+	deliver := func(seqs ...uint64) {
+		var b []Message
+		for _, s := range seqs {
+			if acks[s] == nil {
+				acks[s] = new(atomic.Int32)
+			}
+			b = append(b, msg{s, acks[s]})
+		}
+		f.batches <- b
+	}
+
+	var (
+		mu      sync.Mutex
+		applied []uint64
+		redeliv []uint64
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[uint64]{
+		Workers: 4,
+		Handle: func(ctx context.Context, m Message) (uint64, error) {
+			time.Sleep(time.Duration(rand.Intn(300)) * time.Microsecond)
+			return m.Sequence(), nil
+		},
+		Apply: func(ctx context.Context, m Message, seq uint64) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, seq)
+			if len(applied) == 6 {
+				cancel()
+			}
+			return nil
+		},
+		OnRedelivery: func(m Message) {
+			mu.Lock()
+			redeliv = append(redeliv, m.Sequence())
+			mu.Unlock()
+		},
+	}
+
+	deliver(1, 2, 4, 5) // 3 was deleted from the stream
+	deliver(2, 7)       // 2 redelivered while queued or applied
+	done := make(chan error)
+	go func() { done <- p.Run(ctx, f) }()
+	for acks[7].Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	deliver(5, 9) // 5 redelivered after it was acked: its ack was lost
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+
+	if got := fmt.Sprint(applied); got != "[1 2 4 5 7 9]" {
+		t.Fatalf("applied %s", got)
+	}
+	if got := fmt.Sprint(redeliv); got != "[2 5]" {
+		t.Fatalf("redeliveries %s", got)
+	}
+	if acks[5].Load() != 2 {
+		t.Fatal("redelivery of an applied message not acked again")
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	f := &fakeFetcher{batches: make(chan []Message, 1)}
+	var b []Message
+	acks := make([]atomic.Int32, 20)
+	for i := range acks {
+		b = append(b, msg{uint64(i + 1), &acks[i]})
+	}
+	f.batches <- b
+
+	errBad := errors.New("bad message")
+	p := &Processor[struct{}]{
+		Workers: 4,
+		Handle: func(ctx context.Context, m Message) (struct{}, error) {
+			if m.Sequence() == 8 {
+				return struct{}{}, errBad
+			}
+			return struct{}{}, nil
+		},
+	}
+	if err := p.Run(context.Background(), f); err != errBad {
+		t.Fatalf("Run = %v", err)
+	}
+	for i := 7; i < len(acks); i++ {
+		if acks[i].Load() != 0 {
+			t.Fatalf("message %d acked after the failure", i+1)
+		}
+	}
+}
+
+func TestRunCanceledMidBatch(t *testing.T) {
+	f := &fakeFetcher{batches: make(chan []Message, 1)}
+	acks := make([]atomic.Int32, 20)
+	var b []Message
+	for i := range acks {
+		b = append(b, msg{uint64(i + 1), &acks[i]}) // stream sequences start at 1
+	}
+	f.batches <- b
+
+	var (
+		mu      sync.Mutex
+		handled = map[uint64]bool{}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[uint64]{
+		Workers: 4,
+		Handle: func(ctx context.Context, m Message) (uint64, error) {
+			mu.Lock()
+			handled[m.Sequence()] = true
+			mu.Unlock()
+			if m.Sequence() == 8 {
+				cancel()
+			}
+			time.Sleep(time.Duration(rand.Intn(300)) * time.Microsecond)
+			return m.Sequence(), nil
+		},
+		Apply: func(ctx context.Context, m Message, seq uint64) error {
+			if seq != m.Sequence() {
+				t.Errorf("message %d applied with result %d", m.Sequence(), seq)
+			}
+			return nil
+		},
+	}
+	if err := p.Run(ctx, f); err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+	// Acks cover a prefix of the handled messages.
+	prefix := true
+	for i := range acks {
+		acked := acks[i].Load() > 0
+		if acked && (!prefix || !handled[uint64(i+1)]) {
+			t.Fatalf("message %d acked out of turn or unhandled", i+1)
+		}
+		prefix = prefix && acked
+	}
+}
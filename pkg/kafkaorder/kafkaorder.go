@@ -0,0 +1,228 @@
+// Package kafkaorder runs a Kafka consumer loop with a pool of workers while
+// keeping each partition's side effects and commits in offset order.
+//
+// Records are handled concurrently, then applied and committed one at a time
+// per partition, in offset order: each partition has an ordermutex whose
+// tickets are the partition's offsets, and offsets missing from the stream,
+// as left by compaction or transaction markers, are returned so the records
+// after them don't wait. Different partitions do not wait for each other.
+// The Kafka client is wrapped by a Consumer, so any client library can be used.
+package kafkaorder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Record is a consumed Kafka record.
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// TopicPartition identifies a partition.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// Consumer adapts a Kafka client. Its methods are only called from the Run
+// goroutine.
+type Consumer interface {
+	// Poll returns the next records, in offset order within each partition.
+	Poll(ctx context.Context) ([]Record, error)
+	// Commit commits the offset of the next record to consume per partition.
+	Commit(ctx context.Context, offsets map[TopicPartition]int64) error
+}
+
+// Revoker is implemented by Consumers whose client reports rebalances,
+// typically from a callback run inside Poll. Run calls it after every Poll
+// and forgets the revoked partitions: records of theirs still in flight are
+// neither applied nor committed, and a later assignment starts afresh.
+type Revoker interface {
+	// Revoked returns the partitions revoked since the previous call.
+	Revoked() []TopicPartition
+}
+
+// Processor processes records.
+type Processor[R any] struct {
+	// Workers is the number of records handled at once.
+	Workers int
+	// Filter, if set, skips records for which it returns false: they are
+	// neither handled nor applied, but are committed in order.
+	Filter func(Record) bool
+	// Handle does the concurrent part of the work on a record.
+	Handle func(ctx context.Context, r Record) (R, error)
+	// Apply, if set, runs for each handled record in offset order within its
+	// partition, before the record is committed.
+	Apply func(ctx context.Context, r Record, result R) error
+}
+
+type job struct {
+	r Record
+	t ordermutex.Ticket
+	p *partition
+}
+
+// partition orders one assigned partition's records. The tickets of m stand
+// for consecutive offsets, from the first one consumed since the assignment.
+type partition struct {
+	m    *ordermutex.Mutex
+	next int64 // the offset after the last one dispatched
+
+	stopped bool // a record was not applied; guarded by m's turn
+	revoked bool // guarded by Run's mu
+}
+
+// ticket returns the ticket for offset o, returning those of the offsets
+// skipped to reach it. It reports false for an offset already dispatched.
+func (p *partition) ticket(o int64) (ordermutex.Ticket, bool) {
+	if o < p.next {
+		return nil, false
+	}
+	for ; p.next < o; p.next++ {
+		p.m.ReturnTicket(p.m.GetTicket())
+	}
+	p.next++
+	return p.m.GetTicket(), true
+}
+
+// Run consumes until ctx is done or a record fails, and returns the first
+// error. Offsets are committed before each poll and once more before Run
+// returns, up to the last record applied in order; a failed record, one left
+// unhandled because ctx was done, and everything after it in its partition
+// stay uncommitted.
+func (p *Processor[R]) Run(ctx context.Context, c Consumer) error {
+	if p.Workers <= 0 {
+		panic("kafkaorder: Run called with non-positive Workers")
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mu      sync.Mutex
+		pending = make(map[TopicPartition]int64)
+		failed  bool
+	)
+	fail := func(err error) {
+		mu.Lock()
+		failed = true
+		mu.Unlock()
+		cancel(err)
+	}
+
+	jobs := make(chan job, p.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var (
+					res     R
+					skip    = p.Filter != nil && !p.Filter(j.r)
+					handled = skip
+				)
+				if !skip && ctx.Err() == nil {
+					var err error
+					if res, err = p.Handle(ctx, j.r); err != nil {
+						fail(err)
+					} else {
+						handled = true
+					}
+				}
+
+				j.p.m.Lock(j.t)
+				mu.Lock()
+				ok := handled && !j.p.stopped && !failed && !j.p.revoked
+				mu.Unlock()
+				if ok && !skip && p.Apply != nil {
+					if err := p.Apply(ctx, j.r, res); err != nil {
+						fail(err)
+						ok = false
+					}
+				}
+				mu.Lock()
+				if ok && !failed && !j.p.revoked {
+					pending[TopicPartition{j.r.Topic, j.r.Partition}] = j.r.Offset + 1
+				}
+				mu.Unlock()
+				// Commits are cumulative, so nothing after a record left
+				// unapplied may be committed either.
+				j.p.stopped = j.p.stopped || !ok
+				j.p.m.Unlock(j.t)
+			}
+		}()
+	}
+
+	commit := func(ctx context.Context) error {
+		mu.Lock()
+		if len(pending) == 0 {
+			mu.Unlock()
+			return nil
+		}
+		offsets := pending
+		pending = make(map[TopicPartition]int64)
+		mu.Unlock()
+		return c.Commit(ctx, offsets)
+	}
+
+	partitions := make(map[TopicPartition]*partition)
+	revoker, _ := c.(Revoker)
+poll:
+	for ctx.Err() == nil {
+		if err := commit(ctx); err != nil {
+			cancel(err)
+			break
+		}
+		recs, err := c.Poll(ctx)
+		if err != nil {
+			cancel(err)
+			break
+		}
+		if revoker != nil {
+			for _, tp := range revoker.Revoked() {
+				pt, ok := partitions[tp]
+				if !ok {
+					continue
+				}
+				delete(partitions, tp)
+				mu.Lock()
+				pt.revoked = true
+				delete(pending, tp)
+				mu.Unlock()
+			}
+		}
+		for _, r := range recs {
+			tp := TopicPartition{r.Topic, r.Partition}
+			pt, ok := partitions[tp]
+			if !ok {
+				pt = &partition{m: ordermutex.NewMutex(), next: r.Offset}
+				partitions[tp] = pt
+			}
+			t, ok := pt.ticket(r.Offset)
+			if !ok {
+				continue // redelivered
+			}
+			select {
+			case jobs <- job{r, t, pt}:
+			case <-ctx.Done():
+				pt.m.ReturnTicket(t)
+				break poll
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	err := context.Cause(ctx)
+	if cerr := commit(context.WithoutCancel(ctx)); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package kafkaorder
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsumer serves batches, then blocks until canceled.
+type fakeConsumer struct {
+	batches   [][]Record
+	mu        sync.Mutex
+	committed map[TopicPartition]int64
+}
+
+func (c *fakeConsumer) Poll(ctx context.Context) ([]Record, error) {
+	if len(c.batches) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	b := c.batches[0]
+	c.batches = c.batches[1:]
+	return b, nil
+}
+
+func (c *fakeConsumer) Commit(ctx context.Context, offsets map[TopicPartition]int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tp, o := range offsets {
+		if o <= c.committed[tp] {
+			return errors.New("commit went back")
+		}
+		c.committed[tp] = o
+	}
+	return nil
+}
+
+// records returns n records per partition with gaps in the offsets, as left
+// by compaction or transaction markers, interleaved across partitions.
+func records(partitions, n int) [][]Record {
+	var all []Record
+	for i := 0; i < n; i++ {
+		for p := 0; p < partitions; p++ {
+			all = append(all, Record{Topic: "t", Partition: int32(p), Offset: int64(100 + 3*i)})
+		}
+	}
+	var batches [][]Record
+	for len(all) > 0 {
+		k := min(len(all), 7)
+		batches = append(batches, all[:k])
+		all = all[k:]
+	}
+	return batches
+}
+
+func TestRun(t *testing.T) {
+	c := &fakeConsumer{batches: records(3, 50), committed: map[TopicPartition]int64{}}
+	var (
+		mu      sync.Mutex
+		applied = map[int32][]int64{}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[int64]{
+		Workers: 8,
+		Filter:  func(r Record) bool { return r.Offset%5 != 0 },
+		Handle: func(ctx context.Context, r Record) (int64, error) {
+			time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+			return r.Offset, nil
+		},
+		Apply: func(ctx context.Context, r Record, off int64) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied[r.Partition] = append(applied[r.Partition], off)
+			if len(applied[0])+len(applied[1])+len(applied[2]) == 3*40 {
+				cancel()
+			}
+			return nil
+		},
+	}
+	if err := p.Run(ctx, c); err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+	for part, offs := range applied {
+		if len(offs) != 40 {
+			t.Fatalf("partition %d applied %d records, want 40", part, len(offs))
+		}
+		for i := 1; i < len(offs); i++ {
+			if offs[i] <= offs[i-1] {
+				t.Fatalf("partition %d applied out of order: %v", part, offs)
+			}
+		}
+	}
+	// Everything is committed on the way out, filtered records included.
+	for part := int32(0); part < 3; part++ {
+		if o := c.committed[TopicPartition{"t", part}]; o != 248 {
+			t.Fatalf("partition %d committed %d, want 248", part, o)
+		}
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	c := &fakeConsumer{batches: records(1, 20), committed: map[TopicPartition]int64{}}
+	errBad := errors.New("bad record")
+	var applied []int64
+	p := &Processor[struct{}]{
+		Workers: 4,
+		Handle: func(ctx context.Context, r Record) (struct{}, error) {
+			if r.Offset == 130 {
+				return struct{}{}, errBad
+			}
+			return struct{}{}, nil
+		},
+		Apply: func(ctx context.Context, r Record, _ struct{}) error {
+			applied = append(applied, r.Offset)
+			return nil
+		},
+	}
+	if err := p.Run(context.Background(), c); err != errBad {
+		t.Fatalf("Run = %v", err)
+	}
+	if len(applied) > 10 || c.committed[TopicPartition{"t", 0}] > 130 {
+		t.Fatalf("committed %d past the failed record; applied %v", c.committed[TopicPartition{"t", 0}], applied)
+	}
+}
+
+func TestRunCanceledMidBatch(t *testing.T) {
+	c := &fakeConsumer{batches: records(1, 40), committed: map[TopicPartition]int64{}}
+	var (
+		mu      sync.Mutex
+		handled = map[int64]bool{}
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[int64]{
+		Workers: 4,
+		Handle: func(ctx context.Context, r Record) (int64, error) {
+			mu.Lock()
+			handled[r.Offset] = true
+			mu.Unlock()
+			if r.Offset == 151 {
+				cancel()
+			}
+			time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+			return r.Offset, nil
+		},
+		Apply: func(ctx context.Context, r Record, off int64) error {
+			if off != r.Offset {
+				t.Errorf("record %d applied with result %d", r.Offset, off)
+			}
+			return nil
+		},
+	}
+	if err := p.Run(ctx, c); err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+	committed := c.committed[TopicPartition{"t", 0}]
+	if committed == 0 || committed > 217 {
+		t.Fatalf("committed %d, want a prefix of the records", committed)
+	}
+	for o := int64(100); o < committed; o += 3 {
+		if !handled[o] {
+			t.Fatalf("committed %d past unhandled record %d", committed, o)
+		}
+	}
+}
+
+// revokingConsumer revokes partition 0 after the first batch and then
+// redelivers it from the start, as a new assignment would.
+type revokingConsumer struct {
+	fakeConsumer
+	revoked []TopicPartition
+}
+
+func (c *revokingConsumer) Poll(ctx context.Context) ([]Record, error) {
+	if len(c.batches) == 1 {
+		c.revoked = []TopicPartition{{"t", 0}}
+	}
+	return c.fakeConsumer.Poll(ctx)
+}
+
+// Commit records the offsets as given: a new assignment may commit below
+// what the previous one did.
+func (c *revokingConsumer) Commit(ctx context.Context, offsets map[TopicPartition]int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tp, o := range offsets {
+		c.committed[tp] = o
+	}
+	return nil
+}
+
+func (c *revokingConsumer) Revoked() []TopicPartition {
+	tps := c.revoked
+	c.revoked = nil
+	return tps
+}
+
+func TestRunRevoked(t *testing.T) {
+	first := []Record{{Topic: "t", Offset: 10}, {Topic: "t", Offset: 11}}
+	again := []Record{{Topic: "t", Offset: 10}, {Topic: "t", Offset: 12}}
+	c := &revokingConsumer{fakeConsumer: fakeConsumer{
+		batches:   [][]Record{first, again},
+		committed: map[TopicPartition]int64{},
+	}}
+	var (
+		mu      sync.Mutex
+		applied []int64
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[struct{}]{
+		Workers: 2,
+		Handle:  func(context.Context, Record) (struct{}, error) { return struct{}{}, nil },
+		Apply: func(ctx context.Context, r Record, _ struct{}) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, r.Offset)
+			if r.Offset == 12 {
+				cancel()
+			}
+			return nil
+		},
+	}
+	if err := p.Run(ctx, c); err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+	// Offset 10 is consumed again after the reassignment instead of being
+	// dropped as a duplicate.
+	if n := len(applied); n < 2 || applied[n-2] != 10 || applied[n-1] != 12 {
+		t.Fatalf("applied %v, want the reassigned partition from offset 10", applied)
+	}
+	if o := c.committed[TopicPartition{"t", 0}]; o != 13 {
+		t.Fatalf("committed %d, want 13", o)
+	}
+}
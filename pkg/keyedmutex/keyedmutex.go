@@ -0,0 +1,144 @@
+// Package keyedmutex provides one mutex per key, created on first use and
+// reclaimed as soon as no goroutine holds or waits for it.
+package keyedmutex
+
+import "sync"
+
+// Mutex is a set of mutexes indexed by key. The zero value is ready to use.
+type Mutex struct {
+	t table[sync.Mutex]
+}
+
+// Lock locks key, waiting while another goroutine holds it.
+func (m *Mutex) Lock(key string) {
+	m.t.acquire(key).l.Lock()
+}
+
+// TryLock locks key if nobody holds or waits for it, and reports whether it did.
+func (m *Mutex) TryLock(key string) bool {
+	e, ok := m.t.acquireNew(key)
+	if ok {
+		e.l.Lock()
+	}
+	return ok
+}
+
+// Unlock unlocks key. It panics if key is not locked.
+func (m *Mutex) Unlock(key string) {
+	m.t.release(key).l.Unlock()
+}
+
+// Len returns the number of keys currently held or waited for.
+func (m *Mutex) Len() int { return m.t.len() }
+
+// table maps keys to lazily created locks.
+//
+// Each entry is reference counted by the goroutines holding or waiting for it,
+// and dropped when the count reaches zero, so idle keys cost nothing.
+type table[L any] struct {
+	mu      sync.Mutex
+	entries map[string]*entry[L]
+}
+
+type entry[L any] struct {
+	refs int // guarded by table.mu
+	l    L
+}
+
+// acquire references key's entry, creating it if needed.
+func (t *table[L]) acquire(key string) *entry[L] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entries == nil {
+		t.entries = make(map[string]*entry[L])
+	}
+	e, ok := t.entries[key]
+	if !ok {
+		e = new(entry[L])
+		t.entries[key] = e
+	}
+	e.refs++
+	return e
+}
+
+// acquireNew references key's entry only if it has to create it, that is,
+// nobody else holds or waits for key.
+func (t *table[L]) acquireNew(key string) (*entry[L], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.entries[key]; ok {
+		return nil, false
+	}
+	if t.entries == nil {
+		t.entries = make(map[string]*entry[L])
+	}
+	e := &entry[L]{refs: 1}
+	t.entries[key] = e
+	return e, true
+}
+
+// acquireMax references key's entry, creating it if needed, unless max is
+// positive and the entry already has max references.
+func (t *table[L]) acquireMax(key string, max int) (*entry[L], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entries == nil {
+		t.entries = make(map[string]*entry[L])
+	}
+	e, ok := t.entries[key]
+	if !ok {
+		e = new(entry[L])
+		t.entries[key] = e
+	}
+	if max > 0 && e.refs >= max {
+		return nil, false
+	}
+	e.refs++
+	return e, true
+}
+
+// get returns key's entry without referencing it. It panics if key has no entry.
+func (t *table[L]) get(key string) *entry[L] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		panic("keyedmutex: no ticket issued for key " + key)
+	}
+	return e
+}
+
+// release drops a reference to key's entry and returns it. It panics if key has no entry.
+func (t *table[L]) release(key string) *entry[L] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		panic("keyedmutex: unlock of unlocked key " + key)
+	}
+	e.refs--
+	if e.refs == 0 {
+		delete(t.entries, key)
+	}
+	return e
+}
+
+func (t *table[L]) refs(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[key]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+func (t *table[L]) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
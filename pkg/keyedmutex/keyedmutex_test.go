@@ -0,0 +1,150 @@
+package keyedmutex
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var (
+		m        Mutex
+		wg       sync.WaitGroup
+		counters [4]int
+	)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			key := strconv.Itoa(k)
+			for j := 0; j < 100; j++ {
+				m.Lock(key)
+				counters[k]++
+				m.Unlock(key)
+			}
+		}(i % len(counters))
+	}
+	wg.Wait()
+
+	for k, c := range counters {
+		if c != 1000 {
+			t.Fatalf("key %d counted %d, want 1000", k, c)
+		}
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("%d idle keys not reclaimed", n)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	var m Mutex
+	if !m.TryLock("a") {
+		t.Fatal("TryLock of a free key failed")
+	}
+	if m.TryLock("a") {
+		t.Fatal("TryLock of a held key succeeded")
+	}
+	m.Lock("b")
+	m.Unlock("a")
+	m.Unlock("b")
+	if n := m.Len(); n != 0 {
+		t.Fatalf("%d idle keys not reclaimed", n)
+	}
+}
+
+func TestRWMutex(t *testing.T) {
+	var m RWMutex
+	m.RLock("doc")
+	m.RLock("doc") // readers share
+
+	locked := make(chan struct{})
+	go func() {
+		m.Lock("doc")
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("writer got a key held by readers")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	m.RLock("other") // other keys are independent
+	m.RUnlock("other")
+
+	m.RUnlock("doc")
+	m.RUnlock("doc")
+	<-locked
+	m.Unlock("doc")
+	if n := m.Len(); n != 0 {
+		t.Fatalf("%d idle keys not reclaimed", n)
+	}
+}
+
+func TestOrderMutex(t *testing.T) {
+	var (
+		m     OrderMutex
+		mu    sync.Mutex
+		order = map[string][]int{}
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i % 2)
+		tk := m.GetTicket(key)
+		if i == 4 {
+			m.ReturnTicket(key, tk)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Duration(20-i) * time.Millisecond) // lock in reverse
+			m.Lock(key, tk)
+			mu.Lock()
+			order[key] = append(order[key], i)
+			mu.Unlock()
+			m.Unlock(key, tk)
+		}()
+	}
+	if _, ok := m.TryGetTicket("0", 9); ok {
+		t.Fatal("TryGetTicket exceeded the limit")
+	}
+	tk, ok := m.TryGetTicket("0", 10)
+	if !ok || m.Outstanding("0") != 10 {
+		t.Fatal("TryGetTicket refused below the limit")
+	}
+	m.ReturnTicket("0", tk)
+	wg.Wait()
+
+	if got := fmt.Sprint(order["0"]); got != "[0 2 6 8 10 12 14 16 18]" {
+		t.Fatalf("key 0 locked in order %s", got)
+	}
+	if n := m.Len(); n != 0 {
+		t.Fatalf("%d idle keys not reclaimed", n)
+	}
+}
+
+func TestOrderMutexLockContext(t *testing.T) {
+	var m OrderMutex
+	t0, t1, t2 := m.GetTicket("a"), m.GetTicket("a"), m.GetTicket("a")
+	m.Lock("a", t0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.LockContext(ctx, "a", t1); err != context.DeadlineExceeded {
+		t.Fatalf("LockContext = %v, want deadline exceeded", err)
+	}
+	if n := m.Outstanding("a"); n != 2 {
+		t.Fatalf("Outstanding = %d after a canceled wait, want 2", n)
+	}
+	m.Unlock("a", t0)
+	if err := m.LockContext(context.Background(), "a", t2); err != nil {
+		t.Fatal(err)
+	}
+	m.Unlock("a", t2)
+	if n := m.Len(); n != 0 {
+		t.Fatalf("%d idle keys not reclaimed", n)
+	}
+}
@@ -0,0 +1,99 @@
+package keyedmutex
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// OrderMutex is a set of order mutexes indexed by key: tickets for one key are
+// served in the order they were issued, independently of other keys. A key's
+// mutex is reclaimed once all its tickets are unlocked or returned.
+// The zero value is ready to use.
+//
+// Unlike ordermutex.Mutex, a ticket must be either unlocked or returned, not
+// both.
+type OrderMutex struct {
+	t table[orderLock]
+}
+
+type orderLock struct {
+	once sync.Once
+	m    *ordermutex.Mutex
+}
+
+func (l *orderLock) mutex() *ordermutex.Mutex {
+	l.once.Do(func() { l.m = ordermutex.NewMutex() })
+	return l.m
+}
+
+// GetTicket issues the next ticket for key.
+func (m *OrderMutex) GetTicket(key string) ordermutex.Ticket {
+	return m.t.acquire(key).l.mutex().GetTicket()
+}
+
+// TryGetTicket is like GetTicket but fails if key already has max tickets
+// outstanding. A non-positive max means no limit.
+func (m *OrderMutex) TryGetTicket(key string, max int) (ordermutex.Ticket, bool) {
+	e, ok := m.t.acquireMax(key, max)
+	if !ok {
+		return nil, false
+	}
+	return e.l.mutex().GetTicket(), true
+}
+
+// Lock waits for t's turn among key's tickets.
+func (m *OrderMutex) Lock(key string, t ordermutex.Ticket) {
+	m.t.get(key).l.mutex().Lock(t)
+}
+
+// LockContext is like Lock, but if ctx is done before t's turn comes it
+// leaves the queue at once, returns ctx.Err() and counts as returning t.
+func (m *OrderMutex) LockContext(ctx context.Context, key string, t ordermutex.Ticket) error {
+	om := m.t.get(key).l.mutex()
+	if ctx.Done() == nil {
+		om.Lock(t)
+		return nil
+	}
+	locked := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		// CancelLock finds nothing until LockErr has parked; keep trying
+		// until one or the other wins.
+		for !om.CancelLock(t) {
+			select {
+			case <-locked:
+				return
+			default:
+				runtime.Gosched()
+			}
+		}
+	})
+	err := om.LockErr(t)
+	close(locked)
+	stop()
+	if err == ordermutex.ErrCanceled {
+		m.t.release(key)
+		return ctx.Err()
+	}
+	return err
+}
+
+// Unlock unlocks key, passing the turn to its next ticket.
+func (m *OrderMutex) Unlock(key string, t ordermutex.Ticket) {
+	m.t.get(key).l.mutex().Unlock(t)
+	m.t.release(key)
+}
+
+// ReturnTicket cancels a ticket for key that has not locked.
+func (m *OrderMutex) ReturnTicket(key string, t ordermutex.Ticket) {
+	m.t.get(key).l.mutex().ReturnTicket(t)
+	m.t.release(key)
+}
+
+// Outstanding returns the number of tickets for key not yet unlocked or returned.
+func (m *OrderMutex) Outstanding(key string) int { return m.t.refs(key) }
+
+// Len returns the number of keys with outstanding tickets.
+func (m *OrderMutex) Len() int { return m.t.len() }
@@ -0,0 +1,32 @@
+package keyedmutex
+
+import "sync"
+
+// RWMutex is a set of reader/writer mutexes indexed by key, reclaimed like
+// Mutex's. The zero value is ready to use.
+type RWMutex struct {
+	t table[sync.RWMutex]
+}
+
+// Lock locks key for writing.
+func (m *RWMutex) Lock(key string) {
+	m.t.acquire(key).l.Lock()
+}
+
+// Unlock unlocks key for writing. It panics if key is not locked.
+func (m *RWMutex) Unlock(key string) {
+	m.t.release(key).l.Unlock()
+}
+
+// RLock locks key for reading.
+func (m *RWMutex) RLock(key string) {
+	m.t.acquire(key).l.RLock()
+}
+
+// RUnlock undoes a single RLock of key. It panics if key is not locked.
+func (m *RWMutex) RUnlock(key string) {
+	m.t.release(key).l.RUnlock()
+}
+
+// Len returns the number of keys currently held or waited for.
+func (m *RWMutex) Len() int { return m.t.len() }
@@ -0,0 +1,59 @@
+// Package latch provides a one-shot countdown latch.
+package latch
+
+import (
+	"context"
+	"sync"
+)
+
+// Latch opens once CountDown has been called count times. It is one-shot:
+// once open it stays open, and further CountDown calls are no-ops.
+type Latch struct {
+	mu    sync.Mutex
+	count int
+	open  chan struct{}
+}
+
+// New returns a latch that opens after count CountDown calls; a latch with a
+// non-positive count starts open.
+func New(count int) *Latch {
+	l := &Latch{count: max(count, 0), open: make(chan struct{})}
+	if l.count == 0 {
+		close(l.open)
+	}
+	return l
+}
+
+// CountDown decrements the count, opening the latch when it reaches zero.
+func (l *Latch) CountDown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		close(l.open)
+	}
+}
+
+// Wait blocks until the latch opens or ctx is done, returning ctx.Err() in the latter case.
+func (l *Latch) Wait(ctx context.Context) error {
+	select {
+	case <-l.open:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel closed when the latch opens, for use in select.
+func (l *Latch) Done() <-chan struct{} { return l.open }
+
+// Count returns the number of CountDown calls still needed to open the latch.
+func (l *Latch) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
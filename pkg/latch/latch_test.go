@@ -0,0 +1,41 @@
+package latch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLatch(t *testing.T) {
+	l := New(3)
+	for i := 0; i < 2; i++ {
+		go l.CountDown()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v before the last CountDown", err)
+	}
+	if n := l.Count(); n != 1 {
+		t.Fatalf("Count = %d, want 1", n)
+	}
+
+	l.CountDown()
+	l.CountDown() // extra calls are no-ops
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Done():
+	default:
+		t.Fatal("Done not closed")
+	}
+	if n := l.Count(); n != 0 {
+		t.Fatalf("Count = %d after opening", n)
+	}
+	if err := New(0).Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package leader provides in-process leader election with ordered handoff.
+package leader
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Election elects one leader at a time among the goroutines campaigning in
+// it. Candidates that find a leader in place queue up as followers, and each
+// resignation hands leadership to the oldest of them. The zero value is an
+// election with no leader.
+type Election struct {
+	mu        sync.Mutex
+	seq       uint64 // candidates so far
+	leader    *Term
+	followers list.List // of *Term, oldest first
+	observers map[chan Change]struct{}
+}
+
+// Term is one candidate's leadership, returned by Campaign once it begins.
+type Term struct {
+	e       *Election
+	id      uint64
+	el      *list.Element // in followers while waiting; guarded by e.mu
+	elected chan struct{}
+	done    chan struct{}
+}
+
+// Change reports who leads after a leadership change.
+type Change struct {
+	// Leader is the ID of the new leader's Term, or 0 if nobody leads.
+	Leader uint64
+}
+
+// Campaign waits until the caller becomes leader, behind every earlier
+// campaign still running, or until ctx is done. A campaign canceled as it
+// is elected resigns at once, passing leadership on, and returns ctx.Err().
+func (e *Election) Campaign(ctx context.Context) (*Term, error) {
+	e.mu.Lock()
+	e.seq++
+	t := &Term{e: e, id: e.seq, elected: make(chan struct{}), done: make(chan struct{})}
+	if e.leader == nil {
+		e.elect(t)
+		e.mu.Unlock()
+		return t, nil
+	}
+	t.el = e.followers.PushBack(t)
+	e.mu.Unlock()
+
+	select {
+	case <-t.elected:
+		return t, nil
+	case <-ctx.Done():
+	}
+	e.mu.Lock()
+	if t.el != nil {
+		e.followers.Remove(t.el)
+		t.el = nil
+		e.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	e.mu.Unlock()
+	t.Resign()
+	return nil, ctx.Err()
+}
+
+// elect makes t the leader and tells the observers; mu must be held.
+func (e *Election) elect(t *Term) {
+	e.leader = t
+	if t != nil {
+		close(t.elected)
+		e.notify(Change{Leader: t.id})
+	} else {
+		e.notify(Change{})
+	}
+}
+
+// Leader returns the ID of the current leader's Term, or 0 if nobody leads.
+func (e *Election) Leader() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader == nil {
+		return 0
+	}
+	return e.leader.id
+}
+
+// Followers returns the number of campaigns waiting behind the leader.
+func (e *Election) Followers() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.followers.Len()
+}
+
+// Observe returns a channel reporting the current leadership and then every
+// change, until ctx is done, when it is closed. Observers never hold up the
+// election: a slow observer sees only the latest change.
+func (e *Election) Observe(ctx context.Context) <-chan Change {
+	ch := make(chan Change, 1)
+	e.mu.Lock()
+	if e.observers == nil {
+		e.observers = make(map[chan Change]struct{})
+	}
+	e.observers[ch] = struct{}{}
+	var c Change
+	if e.leader != nil {
+		c.Leader = e.leader.id
+	}
+	ch <- c
+	e.mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.observers, ch)
+		close(ch)
+	})
+	return ch
+}
+
+// notify replaces any undelivered change in each observer with c; mu must be held.
+func (e *Election) notify(c Change) {
+	for ch := range e.observers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c
+	}
+}
+
+// ID returns the term's candidate number: campaigns are numbered from 1 in
+// the order they started.
+func (t *Term) ID() uint64 { return t.id }
+
+// Done returns a channel closed when the term ends.
+func (t *Term) Done() <-chan struct{} { return t.done }
+
+// Resign ends the term, handing leadership to the oldest follower, if any.
+// Further calls are no-ops.
+func (t *Term) Resign() {
+	e := t.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader != t {
+		return
+	}
+	close(t.done)
+	var next *Term
+	if f := e.followers.Front(); f != nil {
+		next = e.followers.Remove(f).(*Term)
+		next.el = nil
+	}
+	e.elect(next)
+}
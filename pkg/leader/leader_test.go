@@ -0,0 +1,116 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHandoffOrder(t *testing.T) {
+	var e Election
+	first, err := e.Campaign(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 10
+	var (
+		mu    sync.Mutex
+		order []uint64
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			term, err := e.Campaign(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, term.ID())
+			mu.Unlock()
+			term.Resign()
+		}()
+		for e.Followers() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	first.Resign()
+	wg.Wait()
+
+	for i, id := range order {
+		if id != uint64(i+2) {
+			t.Fatalf("leaders %v, want campaign order", order)
+		}
+	}
+	if got := e.Leader(); got != 0 {
+		t.Fatalf("Leader() = %d after everyone resigned, want 0", got)
+	}
+}
+
+func TestCanceledCampaign(t *testing.T) {
+	var e Election
+	lead, _ := e.Campaign(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := e.Campaign(ctx)
+		errc <- err
+	}()
+	for e.Followers() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("Campaign = %v, want context.Canceled", err)
+	}
+	if e.Followers() != 0 {
+		t.Fatal("canceled campaign still queued")
+	}
+
+	lead.Resign()
+	if got := e.Leader(); got != 0 {
+		t.Fatalf("Leader() = %d, want 0", got)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var e Election
+	ctx, cancel := context.WithCancel(context.Background())
+	obs := e.Observe(ctx)
+	if c := <-obs; c.Leader != 0 {
+		t.Fatalf("initial change %+v, want no leader", c)
+	}
+
+	a, _ := e.Campaign(context.Background())
+	if c := <-obs; c.Leader != a.ID() {
+		t.Fatalf("change %+v, want leader %d", c, a.ID())
+	}
+	bc := make(chan *Term)
+	go func() {
+		b, _ := e.Campaign(context.Background())
+		bc <- b
+	}()
+	for e.Followers() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	a.Resign()
+	select {
+	case <-a.Done():
+	default:
+		t.Fatal("Done not closed after Resign")
+	}
+	b := <-bc
+	if c := <-obs; c.Leader != b.ID() {
+		t.Fatalf("change %+v, want leader %d", c, b.ID())
+	}
+
+	cancel()
+	for range obs {
+	}
+	b.Resign() // must not block on the closed observer
+}
@@ -0,0 +1,80 @@
+// Package leftright implements the Left-Right concurrency technique: two
+// copies of the state, so readers never block and never wait on writers,
+// while a single writer at a time updates one copy, flips readers over to
+// it, and then updates the other.
+package leftright
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// stripes spreads each read indicator over several cache lines, so readers on
+// different cores rarely write the same one.
+const stripes = 16
+
+// LeftRight holds two instances of T. The zero value holds two zero Ts.
+//
+// Write runs its function on both instances, one after the other, so the
+// function must be deterministic and must not keep pointers into either
+// instance. Readers must not retain the *T passed to Read after it returns.
+type LeftRight[T any] struct {
+	inst [2]T
+
+	leftRight atomic.Uint32 // instance readers use
+	version   atomic.Uint32 // read indicator new readers arrive on
+	readers   [2][stripes]counter
+
+	writeMu sync.Mutex
+}
+
+type counter struct {
+	n atomic.Int64
+	_ [64 - 8]byte
+}
+
+// Read calls fn with the current instance. It never blocks; fn may run
+// concurrently with other readers and with a Write to the other instance.
+func (lr *LeftRight[T]) Read(fn func(*T)) {
+	v := lr.version.Load()
+	c := &lr.readers[v][rand.Uint32()%stripes].n
+	c.Add(1)
+	defer c.Add(-1)
+	fn(&lr.inst[lr.leftRight.Load()])
+}
+
+// Write applies fn to both instances; readers see the change as soon as
+// Write returns, or earlier. Writers are serialized.
+func (lr *LeftRight[T]) Write(fn func(*T)) {
+	lr.writeMu.Lock()
+	defer lr.writeMu.Unlock()
+
+	cur := lr.leftRight.Load()
+	fn(&lr.inst[1-cur])
+	lr.leftRight.Store(1 - cur)
+
+	// Wait out readers that may still be on the old instance: first those
+	// arriving on the other indicator since the last write, then, once new
+	// readers are switched over to it, those on the current one.
+	v := lr.version.Load()
+	lr.drain(1 - v)
+	lr.version.Store(1 - v)
+	lr.drain(v)
+
+	fn(&lr.inst[cur])
+}
+
+func (lr *LeftRight[T]) drain(v uint32) {
+	for {
+		var n int64
+		for i := range lr.readers[v] {
+			n += lr.readers[v][i].n.Load()
+		}
+		if n == 0 {
+			return
+		}
+		runtime.Gosched()
+	}
+}
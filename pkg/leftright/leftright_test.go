@@ -0,0 +1,74 @@
+package leftright
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type config struct {
+	a, b int // always equal
+}
+
+func TestLeftRight(t *testing.T) {
+	var (
+		lr   LeftRight[config]
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for !stop.Load() {
+				lr.Read(func(c *config) {
+					if c.a != c.b {
+						t.Errorf("torn read %+v", *c)
+					}
+					if c.a < last {
+						t.Errorf("read went back from %d to %d", last, c.a)
+					}
+					last = c.a
+				})
+			}
+		}()
+	}
+	for i := 1; i <= 1000; i++ {
+		lr.Write(func(c *config) {
+			c.a++
+			c.b++
+		})
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	lr.Read(func(c *config) {
+		if c.a != 1000 {
+			t.Fatalf("final %+v", *c)
+		}
+	})
+}
+
+func BenchmarkRead(b *testing.B) {
+	var lr LeftRight[config]
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			lr.Read(func(c *config) { _ = c.a })
+		}
+	})
+}
+
+func BenchmarkRWMutexRead(b *testing.B) {
+	var (
+		mu sync.RWMutex
+		c  config
+	)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.RLock()
+			_ = c.a
+			mu.RUnlock()
+		}
+	})
+}
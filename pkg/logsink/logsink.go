@@ -0,0 +1,182 @@
+// Package logsink writes log output to a backend from a background goroutine,
+// in the order the log calls were made.
+//
+// Records are formatted on the calling goroutines, concurrently; each call
+// takes a ticket first, and the formatted records are queued for the backend
+// in ticket order. Slow backend writes do not hold up callers until the queue
+// is full.
+//
+//	sink := logsink.New(os.Stderr, 1024)
+//	defer sink.Close()
+//	logger := slog.New(sink.Handler(func(w io.Writer) slog.Handler {
+//		return slog.NewJSONHandler(w, nil)
+//	}))
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// ErrClosed is returned by writes after Close.
+var ErrClosed = errors.New("logsink: closed")
+
+// Sink queues records for a backend io.Writer.
+type Sink struct {
+	om *ordermutex.Mutex
+	q  chan []byte
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+// New returns a sink writing to w, queueing up to buffer records.
+func New(w io.Writer, buffer int) *Sink {
+	if buffer <= 0 {
+		panic("logsink: New called with non-positive buffer")
+	}
+	s := &Sink{om: ordermutex.NewMutex(), q: make(chan []byte, buffer), done: make(chan struct{})}
+	go s.run(w)
+	return s
+}
+
+func (s *Sink) run(w io.Writer) {
+	defer close(s.done)
+	for p := range s.q {
+		if _, err := w.Write(p); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Write queues a copy of p, so a *Sink can back any logger writing whole
+// records, such as log.Logger.
+func (s *Sink) Write(p []byte) (int, error) {
+	t := s.om.GetTicket()
+	if err := s.enqueue(t, bytes.Clone(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// enqueue queues p in t's turn, waiting while the queue is full.
+func (s *Sink) enqueue(t ordermutex.Ticket, p []byte) error {
+	s.om.Lock(t)
+	defer s.om.Unlock(t)
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	s.q <- p
+	return nil
+}
+
+// Close writes out the queued records and stops the sink. It returns the
+// first error the backend returned, if any.
+func (s *Sink) Close() error {
+	// Queue behind every call already holding a ticket.
+	t := s.om.GetTicket()
+	s.om.Lock(t)
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.q)
+	}
+	s.mu.Unlock()
+	s.om.Unlock(t)
+
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Handler returns a slog.Handler formatting records with handlers built by
+// newHandler, and writing them through s in log-call order. newHandler is
+// called once per record formatted concurrently, and the handlers it returns
+// are reused; each must write a record with a single Write, as the slog text
+// and JSON handlers do.
+func (s *Sink) Handler(newHandler func(io.Writer) slog.Handler) slog.Handler {
+	return newSlogHandler(s, newHandler, nil)
+}
+
+// formatter is an inner handler writing to its own buffer.
+type formatter struct {
+	buf bytes.Buffer
+	h   slog.Handler
+}
+
+type slogHandler struct {
+	s          *Sink
+	newHandler func(io.Writer) slog.Handler
+	ops        []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls, in order
+	pool       sync.Pool
+	probe      slog.Handler // answers Enabled
+}
+
+func newSlogHandler(s *Sink, newHandler func(io.Writer) slog.Handler, ops []func(slog.Handler) slog.Handler) *slogHandler {
+	h := &slogHandler{s: s, newHandler: newHandler, ops: ops}
+	f := h.build()
+	h.probe = f.h
+	h.pool.Put(f)
+	return h
+}
+
+func (h *slogHandler) build() *formatter {
+	f := &formatter{}
+	f.h = h.newHandler(&f.buf)
+	for _, op := range h.ops {
+		f.h = op(f.h)
+	}
+	return f
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.probe.Enabled(ctx, l)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := h.s.om.GetTicket()
+	f, ok := h.pool.Get().(*formatter)
+	if !ok {
+		f = h.build()
+	}
+	f.buf.Reset()
+	err := f.h.Handle(ctx, r)
+	p := bytes.Clone(f.buf.Bytes())
+	h.pool.Put(f)
+	if err != nil {
+		h.s.om.ReturnTicket(t)
+		return err
+	}
+	return h.s.enqueue(t, p)
+}
+
+func (h *slogHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(as) })
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *slogHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	return newSlogHandler(h.s, h.newHandler, append(h.ops[:len(h.ops):len(h.ops)], op))
+}
@@ -0,0 +1,138 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for the sink goroutine and the test.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// gatedHandler holds up formatting of "slow" records until gate closes.
+type gatedHandler struct {
+	slog.Handler
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (h gatedHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == "slow" {
+		close(h.entered)
+		<-h.gate
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h gatedHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return gatedHandler{h.Handler.WithAttrs(as), h.entered, h.gate}
+}
+
+func (h gatedHandler) WithGroup(name string) slog.Handler {
+	return gatedHandler{h.Handler.WithGroup(name), h.entered, h.gate}
+}
+
+func TestCallOrder(t *testing.T) {
+	var out syncBuffer
+	sink := New(&out, 16)
+	entered, gate := make(chan struct{}), make(chan struct{})
+	logger := slog.New(sink.Handler(func(w io.Writer) slog.Handler {
+		return gatedHandler{slog.NewTextHandler(w, &slog.HandlerOptions{
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}), entered, gate}
+	})).With("app", "test")
+
+	done := make(chan struct{})
+	go func() {
+		logger.Info("slow")
+		close(done)
+	}()
+	<-entered // "slow" holds its ticket while it formats
+	fast := make(chan struct{})
+	go func() {
+		logger.WithGroup("g").Info("fast", "n", 1)
+		close(fast)
+	}()
+	time.Sleep(10 * time.Millisecond) // "fast" formats and waits behind "slow"
+	close(gate)
+	<-done
+	<-fast
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "level=INFO msg=slow app=test\nlevel=INFO msg=fast app=test g.n=1\n"
+	if got := out.String(); got != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", got, want)
+	}
+	if err := logger.Handler().Handle(context.Background(), slog.Record{}); err != ErrClosed {
+		t.Fatalf("Handle after Close = %v", err)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	var out syncBuffer
+	sink := New(&out, 4)
+	logger := log.New(sink, "", 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logger.Printf("goroutine %d line %d %s", g, i, strings.Repeat("x", 50))
+			}
+		}()
+	}
+	wg.Wait()
+	sink.Close()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("%d lines, want 800", len(lines))
+	}
+	for _, l := range lines {
+		if !strings.HasSuffix(l, strings.Repeat("x", 50)) {
+			t.Fatalf("mangled line %q", l)
+		}
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestBackendError(t *testing.T) {
+	sink := New(failWriter{}, 1)
+	sink.Write([]byte("lost\n"))
+	if err := sink.Close(); err == nil || err.Error() != "disk full" {
+		t.Fatalf("Close = %v", err)
+	}
+}
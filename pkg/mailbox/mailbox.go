@@ -0,0 +1,200 @@
+// Package mailbox provides an actor-style mailbox: many senders, one consumer
+// processing messages strictly in the order they were sent.
+package mailbox
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrClosed is returned by Send after Close.
+	ErrClosed = errors.New("mailbox: closed")
+	// ErrFull is returned by Send under the Reject policy when the mailbox is full.
+	ErrFull = errors.New("mailbox: full")
+)
+
+// Policy decides what Send does when the mailbox is full.
+type Policy int
+
+const (
+	// Block makes Send wait for room, in send order, until its context is done.
+	Block Policy = iota
+	// Reject makes Send fail with ErrFull.
+	Reject
+	// DropNewest discards the message being sent.
+	DropNewest
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest
+)
+
+// Config configures a Mailbox.
+type Config[M any] struct {
+	// Capacity bounds the queued messages; default 64.
+	Capacity int
+	// Policy applies when the mailbox is full; default Block.
+	Policy Policy
+	// OnDrop, if set, is called with each message discarded by a drop policy.
+	OnDrop func(M)
+}
+
+// Mailbox queues messages for a handler running on its own goroutine.
+type Mailbox[M any] struct {
+	cfg     Config[M]
+	handler func(M)
+
+	mu      sync.Mutex
+	queue   list.List // of M
+	senders list.List // of *sender[M], blocked under Block
+	closed  bool
+
+	wake chan struct{} // nudges the consumer, capacity 1
+	done chan struct{} // closed when the consumer exits
+}
+
+type sender[M any] struct {
+	msg  M
+	err  error
+	done chan struct{} // closed once msg is queued or rejected
+}
+
+// New starts a mailbox whose consumer calls handler for every message.
+func New[M any](cfg Config[M], handler func(M)) *Mailbox[M] {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 64
+	}
+	m := &Mailbox[M]{
+		cfg:     cfg,
+		handler: handler,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Send queues msg, applying the policy if the mailbox is full.
+func (m *Mailbox[M]) Send(ctx context.Context, msg M) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	if m.queue.Len() < m.cfg.Capacity && m.senders.Len() == 0 {
+		m.enqueue(msg)
+		m.mu.Unlock()
+		return nil
+	}
+
+	switch m.cfg.Policy {
+	case Reject:
+		m.mu.Unlock()
+		return ErrFull
+	case DropNewest:
+		m.mu.Unlock()
+		m.drop(msg)
+		return nil
+	case DropOldest:
+		old := m.queue.Remove(m.queue.Front()).(M)
+		m.enqueue(msg)
+		m.mu.Unlock()
+		m.drop(old)
+		return nil
+	}
+
+	s := &sender[M]{msg: msg, done: make(chan struct{})}
+	e := m.senders.PushBack(s)
+	m.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		m.senders.Remove(e)
+		return ctx.Err()
+	}
+}
+
+// enqueue queues msg and nudges the consumer; mu must be held.
+func (m *Mailbox[M]) enqueue(msg M) {
+	m.queue.PushBack(msg)
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Mailbox[M]) drop(msg M) {
+	if m.cfg.OnDrop != nil {
+		m.cfg.OnDrop(msg)
+	}
+}
+
+func (m *Mailbox[M]) run() {
+	defer close(m.done)
+	for {
+		m.mu.Lock()
+		if m.queue.Len() == 0 {
+			closed := m.closed
+			m.mu.Unlock()
+			if closed {
+				return
+			}
+			<-m.wake
+			continue
+		}
+		msg := m.queue.Remove(m.queue.Front()).(M)
+		// Admit the oldest blocked sender into the freed slot.
+		if f := m.senders.Front(); f != nil {
+			s := m.senders.Remove(f).(*sender[M])
+			m.queue.PushBack(s.msg)
+			close(s.done)
+		}
+		m.mu.Unlock()
+
+		m.handler(msg)
+	}
+}
+
+// Close stops accepting messages and waits until the consumer has handled
+// every queued one, or until ctx is done. Senders still blocked get ErrClosed.
+func (m *Mailbox[M]) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		for e := m.senders.Front(); e != nil; e = e.Next() {
+			s := e.Value.(*sender[M])
+			s.err = ErrClosed
+			close(s.done)
+		}
+		m.senders.Init()
+		select {
+		case m.wake <- struct{}{}:
+		default:
+		}
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of queued messages, not counting blocked senders.
+func (m *Mailbox[M]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.queue.Len()
+}
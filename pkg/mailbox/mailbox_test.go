@@ -0,0 +1,127 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	var (
+		got   []int
+		taken atomic.Bool
+	)
+	gate := make(chan struct{})
+	m := New(Config[int]{Capacity: 2}, func(v int) {
+		taken.Store(true)
+		<-gate
+		got = append(got, v)
+	})
+	ctx := context.Background()
+
+	// Senders beyond capacity block and are admitted in send order.
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Send(ctx, i); err != nil {
+				t.Error(err)
+			}
+		}()
+		for !taken.Load() || pending(m) < i {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+	close(gate)
+	wg.Wait()
+	if err := m.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[0 1 2 3 4 5]" {
+		t.Fatalf("handled %v", got)
+	}
+	if err := m.Send(ctx, 9); err != ErrClosed {
+		t.Fatalf("Send after Close = %v", err)
+	}
+}
+
+// pending counts queued messages and blocked senders.
+func pending[M any](m *Mailbox[M]) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.queue.Len() + m.senders.Len()
+}
+
+func TestPolicies(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		p             Policy
+		handled, drop string
+		err           error
+	}{
+		{Reject, "[0 1 2]", "[]", ErrFull},
+		{DropNewest, "[0 1 2]", "[3]", nil},
+		{DropOldest, "[0 2 3]", "[1]", nil},
+	} {
+		var (
+			mu      sync.Mutex
+			handled []int
+			dropped = []int{}
+		)
+		gate := make(chan struct{})
+		m := New(Config[int]{Capacity: 2, Policy: tc.p, OnDrop: func(v int) { dropped = append(dropped, v) }},
+			func(v int) {
+				<-gate
+				mu.Lock()
+				handled = append(handled, v)
+				mu.Unlock()
+			})
+		m.Send(ctx, 0)
+		for m.Len() > 0 { // wait until the handler holds 0
+			time.Sleep(time.Millisecond)
+		}
+		m.Send(ctx, 1)
+		m.Send(ctx, 2)
+		if err := m.Send(ctx, 3); err != tc.err {
+			t.Errorf("%v: Send into full mailbox = %v", tc.p, err)
+		}
+		close(gate)
+		m.Close(ctx)
+		if fmt.Sprint(handled) != tc.handled || fmt.Sprint(dropped) != tc.drop {
+			t.Errorf("%v: handled %v dropped %v; want %s %s", tc.p, handled, dropped, tc.handled, tc.drop)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	gate := make(chan struct{})
+	m := New(Config[int]{Capacity: 1}, func(int) { <-gate })
+	ctx := context.Background()
+	m.Send(ctx, 0)
+	for m.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	m.Send(ctx, 1)
+
+	blocked := make(chan error)
+	go func() { blocked <- m.Send(ctx, 2) }()
+	time.Sleep(5 * time.Millisecond)
+
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := m.Close(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close with a stuck handler = %v", err)
+	}
+	if err := <-blocked; err != ErrClosed {
+		t.Fatalf("blocked Send after Close = %v", err)
+	}
+	close(gate)
+	if err := m.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package mcs implements the MCS queue lock: each waiter spins on a flag in
+// its own queue node instead of on the lock word, so a handoff touches one
+// waiter's cache line rather than every spinner's.
+//
+// It suits very short critical sections on many-core machines. Waiters never
+// park, only yield, so under oversubscription or long critical sections a
+// parking lock such as sync.Mutex or ordermutex is the better choice.
+package mcs
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// spinsBeforeYield is how many polls a waiter makes before yielding the processor.
+const spinsBeforeYield = 32
+
+// Mutex is an MCS lock granting the lock in FIFO order. The zero value is unlocked.
+type Mutex struct {
+	tail   atomic.Pointer[node]
+	holder *node // guarded by the lock itself
+}
+
+type node struct {
+	next   atomic.Pointer[node]
+	locked atomic.Bool
+}
+
+var _ sync.Locker = (*Mutex)(nil)
+
+var nodes = sync.Pool{New: func() any { return new(node) }}
+
+// Lock locks m, queueing behind the current holder and earlier waiters.
+func (m *Mutex) Lock() {
+	n := nodes.Get().(*node)
+	n.next.Store(nil)
+	n.locked.Store(true)
+
+	if prev := m.tail.Swap(n); prev != nil {
+		prev.next.Store(n)
+		for i := 0; n.locked.Load(); i++ {
+			if i >= spinsBeforeYield {
+				runtime.Gosched()
+			}
+		}
+	}
+	m.holder = n
+}
+
+// Unlock unlocks m, handing it directly to the next waiter if there is one.
+func (m *Mutex) Unlock() {
+	n := m.holder
+	if n == nil {
+		panic("mcs: unlock of unlocked mutex")
+	}
+	m.holder = nil
+
+	next := n.next.Load()
+	if next == nil {
+		if m.tail.CompareAndSwap(n, nil) {
+			nodes.Put(n)
+			return
+		}
+		// A waiter swapped itself in but hasn't linked to n yet.
+		for i := 0; next == nil; i++ {
+			if i >= spinsBeforeYield {
+				runtime.Gosched()
+			}
+			next = n.next.Load()
+		}
+	}
+	next.locked.Store(false)
+	nodes.Put(n)
+}
@@ -0,0 +1,49 @@
+package mcs
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMutualExclusion(t *testing.T) {
+	var (
+		m       Mutex
+		wg      sync.WaitGroup
+		counter int
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Lock()
+				counter++
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 16000 {
+		t.Fatalf("counter %d, want 16000", counter)
+	}
+}
+
+func TestUnlockOfUnlocked(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Unlock of an unlocked mutex did not panic")
+		}
+	}()
+	var m Mutex
+	m.Unlock()
+}
+
+func BenchmarkMCS(b *testing.B) {
+	var m Mutex
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Lock()
+			m.Unlock()
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Package mpsc provides a lock-free intrusive multi-producer single-consumer
+// queue (Vyukov's design): Push is one atomic swap, Pop touches no shared
+// counter, and nodes are supplied by the caller so nothing is allocated.
+package mpsc
+
+import "sync/atomic"
+
+// Node is a queue link carrying a value. A node may be in at most one queue
+// at a time and may be reused once popped.
+type Node[T any] struct {
+	next  atomic.Pointer[Node[T]]
+	Value T
+}
+
+// Queue is an MPSC queue. Push may be called from any goroutine; Pop and
+// PopBatch only from one at a time.
+type Queue[T any] struct {
+	head atomic.Pointer[Node[T]] // last pushed, swapped by producers
+	_    [56]byte                // keep producers' and the consumer's lines apart
+	tail *Node[T]                // next to pop, owned by the consumer
+	stub Node[T]
+}
+
+// New returns an empty queue.
+func New[T any]() *Queue[T] {
+	q := &Queue[T]{}
+	q.head.Store(&q.stub)
+	q.tail = &q.stub
+	return q
+}
+
+// Push appends n. It is wait-free.
+func (q *Queue[T]) Push(n *Node[T]) {
+	n.next.Store(nil)
+	prev := q.head.Swap(n)
+	// Between the swap and this store the queue is briefly disconnected;
+	// Pop treats it as empty until the link lands.
+	prev.next.Store(n)
+}
+
+// Pop removes and returns the oldest node, or nil if the queue is empty or
+// a producer is halfway through a Push.
+func (q *Queue[T]) Pop() *Node[T] {
+	tail := q.tail
+	next := tail.next.Load()
+	if tail == &q.stub {
+		if next == nil {
+			return nil
+		}
+		q.tail = next
+		tail = next
+		next = next.next.Load()
+	}
+	if next != nil {
+		q.tail = next
+		return tail
+	}
+	if tail != q.head.Load() {
+		return nil // a Push is in progress
+	}
+	// tail is the last node: put the stub behind it so it can be detached.
+	q.Push(&q.stub)
+	if next = tail.next.Load(); next != nil {
+		q.tail = next
+		return tail
+	}
+	return nil
+}
+
+// PopBatch pops up to len(buf) nodes into buf and returns the filled part.
+func (q *Queue[T]) PopBatch(buf []*Node[T]) []*Node[T] {
+	n := 0
+	for n < len(buf) {
+		nd := q.Pop()
+		if nd == nil {
+			break
+		}
+		buf[n] = nd
+		n++
+	}
+	return buf[:n]
+}
+
+// Empty reports whether the queue has nothing to pop; like Pop, it may miss
+// a Push in progress. Only the consumer may call it.
+func (q *Queue[T]) Empty() bool {
+	return q.tail == &q.stub && q.stub.next.Load() == nil
+}
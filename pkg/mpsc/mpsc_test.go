@@ -0,0 +1,116 @@
+package mpsc
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	q := New[int]()
+	if q.Pop() != nil || !q.Empty() {
+		t.Fatal("new queue not empty")
+	}
+	for i := 0; i < 5; i++ {
+		q.Push(&Node[int]{Value: i})
+	}
+	if q.Empty() {
+		t.Fatal("queue with nodes reported empty")
+	}
+	buf := make([]*Node[int], 3)
+	got := q.PopBatch(buf)
+	if len(got) != 3 || got[0].Value != 0 || got[2].Value != 2 {
+		t.Fatalf("batch %v", got)
+	}
+	for i := 3; i < 5; i++ {
+		if q.Empty() {
+			t.Fatalf("Empty before popping %d", i)
+		}
+		if n := q.Pop(); n == nil || n.Value != i {
+			t.Fatalf("Pop = %v, want %d", n, i)
+		}
+	}
+	if q.Pop() != nil || !q.Empty() {
+		t.Fatal("drained queue not empty")
+	}
+	// Nodes can be reused once popped.
+	n := &Node[int]{Value: 9}
+	q.Push(n)
+	if q.Pop() != n {
+		t.Fatal("reused node lost")
+	}
+	q.Push(n)
+	if q.Pop() != n {
+		t.Fatal("reused node lost twice")
+	}
+}
+
+func TestProducers(t *testing.T) {
+	const producers, each = 4, 2000
+	q := New[[2]int]()
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				q.Push(&Node[[2]int]{Value: [2]int{p, i}})
+			}
+		}()
+	}
+
+	// Each producer's values arrive in its own order.
+	var last [producers]int
+	for i := range last {
+		last[i] = -1
+	}
+	buf := make([]*Node[[2]int], 64)
+	for got := 0; got < producers*each; {
+		for _, n := range q.PopBatch(buf) {
+			p, i := n.Value[0], n.Value[1]
+			if i != last[p]+1 {
+				t.Fatalf("producer %d: got %d after %d", p, i, last[p])
+			}
+			last[p] = i
+			got++
+		}
+	}
+	wg.Wait()
+}
+
+func benchmarkQueue(b *testing.B, batch int) {
+	q := New[int]()
+	done := make(chan struct{})
+	go func() {
+		buf := make([]*Node[int], batch)
+		for n := 0; n < b.N; {
+			n += len(q.PopBatch(buf))
+		}
+		close(done)
+	}()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			q.Push(&Node[int]{})
+		}
+	})
+	<-done
+}
+
+func BenchmarkQueue(b *testing.B)      { benchmarkQueue(b, 1) }
+func BenchmarkQueueBatch(b *testing.B) { benchmarkQueue(b, 64) }
+
+func BenchmarkChannel(b *testing.B) {
+	ch := make(chan *Node[int], 1024)
+	done := make(chan struct{})
+	go func() {
+		for n := 0; n < b.N; n++ {
+			<-ch
+		}
+		close(done)
+	}()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ch <- &Node[int]{}
+		}
+	})
+	<-done
+}
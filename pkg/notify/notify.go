@@ -0,0 +1,131 @@
+// Package notify provides a condition-variable replacement whose waiters
+// block on channels, so they can select on contexts and other events.
+package notify
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Notifier delivers Broadcast and Signal notifications to subscribers.
+// Every notification bumps a version, so a subscriber that remembers the
+// version it last saw can tell whether it missed any. The zero value is
+// ready to use.
+type Notifier struct {
+	mu      sync.Mutex
+	version uint64
+	subs    list.List // of *Subscription, oldest first
+}
+
+// Subscription is a one-shot wait for the next notification.
+type Subscription struct {
+	n  *Notifier
+	el *list.Element
+	ch chan struct{}
+
+	// Version is the notifier's version when the subscription was made.
+	Version uint64
+}
+
+// closed is returned for subscriptions that fire at once.
+var closed = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// Subscribe returns a subscription fired by the next Broadcast, or by a
+// Signal if it is the oldest subscription then.
+func (n *Notifier) Subscribe() *Subscription {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.subscribe()
+}
+
+// subscribe adds a subscription; mu must be held.
+func (n *Notifier) subscribe() *Subscription {
+	s := &Subscription{n: n, ch: make(chan struct{}), Version: n.version}
+	s.el = n.subs.PushBack(s)
+	return s
+}
+
+// SubscribeSince is like Subscribe, but the subscription fires at once if
+// any notification happened after version.
+func (n *Notifier) SubscribeSince(version uint64) *Subscription {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.version > version {
+		return &Subscription{n: n, ch: closed, Version: n.version}
+	}
+	return n.subscribe()
+}
+
+// C returns a channel closed when the subscription fires.
+func (s *Subscription) C() <-chan struct{} { return s.ch }
+
+// Cancel drops the subscription, so a later Signal goes to someone else.
+// It reports false if the subscription had already fired.
+func (s *Subscription) Cancel() bool {
+	s.n.mu.Lock()
+	defer s.n.mu.Unlock()
+	if s.el == nil {
+		return false
+	}
+	s.n.subs.Remove(s.el)
+	s.el = nil
+	return true
+}
+
+// Broadcast fires every subscription.
+func (n *Notifier) Broadcast() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.version++
+	for e := n.subs.Front(); e != nil; e = e.Next() {
+		n.fire(e)
+	}
+	n.subs.Init()
+}
+
+// Signal fires the oldest subscription, if any. The version is bumped
+// either way.
+func (n *Notifier) Signal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.version++
+	if e := n.subs.Front(); e != nil {
+		n.fire(e)
+		n.subs.Remove(e)
+	}
+}
+
+// fire closes e's subscription; mu must be held.
+func (n *Notifier) fire(e *list.Element) {
+	s := e.Value.(*Subscription)
+	s.el = nil
+	close(s.ch)
+}
+
+// Version returns the number of notifications so far.
+func (n *Notifier) Version() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.version
+}
+
+// Wait blocks until a notification after version, returning the version then
+// current, or until ctx is done. A Signal is never lost to a canceled Wait:
+// if one arrives as ctx is done, Wait reports it instead of ctx.Err().
+func (n *Notifier) Wait(ctx context.Context, version uint64) (uint64, error) {
+	s := n.SubscribeSince(version)
+	select {
+	case <-s.ch:
+		return n.Version(), nil
+	case <-ctx.Done():
+		if s.Cancel() {
+			return version, ctx.Err()
+		}
+		return n.Version(), nil
+	}
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func fired(s *Subscription) bool {
+	select {
+	case <-s.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSignal(t *testing.T) {
+	var n Notifier
+	a, b, c := n.Subscribe(), n.Subscribe(), n.Subscribe()
+	if !a.Cancel() {
+		t.Fatal("Cancel of a pending subscription reported it fired")
+	}
+	n.Signal()
+	if fired(a) || !fired(b) || fired(c) {
+		t.Fatalf("Signal fired a=%v b=%v c=%v; want only b", fired(a), fired(b), fired(c))
+	}
+	if b.Cancel() {
+		t.Fatal("Cancel of a fired subscription reported it pending")
+	}
+	n.Broadcast()
+	if !fired(c) || n.Version() != 2 {
+		t.Fatalf("Broadcast: fired %v, version %d", fired(c), n.Version())
+	}
+}
+
+func TestSubscribeSince(t *testing.T) {
+	var n Notifier
+	v := n.Version()
+	n.Broadcast() // nobody listening yet
+
+	if s := n.SubscribeSince(v); !fired(s) {
+		t.Fatal("missed notification not detected")
+	}
+	if s := n.SubscribeSince(n.Version()); fired(s) {
+		t.Fatal("up-to-date subscription fired")
+	}
+}
+
+func TestWait(t *testing.T) {
+	var n Notifier
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := n.Wait(ctx, n.Version()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait without notification = %v", err)
+	}
+
+	done := make(chan uint64)
+	go func() {
+		v, err := n.Wait(context.Background(), 0)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- v
+	}()
+	time.Sleep(5 * time.Millisecond)
+	n.Signal()
+	if v := <-done; v != 1 {
+		t.Fatalf("Wait = %d, want 1", v)
+	}
+}
+
+func TestWaitRacingBroadcast(t *testing.T) {
+	var n Notifier
+	for i := 0; i < 2000; i++ {
+		v := n.Version()
+		go n.Broadcast()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := n.Wait(ctx, v)
+		cancel()
+		if err != nil {
+			t.Fatalf("iteration %d: Broadcast lost: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package once
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy says when a Lazy tries again after its initializer fails.
+// The zero value retries on the next Get.
+type RetryPolicy struct {
+	// Backoff is how long a failure is returned to callers before the next
+	// attempt.
+	Backoff time.Duration
+	// MaxAttempts, if positive, makes the last error permanent after that
+	// many failed attempts.
+	MaxAttempts int
+}
+
+// Lazy is a value initialized on first use by a function that can fail or be
+// canceled. Once initialized, Get is a single atomic load.
+type Lazy[T any] struct {
+	init  func(context.Context) (T, error)
+	retry RetryPolicy
+
+	val atomic.Pointer[T]
+
+	mu       sync.Mutex
+	call     *lazyCall[T] // attempt in progress, if any
+	failures int
+	err      error // last failure
+	retryAt  time.Time
+}
+
+type lazyCall[T any] struct {
+	done    chan struct{}
+	val     T
+	err     error
+	waiters int  // guarded by Lazy.mu
+	gone    bool // every waiter gave up and the attempt was canceled
+	cancel  context.CancelFunc
+}
+
+// NewLazy returns a Lazy initialized by init under retry.
+func NewLazy[T any](init func(context.Context) (T, error), retry RetryPolicy) *Lazy[T] {
+	return &Lazy[T]{init: init, retry: retry}
+}
+
+// Get returns the value, running the initializer if needed. Concurrent
+// callers share one attempt; it runs detached from their contexts and is
+// canceled only once every caller waiting for it has given up. A caller
+// whose ctx is done gets ctx.Err(). A failed attempt's error is returned
+// until the retry policy allows another.
+func (l *Lazy[T]) Get(ctx context.Context) (T, error) {
+	if p := l.val.Load(); p != nil {
+		return *p, nil
+	}
+
+	l.mu.Lock()
+	if p := l.val.Load(); p != nil {
+		l.mu.Unlock()
+		return *p, nil
+	}
+	c := l.call
+	if c == nil || c.gone {
+		if err := l.failed(); err != nil {
+			l.mu.Unlock()
+			var zero T
+			return zero, err
+		}
+		c = l.start(ctx)
+	}
+	c.waiters++
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	c.waiters--
+	if c.waiters == 0 {
+		c.gone = true
+		c.cancel()
+	}
+	l.mu.Unlock()
+	var zero T
+	return zero, ctx.Err()
+}
+
+// failed returns the error callers get without a new attempt, if any;
+// mu must be held.
+func (l *Lazy[T]) failed() error {
+	if l.err == nil {
+		return nil
+	}
+	if l.retry.MaxAttempts > 0 && l.failures >= l.retry.MaxAttempts {
+		return l.err
+	}
+	if time.Now().Before(l.retryAt) {
+		return l.err
+	}
+	return nil
+}
+
+// start begins an attempt; mu must be held.
+func (l *Lazy[T]) start(ctx context.Context) *lazyCall[T] {
+	ictx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &lazyCall[T]{done: make(chan struct{}), cancel: cancel}
+	l.call = c
+	go func() {
+		defer cancel()
+		c.val, c.err = l.init(ictx)
+
+		l.mu.Lock()
+		if l.call == c {
+			l.call = nil
+		}
+		switch {
+		case c.err == nil:
+			l.val.Store(&c.val)
+			l.err = nil
+		case ictx.Err() == nil:
+			// Abandoned attempts don't count against the policy.
+			l.failures++
+			l.err = c.err
+			l.retryAt = time.Now().Add(l.retry.Backoff)
+		}
+		l.mu.Unlock()
+		close(c.done)
+	}()
+	return c
+}
+
+// Initialized reports whether the value is available.
+func (l *Lazy[T]) Initialized() bool { return l.val.Load() != nil }
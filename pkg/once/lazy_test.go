@@ -0,0 +1,84 @@
+package once
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLazyRetry(t *testing.T) {
+	var calls atomic.Int32
+	fail := errors.New("fail")
+	l := NewLazy(func(context.Context) (int, error) {
+		if calls.Add(1) < 3 {
+			return 0, fail
+		}
+		return 42, nil
+	}, RetryPolicy{Backoff: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := l.Get(ctx); err != fail {
+		t.Fatalf("first Get = %v", err)
+	}
+	// Within the backoff, the failure is served without a new attempt.
+	if _, err := l.Get(ctx); err != fail || calls.Load() != 1 {
+		t.Fatalf("Get in backoff = %v after %d calls", err, calls.Load())
+	}
+	time.Sleep(25 * time.Millisecond)
+	l.Get(ctx)
+	time.Sleep(25 * time.Millisecond)
+	if v, err := l.Get(ctx); v != 42 || err != nil || !l.Initialized() {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+	if v, _ := l.Get(ctx); v != 42 || calls.Load() != 3 {
+		t.Fatalf("reinitialized: %d calls", calls.Load())
+	}
+}
+
+func TestLazyMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	l := NewLazy(func(context.Context) (int, error) {
+		calls.Add(1)
+		return 0, errors.New("fail")
+	}, RetryPolicy{MaxAttempts: 2})
+	for i := 0; i < 4; i++ {
+		l.Get(context.Background())
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("%d attempts, want 2", calls.Load())
+	}
+}
+
+func TestLazyCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var calls atomic.Int32
+	l := NewLazy(func(ctx context.Context) (int, error) {
+		if calls.Add(1) > 1 {
+			return 1, nil
+		}
+		<-ctx.Done()
+		<-release
+		return 0, ctx.Err()
+	}, RetryPolicy{Backoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := l.Get(ctx)
+		errc <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Get = %v", err)
+	}
+
+	// A new caller starts over rather than joining the abandoned attempt,
+	// which is still running but not counted as a failure.
+	if v, err := l.Get(context.Background()); v != 1 || err != nil {
+		t.Fatalf("Get = %d, %v", v, err)
+	}
+}
@@ -0,0 +1,72 @@
+package once
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Once is a sync.Once that can be reset, so the next Do runs its function
+// again. The zero value is ready to use.
+type Once struct {
+	done atomic.Bool
+	mu   sync.Mutex
+}
+
+// Do calls f if Do hasn't completed since creation or the last Reset.
+// Concurrent calls wait for the one running f. If f panics, Do is not
+// considered complete.
+func (o *Once) Do(f func()) {
+	if o.done.Load() {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.done.Load() {
+		f()
+		o.done.Store(true)
+	}
+}
+
+// Reset makes the next Do call its function again. If a Do is running, Reset
+// waits for it, so the stale run can't mark the Once complete afterwards.
+func (o *Once) Reset() {
+	o.mu.Lock()
+	o.done.Store(false)
+	o.mu.Unlock()
+}
+
+// OnceValue memoizes the result of a function until it is reset.
+type OnceValue[T any] struct {
+	f   func() T
+	val atomic.Pointer[T]
+	mu  sync.Mutex
+}
+
+// NewOnceValue returns a OnceValue computing its value with f.
+func NewOnceValue[T any](f func() T) *OnceValue[T] {
+	return &OnceValue[T]{f: f}
+}
+
+// Get returns the memoized value, calling f first if there is none.
+// Concurrent calls wait for the one calling f.
+func (o *OnceValue[T]) Get() T {
+	if p := o.val.Load(); p != nil {
+		return *p
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if p := o.val.Load(); p != nil {
+		return *p
+	}
+	v := o.f()
+	o.val.Store(&v)
+	return v
+}
+
+// Reset drops the memoized value, so the next Get calls f again. If a Get is
+// calling f, Reset waits for it and drops its result too.
+func (o *OnceValue[T]) Reset() {
+	o.mu.Lock()
+	o.val.Store(nil)
+	o.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package once
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnceReset(t *testing.T) {
+	var o Once
+	calls := 0
+	f := func() { calls++ }
+	o.Do(f)
+	o.Do(f)
+	o.Reset()
+	o.Do(f)
+	if calls != 2 {
+		t.Fatalf("%d calls, want 2", calls)
+	}
+}
+
+func TestOnceValueReset(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		gen   int
+		start = make(chan struct{})
+		slow  = true
+	)
+	v := NewOnceValue(func() int {
+		mu.Lock()
+		gen++
+		g, s := gen, slow
+		mu.Unlock()
+		if s {
+			close(start)
+			time.Sleep(10 * time.Millisecond)
+		}
+		return g
+	})
+
+	// A Reset racing an in-flight Get discards the value being computed.
+	got := make(chan int)
+	go func() { got <- v.Get() }()
+	<-start
+	mu.Lock()
+	slow = false
+	mu.Unlock()
+	v.Reset()
+	if g := <-got; g != 1 {
+		t.Fatalf("in-flight Get = %d, want 1", g)
+	}
+	if g := v.Get(); g != 2 {
+		t.Fatalf("Get after Reset = %d, want 2", g)
+	}
+	if g := v.Get(); g != 2 {
+		t.Fatalf("memoized Get = %d, want 2", g)
+	}
+}
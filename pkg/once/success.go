@@ -0,0 +1,42 @@
+// Package once provides variants of sync.Once for initialization that can
+// fail or go stale.
+package once
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OnceSuccess runs a function until it succeeds once. Unlike sync.Once, a
+// failed attempt doesn't latch: the next Do tries again. The zero value is
+// ready to use.
+type OnceSuccess struct {
+	done atomic.Bool
+	mu   sync.Mutex
+}
+
+// Do calls f unless an earlier call succeeded, and returns f's error.
+// Concurrent calls wait for the attempt in progress; if it fails, the next of
+// them makes its own attempt. A panicking f counts as a failure.
+func (o *OnceSuccess) Do(f func() error) error {
+	if o.done.Load() {
+		return nil
+	}
+	return o.doSlow(f)
+}
+
+func (o *OnceSuccess) doSlow(f func() error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.done.Load() {
+		return nil
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	o.done.Store(true)
+	return nil
+}
+
+// Done reports whether a call has succeeded.
+func (o *OnceSuccess) Done() bool { return o.done.Load() }
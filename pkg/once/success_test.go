@@ -0,0 +1,36 @@
+package once
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestOnceSuccess(t *testing.T) {
+	var o OnceSuccess
+	calls := 0
+	fail := errors.New("fail")
+
+	if err := o.Do(func() error { calls++; return fail }); err != fail || o.Done() {
+		t.Fatalf("failed attempt: %v, done %v", err, o.Done())
+	}
+	func() {
+		defer func() { recover() }()
+		o.Do(func() error { calls++; panic("boom") })
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := o.Do(func() error { calls++; return nil }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 3 || !o.Done() {
+		t.Fatalf("%d calls, done %v; want 3, true", calls, o.Done())
+	}
+}
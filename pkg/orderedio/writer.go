@@ -0,0 +1,277 @@
+// Package orderedio assembles output produced concurrently in pieces, such as
+// download segments or log files written by parallel workers, in a fixed order.
+package orderedio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/notify"
+)
+
+var (
+	// ErrBufferFull is returned by Write under the Fail policy.
+	ErrBufferFull = errors.New("orderedio: buffer full")
+	// ErrFinished is returned for a ticket already finished or canceled.
+	ErrFinished = errors.New("orderedio: ticket finished")
+)
+
+// Policy decides what Write does with early data once MaxBuffer is reached.
+type Policy int
+
+const (
+	// Block waits until earlier tickets drain the buffer.
+	Block Policy = iota
+	// Spill writes the data to a temporary file instead.
+	Spill
+	// Fail returns ErrBufferFull.
+	Fail
+)
+
+// Config configures a Writer. The zero value buffers without limit.
+type Config struct {
+	// MaxBuffer caps the bytes held in memory for tickets whose turn has not
+	// come; zero means no cap.
+	MaxBuffer int64
+	// Policy applies when MaxBuffer is reached.
+	Policy Policy
+	// SpillDir is where Spill creates its files; empty means os.TempDir.
+	SpillDir string
+}
+
+// Ticket is a reserved place in the output.
+type Ticket struct {
+	id uint64
+}
+
+// Writer writes the data of each ticket to the underlying writer in ticket
+// order. The ticket whose turn it is writes straight through; data of later
+// tickets is held until every earlier ticket is finished.
+type Writer struct {
+	dst io.Writer
+	cfg Config
+
+	writeMu sync.Mutex // serializes writes to dst; taken before mu
+
+	mu       sync.Mutex
+	next     uint64
+	head     uint64
+	segs     map[uint64]*segment
+	buffered int64
+	err      error
+	space    notify.Notifier
+}
+
+type segment struct {
+	mem   bytes.Buffer
+	file  *os.File // spilled data, after mem
+	done  bool
+	abort bool
+}
+
+// NewWriter returns a Writer writing to dst.
+func NewWriter(dst io.Writer, cfg Config) *Writer {
+	return &Writer{dst: dst, cfg: cfg, segs: make(map[uint64]*segment)}
+}
+
+// Reserve returns the next place in the output.
+func (w *Writer) Reserve() Ticket {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t := Ticket{w.next}
+	w.segs[t.id] = &segment{}
+	w.next++
+	return t
+}
+
+// Write appends p to t's data. Writes for one ticket must not run concurrently.
+func (w *Writer) Write(t Ticket, p []byte) (int, error) {
+	return w.WriteContext(context.Background(), t, p)
+}
+
+// WriteContext is like Write but gives up with ctx while the Block policy
+// waits for room.
+func (w *Writer) WriteContext(ctx context.Context, t Ticket, p []byte) (int, error) {
+	w.mu.Lock()
+	for {
+		s, err := w.segment(t)
+		if err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+		if t.id == w.head {
+			w.mu.Unlock()
+			return w.writeHead(s, p)
+		}
+		if s.file != nil {
+			n, err := s.file.Write(p)
+			w.mu.Unlock()
+			return n, err
+		}
+		if w.cfg.MaxBuffer <= 0 || w.buffered+int64(len(p)) <= w.cfg.MaxBuffer || w.buffered == 0 {
+			s.mem.Write(p)
+			w.buffered += int64(len(p))
+			w.mu.Unlock()
+			return len(p), nil
+		}
+		switch w.cfg.Policy {
+		case Fail:
+			w.mu.Unlock()
+			return 0, ErrBufferFull
+		case Spill:
+			f, err := os.CreateTemp(w.cfg.SpillDir, "orderedio-*")
+			if err != nil {
+				w.mu.Unlock()
+				return 0, err
+			}
+			s.file = f
+			continue
+		}
+		version := w.space.Version()
+		w.mu.Unlock()
+		if _, err := w.space.Wait(ctx, version); err != nil {
+			return 0, err
+		}
+		w.mu.Lock()
+	}
+}
+
+// segment returns t's live segment; mu must be held.
+func (w *Writer) segment(t Ticket) (*segment, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	s, ok := w.segs[t.id]
+	if !ok || s.done {
+		return nil, ErrFinished
+	}
+	return s, nil
+}
+
+// writeHead writes p through for the ticket whose turn it is, after any data
+// it buffered before its turn came.
+func (w *Writer) writeHead(s *segment, p []byte) (int, error) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if err := w.drain(s); err != nil {
+		return 0, err
+	}
+	n, err := w.dst.Write(p)
+	if err != nil {
+		w.fail(err)
+	}
+	return n, err
+}
+
+// Finish marks t's data complete. If t has the turn, Finish writes out
+// every finished ticket after it, and the data buffered so far by the first
+// unfinished one, which gets the turn.
+func (w *Writer) Finish(t Ticket) error {
+	return w.end(t, false)
+}
+
+// Cancel drops t and its data; the output goes on with the next ticket.
+func (w *Writer) Cancel(t Ticket) error {
+	return w.end(t, true)
+}
+
+func (w *Writer) end(t Ticket, abort bool) error {
+	w.mu.Lock()
+	s, err := w.segment(t)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	s.done, s.abort = true, abort
+	head := t.id == w.head
+	w.mu.Unlock()
+	if !head {
+		return nil
+	}
+
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	for {
+		w.mu.Lock()
+		s, ok := w.segs[w.head]
+		w.mu.Unlock()
+		if !ok {
+			return nil // not reserved yet
+		}
+		if err := w.drain(s); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		if !s.done {
+			w.mu.Unlock()
+			return nil
+		}
+		delete(w.segs, w.head)
+		w.head++
+		w.space.Broadcast() // a blocked Write may now have the turn
+		w.mu.Unlock()
+	}
+}
+
+// drain writes out, or drops if aborted, the data s buffered; writeMu must
+// be held.
+func (w *Writer) drain(s *segment) error {
+	for {
+		w.mu.Lock()
+		if w.err != nil {
+			w.mu.Unlock()
+			return w.err
+		}
+		data := s.mem.Bytes()
+		s.mem = bytes.Buffer{}
+		w.buffered -= int64(len(data))
+		f := s.file
+		s.file = nil
+		abort := s.abort
+		if len(data) > 0 {
+			w.space.Broadcast()
+		}
+		w.mu.Unlock()
+
+		if len(data) == 0 && f == nil {
+			return nil
+		}
+		var err error
+		if !abort {
+			if _, err = w.dst.Write(data); err == nil && f != nil {
+				if _, err = f.Seek(0, io.SeekStart); err == nil {
+					_, err = io.Copy(w.dst, f)
+				}
+			}
+		}
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+		if err != nil {
+			w.fail(err)
+			return err
+		}
+	}
+}
+
+// fail records the first write error, after which every call returns it.
+func (w *Writer) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+		w.space.Broadcast()
+	}
+}
+
+// Buffered returns the bytes held in memory for tickets waiting their turn.
+func (w *Writer) Buffered() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buffered
+}
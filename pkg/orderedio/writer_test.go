@@ -0,0 +1,96 @@
+package orderedio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// assemble writes n segments from concurrent workers in random chunks and
+// returns the output and the expected output.
+func assemble(t *testing.T, cfg Config, n int) (got, want string) {
+	var dst bytes.Buffer
+	w := NewWriter(&dst, cfg)
+	tickets := make([]Ticket, n)
+	for i := range tickets {
+		tickets[i] = w.Reserve()
+		want += fmt.Sprintf("<segment %d: %s>", i, bytes.Repeat([]byte{'a' + byte(i%26)}, 10*i))
+	}
+
+	var wg sync.WaitGroup
+	for i := n - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf("<segment %d: %s>", i, bytes.Repeat([]byte{'a' + byte(i%26)}, 10*i)))
+			for len(data) > 0 {
+				k := min(len(data), 1+rand.Intn(16))
+				if _, err := w.Write(tickets[i], data[:k]); err != nil {
+					t.Error(err)
+					return
+				}
+				data = data[k:]
+				time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+			}
+			if err := w.Finish(tickets[i]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if w.Buffered() != 0 {
+		t.Fatalf("%d bytes left buffered", w.Buffered())
+	}
+	return dst.String(), want
+}
+
+func TestWriter(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{MaxBuffer: 64, Policy: Block},
+		{MaxBuffer: 64, Policy: Spill, SpillDir: t.TempDir()},
+	} {
+		got, want := assemble(t, cfg, 40)
+		if got != want {
+			t.Fatalf("%+v: output out of order:\n%s", cfg, got)
+		}
+		if cfg.SpillDir != "" {
+			if files, _ := os.ReadDir(cfg.SpillDir); len(files) != 0 {
+				t.Fatalf("%d spill files left", len(files))
+			}
+		}
+	}
+}
+
+func TestFailAndCancel(t *testing.T) {
+	var dst bytes.Buffer
+	w := NewWriter(&dst, Config{MaxBuffer: 4, Policy: Fail})
+	t0, t1, t2 := w.Reserve(), w.Reserve(), w.Reserve()
+
+	w.Write(t2, []byte("cc"))
+	if _, err := w.Write(t1, []byte("bbb")); err != ErrBufferFull {
+		t.Fatalf("Write over the cap = %v", err)
+	}
+	w.Write(t1, []byte("bb"))
+	w.Cancel(t1)
+	w.Finish(t2)
+	if dst.Len() != 0 {
+		t.Fatal("wrote ahead of the first ticket")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.WriteContext(ctx, t0, []byte("aa")) // the head never waits
+	w.Finish(t0)
+	if dst.String() != "aacc" {
+		t.Fatalf("output %q, want %q", dst.String(), "aacc")
+	}
+	if _, err := w.Write(t0, []byte("x")); err != ErrFinished {
+		t.Fatalf("Write after Finish = %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package orderedpool runs tasks on a fixed set of workers and delivers their
+// results strictly in submission order.
+package orderedpool
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/future"
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// ErrClosed fails the futures of tasks submitted after Close.
+var ErrClosed = errors.New("orderedpool: closed")
+
+// Pool executes tasks concurrently on its workers. Each task takes an
+// ordermutex ticket on submission; after running, its worker locks that
+// ticket to deliver the result, so results are delivered one at a time in
+// submission order however the tasks interleave.
+type Pool[R any] struct {
+	m       *ordermutex.Mutex
+	deliver func(R, error)
+
+	submitMu sync.Mutex // keeps ticket order and queue order the same
+	jobs     chan job[R]
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+type job[R any] struct {
+	t    ordermutex.Ticket
+	task func() (R, error)
+	p    *future.Promise[R]
+}
+
+// New starts a pool of workers. deliver, if not nil, is called with every
+// result in submission order, before the task's future completes.
+func New[R any](workers int, deliver func(R, error)) *Pool[R] {
+	if workers <= 0 {
+		panic("orderedpool: New called with non-positive workers")
+	}
+	p := &Pool[R]{
+		m:       ordermutex.NewMutex(),
+		deliver: deliver,
+		jobs:    make(chan job[R], workers),
+	}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues task, blocking while every worker is busy and the queue is
+// full. The returned future completes in submission order; a panic in task
+// fails it with a *future.PanicError.
+func (p *Pool[R]) Submit(task func() (R, error)) *future.Future[R] {
+	pr := future.NewPromise[R]()
+	p.submitMu.Lock()
+	defer p.submitMu.Unlock()
+	if p.closed {
+		pr.Fail(ErrClosed)
+		return pr.Future()
+	}
+	// Workers take jobs in ticket order, so the oldest running job can
+	// always deliver and no worker waits on a job still queued.
+	p.jobs <- job[R]{t: p.m.GetTicket(), task: task, p: pr}
+	return pr.Future()
+}
+
+func (p *Pool[R]) work() {
+	defer p.wg.Done()
+	for j := range p.jobs {
+		v, err := run(j.task)
+
+		p.m.Lock(j.t)
+		if p.deliver != nil {
+			p.deliver(v, err)
+		}
+		if err != nil {
+			j.p.Fail(err)
+		} else {
+			j.p.Complete(v)
+		}
+		p.m.Unlock(j.t)
+	}
+}
+
+func run[R any](task func() (R, error)) (v R, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &future.PanicError{Value: r}
+		}
+	}()
+	return task()
+}
+
+// Close stops accepting tasks and waits for the submitted ones to be
+// delivered.
+func (p *Pool[R]) Close() {
+	p.submitMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.submitMu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,58 @@
+package orderedpool
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/future"
+)
+
+func TestOrder(t *testing.T) {
+	var got []int
+	p := New(4, func(v int, err error) { got = append(got, v) })
+	for i := 0; i < 50; i++ {
+		p.Submit(func() (int, error) {
+			time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+			return i, nil
+		})
+	}
+	p.Close()
+	if len(got) != 50 {
+		t.Fatalf("delivered %d results", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("result %d delivered at position %d", v, i)
+		}
+	}
+}
+
+func TestFutures(t *testing.T) {
+	p := New[int](2, nil)
+	defer p.Close()
+	ctx := context.Background()
+
+	slow := p.Submit(func() (int, error) { time.Sleep(20 * time.Millisecond); return 1, nil })
+	fast := p.Submit(func() (int, error) { panic("boom") })
+
+	// The fast task finished first but completes after the slow one.
+	<-fast.Done()
+	if _, _, ok := slow.Result(); !ok {
+		t.Fatal("later future completed before an earlier one")
+	}
+	var pe *future.PanicError
+	if _, err := fast.Get(ctx); !errors.As(err, &pe) {
+		t.Fatalf("panicking task = %v", err)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	p := New[int](1, nil)
+	p.Close()
+	if _, err := p.Submit(func() (int, error) { return 0, nil }).Get(context.Background()); err != ErrClosed {
+		t.Fatalf("Submit after Close = %v", err)
+	}
+}
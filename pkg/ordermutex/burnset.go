@@ -0,0 +1,99 @@
+package ordermutex
+
+import (
+	"slices"
+	"sort"
+)
+
+// burnSet holds a set of tickets, such as the burned ones, as sorted,
+// coalesced half-open spans, so memory grows with the number of gaps and
+// cur can jump a whole span at once.
+type burnSet struct {
+	spans []span
+	n     uint64
+}
+
+type span struct{ lo, hi uint64 }
+
+func (b *burnSet) len() int { return int(b.n) }
+
+func (b *burnSet) has(id uint64) bool {
+	i := sort.Search(len(b.spans), func(i int) bool { return b.spans[i].hi > id })
+	return i < len(b.spans) && b.spans[i].lo <= id
+}
+
+// add burns id, which must not be burned yet.
+func (b *burnSet) add(id uint64) {
+	b.n++
+	i := sort.Search(len(b.spans), func(i int) bool { return b.spans[i].lo > id })
+	left := i > 0 && b.spans[i-1].hi == id
+	right := i < len(b.spans) && b.spans[i].lo == id+1
+	switch {
+	case left && right:
+		b.spans[i-1].hi = b.spans[i].hi
+		b.spans = slices.Delete(b.spans, i, i+1)
+	case left:
+		b.spans[i-1].hi++
+	case right:
+		b.spans[i].lo--
+	default:
+		b.spans = slices.Insert(b.spans, i, span{id, id + 1})
+	}
+}
+
+// skip returns the first ticket at or after cur that is not burned,
+// forgetting the burned ones it passes.
+func (b *burnSet) skip(cur uint64) uint64 {
+	if len(b.spans) == 0 || b.spans[0].lo != cur {
+		return cur
+	}
+	s := b.spans[0]
+	b.spans = b.spans[1:]
+	b.n -= s.hi - s.lo
+	return s.hi
+}
+
+// count returns the number of burned tickets in [lo, hi).
+func (b *burnSet) count(lo, hi uint64) uint64 {
+	var n uint64
+	i := sort.Search(len(b.spans), func(i int) bool { return b.spans[i].hi > lo })
+	for ; i < len(b.spans) && b.spans[i].lo < hi; i++ {
+		n += min(b.spans[i].hi, hi) - max(b.spans[i].lo, lo)
+	}
+	return n
+}
+
+// addRange adds [lo, hi), which must lie above every ticket in the set.
+func (b *burnSet) addRange(lo, hi uint64) {
+	if lo >= hi {
+		return
+	}
+	b.n += hi - lo
+	if k := len(b.spans); k > 0 && b.spans[k-1].hi == lo {
+		b.spans[k-1].hi = hi
+		return
+	}
+	b.spans = append(b.spans, span{lo, hi})
+}
+
+// remove takes id out of the set and reports whether it was there.
+func (b *burnSet) remove(id uint64) bool {
+	i := sort.Search(len(b.spans), func(i int) bool { return b.spans[i].hi > id })
+	if i == len(b.spans) || b.spans[i].lo > id {
+		return false
+	}
+	b.n--
+	s := b.spans[i]
+	switch {
+	case s.lo == id && s.hi == id+1:
+		b.spans = slices.Delete(b.spans, i, i+1)
+	case s.lo == id:
+		b.spans[i].lo++
+	case s.hi == id+1:
+		b.spans[i].hi--
+	default:
+		b.spans[i].hi = id
+		b.spans = slices.Insert(b.spans, i+1, span{id + 1, s.hi})
+	}
+	return true
+}
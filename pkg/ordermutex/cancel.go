@@ -0,0 +1,45 @@
+package ordermutex
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrCanceled is the panic value of Lock, and the error of LockErr, for a
+// ticket whose wait was canceled by CancelLock.
+var ErrCanceled = errors.New("ordermutex: lock canceled")
+
+// CancelLock evicts the goroutine parked in Lock for t, if any: its Lock
+// panics with ErrCanceled, or its LockErr returns it, and t is burned, as
+// if returned. A callback pending in WhenMyTurn is dropped the same way.
+// CancelLock reports whether it found a waiter; a ticket that holds the
+// lock or has not called Lock yet is left alone.
+//
+// Tickets made by Share, GetGroupTicket and Split can't be canceled.
+func (m *Mutex) CancelLock(t Ticket) bool {
+	switch t.(type) {
+	case *sharedTicket, *groupMember, *subTicket:
+		return false
+	}
+	id := t.ID()
+	if m.passedFast(id) {
+		return false
+	}
+
+	m.lock()
+	defer m.unlock()
+	w, ok := m.waiters.take(id)
+	if !ok {
+		return false
+	}
+	if m.burn(id) {
+		m.release(id)
+	}
+	if w.run != nil {
+		return true // WhenMyTurn: nobody to wake
+	}
+	w.canceled = true
+	raceRelease(unsafe.Pointer(w))
+	m.wake(w)
+	return true
+}
@@ -0,0 +1,95 @@
+// Package chaos wraps an OrderMutex with seeded fault injection, to shake out
+// hidden timing assumptions in code built on top of it.
+//
+// Injection only happens in binaries built with -tags chaos; otherwise Wrap
+// returns the mutex unchanged.
+package chaos
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Config tunes the injected faults. Rates are probabilities in [0, 1].
+type Config struct {
+	// Seed makes a run reproducible, as far as the scheduler allows.
+	Seed uint64
+	// MaxDelay bounds the random delay added before Lock and after each wakeup.
+	MaxDelay time.Duration
+	// ReorderRate is the chance that GetTicket stalls up to MaxDelay before
+	// issuing, so concurrent callers get tickets in a shuffled order.
+	ReorderRate float64
+	// BurnRate is the chance that GetTicket first issues and returns a phantom
+	// ticket, leaving a burned gap in the sequence.
+	BurnRate float64
+}
+
+// Mutex is an OrderMutex with injected faults.
+type Mutex struct {
+	m   ordermutex.OrderMutex
+	cfg Config
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+var _ ordermutex.OrderMutex = (*Mutex)(nil)
+
+// Wrap returns m with the faults described by cfg injected, or m itself
+// in builds without the chaos tag.
+func Wrap(m ordermutex.OrderMutex, cfg Config) ordermutex.OrderMutex {
+	if !enabled {
+		return m
+	}
+	return &Mutex{
+		m:   m,
+		cfg: cfg,
+		rnd: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+	}
+}
+
+func (c *Mutex) GetTicket() ordermutex.Ticket {
+	if c.roll(c.cfg.ReorderRate) {
+		c.sleep()
+	}
+	if c.roll(c.cfg.BurnRate) {
+		c.m.ReturnTicket(c.m.GetTicket())
+	}
+	return c.m.GetTicket()
+}
+
+func (c *Mutex) Lock(t ordermutex.Ticket) {
+	c.sleep()
+	c.m.Lock(t)
+	c.sleep()
+}
+
+func (c *Mutex) Unlock(t ordermutex.Ticket) {
+	c.m.Unlock(t)
+}
+
+func (c *Mutex) ReturnTicket(t ordermutex.Ticket) {
+	c.m.ReturnTicket(t)
+}
+
+func (c *Mutex) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rnd.Float64() < rate
+}
+
+func (c *Mutex) sleep() {
+	if c.cfg.MaxDelay <= 0 {
+		return
+	}
+	c.mu.Lock()
+	d := time.Duration(c.rnd.Int64N(int64(c.cfg.MaxDelay)))
+	c.mu.Unlock()
+	time.Sleep(d)
+}
@@ -0,0 +1,46 @@
+package chaos
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Run with -tags chaos to exercise the injected faults.
+func TestWrapKeepsOrder(t *testing.T) {
+	m := Wrap(ordermutex.NewMutex(), Config{
+		Seed:        1,
+		MaxDelay:    time.Millisecond,
+		ReorderRate: 0.5,
+		BurnRate:    0.5,
+	})
+
+	const n = 50
+	tickets := make([]ordermutex.Ticket, n)
+	for i := range tickets {
+		tickets[i] = m.GetTicket()
+	}
+
+	var (
+		wg  sync.WaitGroup
+		got []uint64
+	)
+	for i := n - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(tk ordermutex.Ticket) {
+			defer wg.Done()
+			m.Lock(tk)
+			got = append(got, tk.ID())
+			m.Unlock(tk)
+		}(tickets[i])
+	}
+	wg.Wait()
+
+	for i := range got {
+		if got[i] != tickets[i].ID() {
+			t.Fatalf("lock %d went to ticket %d, want %d", i, got[i], tickets[i].ID())
+		}
+	}
+}
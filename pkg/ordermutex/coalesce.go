@@ -0,0 +1,89 @@
+package ordermutex
+
+import "time"
+
+// Producer issues coalescable tickets: a holder of one of them can extend
+// its critical section over the tickets right behind it issued by the same
+// Producer, without a wake-up per ticket. See Mutex.Coalesce.
+type Producer struct {
+	m  *Mutex
+	id uint64
+}
+
+// NewProducer returns a new producer of coalescable tickets for m.
+func (m *Mutex) NewProducer() *Producer {
+	return &Producer{m: m, id: m.producers.Add(1)}
+}
+
+// GetTicket is like Mutex.GetTicket, marking the ticket as p's.
+func (p *Producer) GetTicket() Ticket {
+	m := p.m
+	if m.slots != nil {
+		m.slots <- struct{}{}
+	}
+	m.lock()
+	defer m.unlock()
+	t := m.issue()
+	if m.producerOf == nil {
+		m.producerOf = make(map[uint64]uint64)
+	}
+	m.producerOf[t.ID()] = p.id
+	return t
+}
+
+// Coalesce hands the lock held by t straight to the next ticket in sequence,
+// if both were issued by the same Producer and the next one is neither
+// returned nor parked in Lock, and no promoted ticket is waiting to go
+// first, and returns that ticket. The caller then holds
+// it as if it had locked it, and must Unlock or Coalesce it instead of
+// locking it. Otherwise Coalesce reports false and t keeps the lock.
+//
+// Coalesce panics if t does not hold the lock.
+func (m *Mutex) Coalesce(t Ticket) (Ticket, bool) {
+	switch t.(type) {
+	case *sharedTicket, *groupMember, *subTicket:
+		return nil, false
+	}
+	id := t.ID()
+
+	m.lock()
+	if m.strict {
+		if err := m.checkUnlock(t); err != nil {
+			m.unlock()
+			m.misuse(err)
+			return nil, false
+		}
+	}
+	if id != m.turn() || !m.held {
+		m.unlock()
+		panic("Coalesce called for a ticket that does not hold the lock")
+	}
+	next := id + 1
+	owner, ok := m.producerOf[id]
+	nextOwner, nextOK := m.producerOf[next]
+	_, waiting := m.waiters.get(next)
+	if !ok || !nextOK || owner != nextOwner || m.overActive || len(m.promoted) > 0 || waiting || m.burned.has(next) {
+		m.unlock()
+		return nil, false
+	}
+
+	delete(m.producerOf, id)
+	m.unwatch()
+	if len(m.hooks) > 0 {
+		now := time.Now()
+		m.emit(hookUnlock, id, now, now.Sub(m.heldAt))
+		m.emit(hookLockAcquired, next, now, 0)
+	}
+	m.heldAt = m.now()
+	m.cur = next
+	m.release(id)
+	m.advanceAndWakeNext()
+	nt := m.ticket(next)
+	m.unlock()
+
+	m.debugRecord(next, debugLocked)
+	if m.watchdog != nil {
+		m.watch(next)
+	}
+	return nt, true
+}
@@ -0,0 +1,104 @@
+package ordermutex
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// debugKeep bounds how many tickets keep debug records.
+const debugKeep = 1 << 12
+
+// DebugRecord captures who performed a ticket transition.
+type DebugRecord struct {
+	Goroutine uint64
+	Time      time.Time
+	Stack     []byte
+}
+
+// DebugInfo holds the records of one ticket; transitions that did not happen are nil.
+type DebugInfo struct {
+	Issued *DebugRecord
+	Locked *DebugRecord
+	Burned *DebugRecord
+}
+
+// debugState is only populated in builds with the ordermutexdebug tag.
+type debugState struct {
+	mu    sync.Mutex
+	info  map[uint64]*DebugInfo
+	order []uint64
+}
+
+// DebugInfo returns the records of ticket id. It always reports false unless
+// the binary is built with -tags ordermutexdebug; only the most recent
+// tickets are kept.
+func (m *Mutex) DebugInfo(id uint64) (DebugInfo, bool) {
+	if !debugEnabled {
+		return DebugInfo{}, false
+	}
+	d := &m.debug
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info, ok := d.info[id]
+	if !ok {
+		return DebugInfo{}, false
+	}
+	return *info, true
+}
+
+type debugKind int
+
+const (
+	debugIssued debugKind = iota
+	debugLocked
+	debugBurned
+)
+
+func (m *Mutex) debugRecord(id uint64, k debugKind) {
+	if !debugEnabled {
+		return
+	}
+	buf := make([]byte, 4096)
+	buf = buf[:runtime.Stack(buf, false)]
+	rec := &DebugRecord{Goroutine: goroutineID(buf), Time: time.Now(), Stack: buf}
+
+	d := &m.debug
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.info == nil {
+		d.info = make(map[uint64]*DebugInfo)
+	}
+	info, ok := d.info[id]
+	if !ok {
+		info = &DebugInfo{}
+		d.info[id] = info
+		d.order = append(d.order, id)
+		if len(d.order) > debugKeep {
+			delete(d.info, d.order[0])
+			d.order = d.order[1:]
+		}
+	}
+	switch k {
+	case debugIssued:
+		info.Issued = rec
+	case debugLocked:
+		info.Locked = rec
+	case debugBurned:
+		info.Burned = rec
+	}
+}
+
+// goroutineID parses the "goroutine N [...]" header of a stack trace.
+func goroutineID(stack []byte) uint64 {
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(stack[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
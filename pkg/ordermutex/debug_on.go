@@ -0,0 +1,5 @@
+//go:build ordermutexdebug
+
+package ordermutex
+
+const debugEnabled = true
@@ -0,0 +1,316 @@
+// Package etcd implements an OrderMutex shared by processes through etcd,
+// for strict cross-replica ordering.
+//
+//	s, _ := concurrency.NewSession(cli, concurrency.WithTTL(10))
+//	m := etcd.New(s, etcd.Config{Prefix: "/locks/ingest"})
+//	t := m.GetTicket()
+//	m.Lock(t)
+//	defer m.Unlock(t)
+//
+// A ticket is a key under Prefix, and its ID is the key's create revision, so
+// the order is the order of writes in the cluster. Lock watches only the key
+// issued just before its own, and returns once no earlier key is left.
+// Unlock and ReturnTicket delete the key.
+//
+// Keys are attached to the session's lease: if a replica crashes, its lease
+// expires and its tickets are burned, so the order moves on without it.
+package etcd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+var (
+	// ErrTicketGone is returned by Lock for a ticket that was unlocked,
+	// returned, or burned when its lease expired.
+	ErrTicketGone = errors.New("ordermutex/etcd: ticket unlocked, returned or expired")
+	// ErrSessionDone is returned by Lock once the session has ended.
+	ErrSessionDone = errors.New("ordermutex/etcd: session done")
+)
+
+// Config configures a Mutex.
+type Config struct {
+	// Prefix is the key prefix of the order; mutexes with the same Prefix
+	// on the same cluster share it.
+	Prefix string
+	// RetryBackoff is the first delay before the OrderMutex methods retry a
+	// failed etcd call; it doubles up to 32 times that. Zero means 50ms.
+	RetryBackoff time.Duration
+	// OnError, if set, is called with every etcd error the OrderMutex
+	// methods retry.
+	OnError func(error)
+}
+
+// Mutex is an OrderMutex backed by etcd. The OrderMutex methods retry etcd
+// errors until they succeed; the Context variants return them instead.
+type Mutex struct {
+	s      *concurrency.Session
+	cli    *clientv3.Client
+	prefix string
+	cfg    Config
+}
+
+var _ ordermutex.OrderMutex = (*Mutex)(nil)
+
+// New returns a Mutex for the order under cfg.Prefix, issuing tickets under
+// the lease of s.
+func New(s *concurrency.Session, cfg Config) *Mutex {
+	if cfg.Prefix == "" {
+		panic("etcd: New called with empty Prefix")
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	return &Mutex{s: s, cli: s.Client(), prefix: cfg.Prefix + "/", cfg: cfg}
+}
+
+// Wire format, shared with ordermutex: version byte, big-endian ticket ID.
+const (
+	ticketVersion = 1
+	ticketSize    = 1 + 8
+)
+
+// ticket carries its key; tickets from elsewhere are looked up by ID.
+type ticket struct {
+	id  uint64
+	key string
+}
+
+func (t ticket) ID() uint64 { return t.id }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, ticketSize)
+	b[0] = ticketVersion
+	binary.BigEndian.PutUint64(b[1:], t.id)
+	return b, nil
+}
+
+// Adopt decodes a ticket serialized with MarshalBinary, typically by another
+// replica, so it can be locked or returned here.
+func (m *Mutex) Adopt(ctx context.Context, data []byte) (ordermutex.Ticket, error) {
+	if len(data) != ticketSize || data[0] != ticketVersion {
+		return nil, ordermutex.ErrInvalidTicket
+	}
+	id := binary.BigEndian.Uint64(data[1:])
+	key, err := m.keyOf(ctx, ticketID(id))
+	if err != nil {
+		return nil, err
+	}
+	return ticket{id: id, key: key}, nil
+}
+
+// ticketID is a ticket known only by its ID.
+type ticketID uint64
+
+func (t ticketID) ID() uint64 { return uint64(t) }
+
+func (t ticketID) MarshalBinary() ([]byte, error) {
+	return ticket{id: uint64(t)}.MarshalBinary()
+}
+
+// keyOf returns the key of t, looking it up if t was not issued here.
+func (m *Mutex) keyOf(ctx context.Context, t ordermutex.Ticket) (string, error) {
+	if tk, ok := t.(ticket); ok {
+		return tk.key, nil
+	}
+	id := int64(t.ID())
+	resp, err := m.cli.Get(ctx, m.prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithMinCreateRev(id), clientv3.WithMaxCreateRev(id))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", ErrTicketGone
+	}
+	return string(resp.Kvs[0].Key), nil
+}
+
+func (m *Mutex) GetTicket() ordermutex.Ticket {
+	key := m.newKey()
+	var t ordermutex.Ticket
+	m.retry(func(ctx context.Context) (err error) {
+		t, err = m.issue(ctx, key)
+		return err
+	})
+	return t
+}
+
+// GetTicketContext issues a ticket. If it fails the ticket may still have
+// been issued, and the order waits on it until the session's lease expires;
+// GetTicket avoids this by retrying the same request.
+func (m *Mutex) GetTicketContext(ctx context.Context) (ordermutex.Ticket, error) {
+	return m.issue(ctx, m.newKey())
+}
+
+func (m *Mutex) newKey() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return m.prefix + hex.EncodeToString(b[:])
+}
+
+// issue creates key, or finds it created by an earlier attempt.
+func (m *Mutex) issue(ctx context.Context, key string) (ordermutex.Ticket, error) {
+	resp, err := m.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, "", clientv3.WithLease(m.s.Lease()))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	id := resp.Header.Revision
+	if !resp.Succeeded {
+		id = resp.Responses[0].GetResponseRange().Kvs[0].CreateRevision
+	}
+	return ticket{id: uint64(id), key: key}, nil
+}
+
+// Lock blocks until t has the turn. It panics if t is gone.
+func (m *Mutex) Lock(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		err := m.lock(ctx, t)
+		if errors.Is(err, ErrTicketGone) || errors.Is(err, ErrSessionDone) {
+			panic(err)
+		}
+		return err
+	})
+}
+
+// LockContext blocks until t has the turn or ctx is done. If ctx ends first,
+// t is returned as by ReturnTicket, so the order does not wait on it; if
+// that fails too, the error says so and t stays until its lease expires.
+func (m *Mutex) LockContext(ctx context.Context, t ordermutex.Ticket) error {
+	err := m.lock(ctx, t)
+	if err != nil && err == ctx.Err() {
+		if rerr := m.ReturnTicketContext(context.WithoutCancel(ctx), t); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+	}
+	return err
+}
+
+func (m *Mutex) lock(ctx context.Context, t ordermutex.Ticket) error {
+	key, err := m.keyOf(ctx, t)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.s.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		// Read our key and the one issued just before it in one revision.
+		resp, err := m.cli.Txn(ctx).Then(
+			clientv3.OpGet(key),
+			clientv3.OpGet(m.prefix, append(clientv3.WithLastCreate(),
+				clientv3.WithMaxCreateRev(int64(t.ID())-1))...),
+		).Commit()
+		if err != nil {
+			return m.lockErr(ctx, err)
+		}
+		if len(resp.Responses[0].GetResponseRange().Kvs) == 0 {
+			return ErrTicketGone
+		}
+		prev := resp.Responses[1].GetResponseRange().Kvs
+		if len(prev) == 0 {
+			return nil
+		}
+		if err := m.waitDelete(ctx, string(prev[0].Key), resp.Header.Revision); err != nil {
+			return m.lockErr(ctx, err)
+		}
+	}
+}
+
+// lockErr reports the session ending rather than the cancellation it causes.
+func (m *Mutex) lockErr(ctx context.Context, err error) error {
+	select {
+	case <-m.s.Done():
+		return ErrSessionDone
+	default:
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// waitDelete waits until key is deleted after revision rev.
+func (m *Mutex) waitDelete(ctx context.Context, key string, rev int64) error {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	for resp := range m.cli.Watch(ctx, key, clientv3.WithRev(rev+1), clientv3.WithFilterPut()) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		if len(resp.Events) > 0 {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// Unlock passes the turn on.
+func (m *Mutex) Unlock(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		return m.UnlockContext(ctx, t)
+	})
+}
+
+// UnlockContext passes the turn on. Unlocking a ticket again is a no-op, so
+// a failed call can be retried.
+func (m *Mutex) UnlockContext(ctx context.Context, t ordermutex.Ticket) error {
+	return m.ReturnTicketContext(ctx, t)
+}
+
+// ReturnTicket cancels a ticket that has not locked, from any replica.
+// Returning a ticket that is gone is a no-op.
+func (m *Mutex) ReturnTicket(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		return m.ReturnTicketContext(ctx, t)
+	})
+}
+
+// ReturnTicketContext is like ReturnTicket but gives up with ctx.
+func (m *Mutex) ReturnTicketContext(ctx context.Context, t ordermutex.Ticket) error {
+	key, err := m.keyOf(ctx, t)
+	if err == ErrTicketGone {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = m.cli.Delete(ctx, key)
+	return err
+}
+
+// retry runs op until it succeeds, backing off between failures.
+func (m *Mutex) retry(op func(context.Context) error) {
+	backoff := m.cfg.RetryBackoff
+	for {
+		err := op(context.Background())
+		if err == nil {
+			return
+		}
+		if m.cfg.OnError != nil {
+			m.cfg.OnError(err)
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 32*m.cfg.RetryBackoff)
+	}
+}
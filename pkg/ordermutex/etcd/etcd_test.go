@@ -0,0 +1,174 @@
+package etcd
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// freeURL returns a local URL on a port nobody listens on.
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// cluster starts a single-member etcd and returns a client factory.
+func cluster(t *testing.T) func() *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	peer, client := freeURL(t), freeURL(t)
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{client}, []url.URL{client}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd did not start")
+	}
+	return func() *clientv3.Client {
+		cli, err := clientv3.New(clientv3.Config{Endpoints: []string{client.Host}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cli.Close() })
+		return cli
+	}
+}
+
+// replica returns a mutex on the shared test order with its own session.
+func replica(t *testing.T, client func() *clientv3.Client) (*Mutex, *concurrency.Session) {
+	s, err := concurrency.NewSession(client(), concurrency.WithTTL(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return New(s, Config{Prefix: "/test"}), s
+}
+
+func TestEtcd(t *testing.T) {
+	client := cluster(t)
+	t.Run("Order", func(t *testing.T) { testOrder(t, client) })
+	t.Run("LockContext", func(t *testing.T) { testLockContext(t, client) })
+	t.Run("LeaseExpiry", func(t *testing.T) { testLeaseExpiry(t, client) })
+	t.Run("Adopt", func(t *testing.T) { testAdopt(t, client) })
+}
+
+func testOrder(t *testing.T, client func() *clientv3.Client) {
+	a, _ := replica(t, client)
+	b, _ := replica(t, client)
+	ms := []*Mutex{a, b}
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		m := ms[i%2]
+		tk := m.GetTicket()
+		if i == 3 {
+			a.ReturnTicket(tk)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Duration(8-i) * 5 * time.Millisecond) // lock in reverse
+			m.Lock(tk)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			m.Unlock(tk)
+		}()
+	}
+	wg.Wait()
+	if got := fmt.Sprint(order); got != "[0 1 2 4 5 6 7]" {
+		t.Fatalf("locked in order %s", got)
+	}
+}
+
+func testLockContext(t *testing.T, client func() *clientv3.Client) {
+	a, _ := replica(t, client)
+	t0 := a.GetTicket()
+	t1 := a.GetTicket()
+	t2 := a.GetTicket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.LockContext(ctx, t1); err != context.DeadlineExceeded {
+		t.Fatalf("LockContext = %v", err)
+	}
+	a.Lock(t0)
+	a.Unlock(t0)
+	// t1 was returned, so t2 is next.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.LockContext(ctx, t2); err != nil {
+		t.Fatal(err)
+	}
+	a.Unlock(t2)
+	a.Unlock(t2)
+	if err := a.LockContext(ctx, t0); err != ErrTicketGone {
+		t.Fatalf("Lock of an unlocked ticket = %v", err)
+	}
+}
+
+func testLeaseExpiry(t *testing.T, client func() *clientv3.Client) {
+	a, sa := replica(t, client)
+	b, _ := replica(t, client)
+	ta := a.GetTicket()
+	tb := b.GetTicket()
+	a.Lock(ta)
+
+	done := make(chan struct{})
+	go func() {
+		b.Lock(tb)
+		b.Unlock(tb)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Replica a crashes holding the lock: its lease goes and its ticket burns.
+	if _, err := sa.Client().Revoke(context.Background(), sa.Lease()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("order stalled on a ticket whose lease expired")
+	}
+}
+
+func testAdopt(t *testing.T, client func() *clientv3.Client) {
+	a, _ := replica(t, client)
+	b, _ := replica(t, client)
+	ta := a.GetTicket()
+	data, _ := ta.(encoding.BinaryMarshaler).MarshalBinary()
+	tb, err := b.Adopt(context.Background(), data)
+	if err != nil || tb.ID() != ta.ID() {
+		t.Fatalf("Adopt = %v, %v", tb, err)
+	}
+	b.Lock(tb)
+	b.Unlock(tb)
+	if _, err := b.Adopt(context.Background(), data); err != ErrTicketGone {
+		t.Fatalf("Adopt of an unlocked ticket = %v", err)
+	}
+}
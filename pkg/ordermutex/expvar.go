@@ -0,0 +1,37 @@
+package ordermutex
+
+import "expvar"
+
+// State is a point-in-time view of a Mutex.
+type State struct {
+	// Current is the ticket whose turn it is.
+	Current uint64 `json:"current"`
+	// Next is the ticket GetTicket will issue next.
+	Next    uint64 `json:"next"`
+	Waiters int    `json:"waiters"`
+	// Burned counts returned tickets not yet skipped over.
+	Burned int `json:"burned"`
+}
+
+func (m *Mutex) State() State {
+	m.lock()
+	defer m.unlock()
+
+	return State{
+		Current: m.turn(),
+		Next:    m.next.Load(),
+		Waiters: m.waiters.len(),
+		Burned:  m.burned.len(),
+	}
+}
+
+// PublishExpvar exports m's State under name, evaluated on every read.
+// Like expvar.Publish, it panics if name is already registered;
+// it also panics if m can't report its state.
+func PublishExpvar(name string, m OrderMutex) {
+	s, ok := m.(interface{ State() State })
+	if !ok {
+		panic("PublishExpvar called with a mutex that does not expose State")
+	}
+	expvar.Publish(name, expvar.Func(func() any { return s.State() }))
+}
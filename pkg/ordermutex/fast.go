@@ -0,0 +1,94 @@
+package ordermutex
+
+import "unsafe"
+
+// Fast path.
+//
+// While the mutex is quiet (no waiters, no burned, promoted or coalescable
+// tickets, and no adopted ones on a bounded mutex), cur and held live in the
+// state word and uncontended Lock/Unlock pairs are a single CAS each.
+// Anything else sets stateSlow under mu, which moves ownership of cur and held
+// back to the fields guarded by mu; unlock hands them back to the word once the
+// mutex is quiet again.
+//
+// Options that observe every transition (hooks, timers, strict mode, debug
+// records) disable the fast path for the lifetime of the mutex.
+const (
+	stateHeld = 1 << iota
+	stateSlow
+	stateShift = iota
+)
+
+// enableFast turns on the fast path if no option needs the slow one.
+func (m *Mutex) enableFast() {
+	m.fast = len(m.hooks) == 0 && m.skipAfter == 0 && m.watchdog == nil &&
+		m.onStall == nil && !m.strict && !debugEnabled
+	if !m.fast {
+		m.state.Store(stateSlow)
+	}
+}
+
+func (m *Mutex) tryLockFast(id uint64) bool {
+	s := m.state.Load()
+	if s&(stateSlow|stateHeld) != 0 || s>>stateShift != id {
+		return false
+	}
+	if !m.state.CompareAndSwap(s, s|stateHeld) {
+		return false
+	}
+	raceAcquire(unsafe.Pointer(m))
+	return true
+}
+
+func (m *Mutex) tryUnlockFast(id uint64) bool {
+	s := m.state.Load()
+	if s&(stateSlow|stateHeld) != stateHeld || s>>stateShift != id {
+		return false
+	}
+	raceRelease(unsafe.Pointer(m))
+	if !m.state.CompareAndSwap(s, (id+1)<<stateShift) {
+		return false
+	}
+	if m.slots != nil {
+		<-m.slots // the mutex is quiet, so every ticket holds a slot
+	}
+	return true
+}
+
+// passedFast reports whether ticket id has certainly been served.
+func (m *Mutex) passedFast(id uint64) bool {
+	s := m.state.Load()
+	return s&stateSlow == 0 && id < s>>stateShift
+}
+
+// lock acquires mu and takes cur and held over from the state word.
+func (m *Mutex) lock() {
+	m.mu.Lock()
+	if !m.fast {
+		return
+	}
+	for {
+		s := m.state.Load()
+		if s&stateSlow != 0 {
+			return
+		}
+		if m.state.CompareAndSwap(s, s|stateSlow) {
+			m.cur = s >> stateShift
+			m.held = s&stateHeld != 0
+			return
+		}
+	}
+}
+
+// unlock hands cur and held back to the state word if the mutex is quiet, and releases mu.
+func (m *Mutex) unlock() {
+	if m.fast && m.waiters.len() == 0 && m.burned.len() == 0 && len(m.promoted) == 0 && !m.overActive &&
+		len(m.producerOf) == 0 && m.unslotted.len() == 0 {
+		s := m.cur << stateShift
+		if m.held {
+			s |= stateHeld
+		}
+		m.state.Store(s)
+	}
+	m.mu.Unlock()
+}
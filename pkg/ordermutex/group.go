@@ -0,0 +1,126 @@
+package ordermutex
+
+import "sync"
+
+// GetGroupTicket issues one ticket for a group of n participants, as n
+// member tickets, for multi-part work that must run together in order.
+// Each member calls Lock and Unlock on its own ticket. The group's turn
+// begins only once every member has called Lock: the last one takes the
+// turn, and all members' Locks return together when it begins. The turn
+// passes on once every member has unlocked.
+//
+// A member that drops out with ReturnTicket before Lock leaves the group,
+// and the rest go ahead without it; if all drop out, the ticket is burned.
+// If the group's turn is lost, to WithSkipAbsent or CancelLock, every
+// member's Lock fails with the same error.
+func (m *Mutex) GetGroupTicket(n int) []Ticket {
+	if n <= 0 {
+		panic("GetGroupTicket called with non-positive n")
+	}
+	g := &group{Ticket: m.GetTicket(), n: n, ready: make(chan struct{})}
+	members := make([]Ticket, n)
+	for i := range members {
+		members[i] = &groupMember{group: g}
+	}
+	return members
+}
+
+// group forwards the last member's Lock and the last Unlock or ReturnTicket
+// to the ticket it wraps.
+type group struct {
+	Ticket
+
+	mu                      sync.Mutex
+	n                       int // members still in the group
+	arrived, unlocked, left int
+	ready                   chan struct{} // closed once the wrapped ticket's Lock returns
+	err                     error         // what it returned; set before ready is closed
+}
+
+type groupMember struct {
+	*group
+	state memberState // guarded by group.mu
+}
+
+type memberState int
+
+const (
+	memberIdle memberState = iota
+	memberLocking
+	memberUnlocked
+	memberReturned
+)
+
+func (t *groupMember) lock(m *Mutex) error {
+	g := t.group
+	g.mu.Lock()
+	if t.state != memberIdle {
+		g.mu.Unlock()
+		panic("Lock called twice for a group member ticket")
+	}
+	t.state = memberLocking
+	g.arrived++
+	last := g.arrived == g.n
+	g.mu.Unlock()
+
+	if last {
+		return g.lock(m)
+	}
+	<-g.ready
+	return g.err
+}
+
+// lock takes the group's turn for its members and lets them in.
+func (g *group) lock(m *Mutex) error {
+	err := m.LockErr(g.Ticket)
+	g.mu.Lock()
+	g.err = err
+	g.mu.Unlock()
+	close(g.ready)
+	return err
+}
+
+func (t *groupMember) unlock(m *Mutex) {
+	g := t.group
+	g.mu.Lock()
+	if t.state != memberLocking || g.err != nil {
+		g.mu.Unlock()
+		panic("Unlock called for a ticket that does not hold the lock")
+	}
+	t.state = memberUnlocked
+	g.unlocked++
+	last := g.unlocked == g.n
+	g.mu.Unlock()
+
+	if last {
+		m.Unlock(g.Ticket)
+	}
+}
+
+func (t *groupMember) giveUp(m *Mutex) {
+	g := t.group
+	g.mu.Lock()
+	if t.state != memberIdle {
+		// Returning after Unlock is a no-op, as for plain tickets.
+		g.mu.Unlock()
+		return
+	}
+	t.state = memberReturned
+	g.n--
+	var lock, burn bool
+	switch {
+	case g.n == 0:
+		burn = true
+	case g.arrived == g.n:
+		// Everyone left is waiting for this member; it takes the turn for them.
+		lock = true
+	}
+	g.mu.Unlock()
+
+	switch {
+	case burn:
+		m.ReturnTicket(g.Ticket)
+	case lock:
+		go g.lock(m)
+	}
+}
@@ -0,0 +1,77 @@
+package ordermutex
+
+import "time"
+
+// Option configures a Mutex.
+type Option func(*Mutex)
+
+// Event describes a ticket lifecycle transition.
+type Event struct {
+	ID   uint64
+	Time time.Time
+	// Elapsed is the duration of the phase that just ended:
+	// the wait for OnLockAcquired, the hold for OnUnlock; zero otherwise.
+	Elapsed time.Duration
+}
+
+// Hooks receive ticket lifecycle events. Nil hooks are skipped.
+//
+// Hooks run synchronously on the goroutine making the call, sometimes with the
+// mutex's internal lock held: they must be fast and must not call back into the Mutex.
+type Hooks struct {
+	OnIssued       func(Event)
+	OnLockWait     func(Event)
+	OnLockAcquired func(Event)
+	OnUnlock       func(Event)
+	OnBurned       func(Event)
+}
+
+// WithHooks registers lifecycle hooks. It can be given several times;
+// hooks run in registration order.
+func WithHooks(h Hooks) Option {
+	return func(m *Mutex) {
+		m.hooks = append(m.hooks, h)
+	}
+}
+
+type hookKind int
+
+const (
+	hookIssued hookKind = iota
+	hookLockWait
+	hookLockAcquired
+	hookUnlock
+	hookBurned
+)
+
+func (h *Hooks) get(k hookKind) func(Event) {
+	switch k {
+	case hookIssued:
+		return h.OnIssued
+	case hookLockWait:
+		return h.OnLockWait
+	case hookLockAcquired:
+		return h.OnLockAcquired
+	case hookUnlock:
+		return h.OnUnlock
+	case hookBurned:
+		return h.OnBurned
+	}
+	return nil
+}
+
+// now returns the current time only when someone is listening.
+func (m *Mutex) now() time.Time {
+	if len(m.hooks) == 0 && m.onStall == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (m *Mutex) emit(k hookKind, id uint64, at time.Time, elapsed time.Duration) {
+	for i := range m.hooks {
+		if fn := m.hooks[i].get(k); fn != nil {
+			fn(Event{ID: id, Time: at, Elapsed: elapsed})
+		}
+	}
+}
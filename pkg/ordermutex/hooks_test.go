@@ -0,0 +1,319 @@
+package ordermutex
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	rec := func(kind string) func(Event) {
+		return func(e Event) {
+			if e.Time.IsZero() {
+				t.Errorf("%s %d: zero time", kind, e.ID)
+			}
+			mu.Lock()
+			log = append(log, fmt.Sprintf("%s %d", kind, e.ID))
+			mu.Unlock()
+		}
+	}
+	var held time.Duration
+	m := NewMutex(WithHooks(Hooks{
+		OnIssued:       rec("issued"),
+		OnLockWait:     rec("wait"),
+		OnLockAcquired: rec("acquired"),
+		OnUnlock: func(e Event) {
+			held = e.Elapsed
+			rec("unlock")(e)
+		},
+		OnBurned: rec("burned"),
+	}))
+
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	m.ReturnTicket(t1)
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t2)
+		m.Unlock(t2)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	m.Lock(t0)
+	time.Sleep(10 * time.Millisecond)
+	m.Unlock(t0)
+	<-done
+
+	want := []string{
+		"issued 0", "issued 1", "issued 2", "burned 1",
+		"wait 2", "acquired 0", "unlock 0", "acquired 2", "unlock 2",
+	}
+	if fmt.Sprint(log) != fmt.Sprint(want) {
+		t.Fatalf("events %v, want %v", log, want)
+	}
+	if held <= 0 {
+		t.Fatalf("hold time not reported")
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	type report struct {
+		id    uint64
+		held  time.Duration
+		stack []byte
+	}
+	reports := make(chan report, 4)
+	m := NewMutex(WithWatchdog(Watchdog{
+		Threshold: 20 * time.Millisecond,
+		Stack:     true,
+		OnLongHold: func(id uint64, held time.Duration, stack []byte) {
+			reports <- report{id, held, stack}
+		},
+	}))
+
+	fast := m.GetTicket()
+	m.Lock(fast)
+	m.Unlock(fast)
+
+	slow := m.GetTicket()
+	m.Lock(slow)
+	time.Sleep(50 * time.Millisecond)
+	m.Unlock(slow)
+
+	select {
+	case r := <-reports:
+		if r.id != slow.ID() || r.held < 20*time.Millisecond || len(r.stack) == 0 {
+			t.Fatalf("unexpected report: id %d held %v stack %d bytes", r.id, r.held, len(r.stack))
+		}
+	default:
+		t.Fatal("long hold not reported")
+	}
+	select {
+	case r := <-reports:
+		t.Fatalf("extra report for %d", r.id)
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestWatchdogFiredAfterUnlock(t *testing.T) {
+	reports := make(chan uint64, 1)
+	m := NewMutex(WithWatchdog(Watchdog{
+		Threshold:  time.Millisecond,
+		OnLongHold: func(id uint64, _ time.Duration, _ []byte) { reports <- id },
+	}))
+	t0 := m.GetTicket()
+	m.Lock(t0)
+
+	// The timer fires while Unlock has mu, too late for Stop to recall it.
+	m.mu.Lock()
+	time.Sleep(20 * time.Millisecond)
+	m.unwatch()
+	m.mu.Unlock()
+
+	select {
+	case id := <-reports:
+		t.Fatalf("hold of %d reported after it ended", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.Unlock(t0)
+}
+
+func TestStallDetector(t *testing.T) {
+	reports := make(chan *StallReport, 16)
+	m := NewMutex(WithStallDetector(20*time.Millisecond, func(r *StallReport) {
+		reports <- r
+	}))
+
+	t0 := m.GetTicket() // issued, never locked
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	m.ReturnTicket(t2)
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		close(done)
+	}()
+
+	var r *StallReport
+	select {
+	case r = <-reports:
+	case <-time.After(time.Second):
+		t.Fatal("stall not reported")
+	}
+	if r.Turn != t0.ID() || !r.Issued || r.Locked || fmt.Sprint(r.Waiters) != "[1]" || r.BurnedAhead != 1 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	if r.Error() == "" {
+		t.Fatal("empty error text")
+	}
+
+	m.ReturnTicket(t0)
+	<-done
+
+	// detector goes quiet once nobody waits
+	time.Sleep(50 * time.Millisecond)
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(reports) != 0 {
+		t.Fatal("reports after the stall cleared")
+	}
+}
+
+// expvarRuns keeps names unique under -count, as expvar can't unpublish.
+var expvarRuns int
+
+func TestPublishExpvar(t *testing.T) {
+	expvarRuns++
+	name := fmt.Sprintf("ordermutex_test_%d", expvarRuns)
+	m := NewMutex()
+	PublishExpvar(name, m)
+
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	m.ReturnTicket(t2)
+	go m.Lock(t1)
+	time.Sleep(20 * time.Millisecond)
+
+	got := expvar.Get(name).String()
+	want := `{"current":0,"next":3,"waiters":1,"burned":1}`
+	if got != want {
+		t.Fatalf("expvar %s, want %s", got, want)
+	}
+
+	m.Lock(t0)
+	m.Unlock(t0)
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m := NewMutex(WithLogger(l))
+
+	t0 := m.GetTicket()
+	m.Lock(t0)
+	m.Unlock(t0)
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="ticket issued" ticket=0`,
+		`msg="ticket locked" ticket=0 wait=`,
+		`msg="ticket unlocked" ticket=0 held=`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDebugInfo(t *testing.T) {
+	m := NewMutex()
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	m.ReturnTicket(t1)
+	m.Lock(t0)
+	m.Unlock(t0)
+
+	info, ok := m.DebugInfo(t0.ID())
+	if !debugEnabled {
+		if ok {
+			t.Fatal("DebugInfo reported records without the ordermutexdebug tag")
+		}
+		return
+	}
+	if !ok || info.Issued == nil || info.Locked == nil || info.Burned != nil {
+		t.Fatalf("unexpected info for t0: %+v", info)
+	}
+	if info.Locked.Goroutine == 0 || !strings.Contains(string(info.Locked.Stack), "TestDebugInfo") {
+		t.Fatalf("locked record lacks goroutine or stack: %+v", info.Locked)
+	}
+	if info, ok := m.DebugInfo(t1.ID()); !ok || info.Burned == nil || info.Locked != nil {
+		t.Fatalf("unexpected info for t1: %+v", info)
+	}
+}
+
+func TestPprofLabels(t *testing.T) {
+	m := NewMutex(WithName("ingest"), WithPprofLabels())
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+
+	done := make(chan struct{})
+	go func() {
+		pprof.Do(context.Background(), pprof.Labels("caller", "mine"), func(ctx context.Context) {
+			m.Lock(t1)
+			if v, _ := pprof.Label(ctx, "caller"); v != "mine" {
+				t.Error("caller labels lost")
+			}
+			m.Unlock(t1)
+		})
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := `"ordermutex":"ingest", "ordermutex.phase":"wait", "ordermutex.ticket":"1"`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("goroutine profile lacks %s:\n%s", want, buf.String())
+	}
+
+	m.Lock(t0)
+	m.Unlock(t0)
+	<-done
+}
+
+func TestStats(t *testing.T) {
+	if s := NewMutex().Stats(); s != (Stats{}) {
+		t.Fatalf("stats without WithStats: %+v", s)
+	}
+
+	m := NewMutex(WithStats())
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	t3 := m.GetTicket()
+	m.ReturnTicket(t3)
+	m.Lock(t0)
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		m.Lock(t2)
+		m.Unlock(t2)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if s := m.Stats(); s.OldestWaiter < 20*time.Millisecond {
+		t.Fatalf("oldest waiter %v, want >= 20ms", s.OldestWaiter)
+	}
+	m.Unlock(t0)
+	<-done
+
+	s := m.Stats()
+	if s.Issued != 4 || s.Acquired != 3 || s.Burned != 1 || s.BurnRatio != 0.25 {
+		t.Fatalf("counts %+v", s)
+	}
+	if s.MaxLockWait < 20*time.Millisecond || s.MaxQueueWait < s.MaxLockWait ||
+		s.MeanQueueWait <= 0 || s.MeanQueueWait > s.MaxQueueWait || s.OldestWaiter != 0 {
+		t.Fatalf("durations %+v", s)
+	}
+}
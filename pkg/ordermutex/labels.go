@@ -0,0 +1,46 @@
+package ordermutex
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"unsafe"
+)
+
+// WithName names the mutex in diagnostics such as pprof labels.
+func WithName(name string) Option {
+	return func(m *Mutex) {
+		m.name = name
+	}
+}
+
+// WithPprofLabels tags goroutines parked in Lock with pprof labels
+// "ordermutex" (the mutex name), "ordermutex.ticket" and "ordermutex.phase",
+// so goroutine profiles tell waiters apart from other channel receives.
+// The goroutine's own labels are restored once the lock is acquired.
+func WithPprofLabels() Option {
+	return func(m *Mutex) {
+		m.pprofLabels = true
+	}
+}
+
+// labelWait swaps in the wait labels and returns a func restoring the previous ones.
+func (m *Mutex) labelWait(id uint64) (restore func()) {
+	prev := runtime_getProfLabel()
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels(
+		"ordermutex", m.name,
+		"ordermutex.ticket", strconv.FormatUint(id, 10),
+		"ordermutex.phase", "wait",
+	))
+	pprof.SetGoroutineLabels(ctx)
+	return func() { runtime_setProfLabel(prev) }
+}
+
+// The runtime keeps goroutine labels as an opaque pointer; saving and restoring
+// it is the only way to put back labels set by the caller without its context.
+
+//go:linkname runtime_getProfLabel runtime/pprof.runtime_getProfLabel
+func runtime_getProfLabel() unsafe.Pointer
+
+//go:linkname runtime_setProfLabel runtime/pprof.runtime_setProfLabel
+func runtime_setProfLabel(labels unsafe.Pointer)
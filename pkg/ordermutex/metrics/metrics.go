@@ -0,0 +1,125 @@
+// Package metrics exports ordermutex activity as Prometheus metrics.
+//
+//	c := metrics.NewCollector("myapp")
+//	prometheus.MustRegister(c)
+//	m := ordermutex.NewMutex(c.Instrument("ingest"))
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Collector gathers metrics of any number of mutexes, labeled by mutex name.
+type Collector struct {
+	waitTime *prometheus.HistogramVec
+	holdTime *prometheus.HistogramVec
+	depth    *prometheus.GaugeVec
+	burned   *prometheus.CounterVec
+	issued   *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+func NewCollector(namespace string) *Collector {
+	labels := []string{"mutex"}
+	return &Collector{
+		waitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "ordermutex",
+			Name:      "wait_seconds",
+			Help:      "Time from Lock call to acquisition.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 14),
+		}, labels),
+		holdTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "ordermutex",
+			Name:      "hold_seconds",
+			Help:      "Time from acquisition to Unlock.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 14),
+		}, labels),
+		depth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "ordermutex",
+			Name:      "queue_depth",
+			Help:      "Tickets issued and not yet unlocked or burned.",
+		}, labels),
+		burned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ordermutex",
+			Name:      "burned_total",
+			Help:      "Tickets returned before locking.",
+		}, labels),
+		issued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ordermutex",
+			Name:      "issued_total",
+			Help:      "Tickets issued.",
+		}, labels),
+	}
+}
+
+// Instrument returns an option recording the metrics of one mutex under name.
+func (c *Collector) Instrument(name string) ordermutex.Option {
+	wait := c.waitTime.WithLabelValues(name)
+	hold := c.holdTime.WithLabelValues(name)
+	depth := c.depth.WithLabelValues(name)
+	burned := c.burned.WithLabelValues(name)
+	issued := c.issued.WithLabelValues(name)
+
+	// Adopted tickets were issued elsewhere and never counted into depth, so
+	// only the ones issued here are taken off it again.
+	var (
+		mu     sync.Mutex
+		queued = make(map[uint64]struct{})
+	)
+	dequeue := func(id uint64) {
+		mu.Lock()
+		_, ok := queued[id]
+		delete(queued, id)
+		mu.Unlock()
+		if ok {
+			depth.Dec()
+		}
+	}
+
+	return ordermutex.WithHooks(ordermutex.Hooks{
+		OnIssued: func(e ordermutex.Event) {
+			mu.Lock()
+			queued[e.ID] = struct{}{}
+			mu.Unlock()
+			issued.Inc()
+			depth.Inc()
+		},
+		OnLockAcquired: func(e ordermutex.Event) {
+			wait.Observe(e.Elapsed.Seconds())
+		},
+		OnUnlock: func(e ordermutex.Event) {
+			hold.Observe(e.Elapsed.Seconds())
+			dequeue(e.ID)
+		},
+		OnBurned: func(e ordermutex.Event) {
+			burned.Inc()
+			dequeue(e.ID)
+		},
+	})
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.waitTime.Describe(ch)
+	c.holdTime.Describe(ch)
+	c.depth.Describe(ch)
+	c.burned.Describe(ch)
+	c.issued.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.waitTime.Collect(ch)
+	c.holdTime.Collect(ch)
+	c.depth.Collect(ch)
+	c.burned.Collect(ch)
+	c.issued.Collect(ch)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"encoding"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector("test")
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	m := ordermutex.NewMutex(c.Instrument("ingest"))
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	m.ReturnTicket(t1)
+	m.Lock(t0)
+	m.Unlock(t0)
+
+	if v := testutil.ToFloat64(c.issued.WithLabelValues("ingest")); v != 3 {
+		t.Fatalf("issued %v, want 3", v)
+	}
+	if v := testutil.ToFloat64(c.burned.WithLabelValues("ingest")); v != 1 {
+		t.Fatalf("burned %v, want 1", v)
+	}
+	if v := testutil.ToFloat64(c.depth.WithLabelValues("ingest")); v != 1 {
+		t.Fatalf("depth %v, want 1", v)
+	}
+	if n := testutil.CollectAndCount(c, "test_ordermutex_hold_seconds"); n != 1 {
+		t.Fatalf("hold histogram series %d, want 1", n)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Fatal(err)
+	}
+	m.ReturnTicket(t2)
+}
+
+func TestCollectorAdopted(t *testing.T) {
+	c := NewCollector("test")
+	peer := ordermutex.NewMutex()
+	data, err := peer.GetTicket().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := ordermutex.NewMutex(c.Instrument("adopt"))
+	a, err := m.Adopt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Lock(a)
+	m.Unlock(a)
+	if v := testutil.ToFloat64(c.depth.WithLabelValues("adopt")); v != 0 {
+		t.Fatalf("depth %v after an adopted ticket, want 0", v)
+	}
+}
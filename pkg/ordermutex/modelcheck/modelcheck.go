@@ -0,0 +1,240 @@
+// Package modelcheck validates OrderMutex implementations against a reference
+// model by exhaustively replaying every interleaving of a few small actors.
+//
+// Each actor takes a ticket and then either locks and unlocks it or returns it.
+// For every schedule the harness checks that tickets are admitted exactly when
+// and in the order the model says: by issue order, skipping returned tickets,
+// one holder at a time.
+package modelcheck
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Config bounds the exploration.
+type Config struct {
+	// Actors is the number of concurrent actors; default 3.
+	// The number of schedules grows factorially with it.
+	Actors int
+	// Timeout bounds how long an expected admission may take; default 1s.
+	Timeout time.Duration
+	// Settle is how long to watch for a wrongful admission after each step
+	// that admits nobody. Zero only yields the processor, which catches most
+	// but not all such bugs.
+	Settle time.Duration
+}
+
+type op int
+
+const (
+	opGet op = iota
+	opLock
+	opUnlock
+	opReturn
+)
+
+func (o op) String() string {
+	return [...]string{"GetTicket", "Lock", "Unlock", "ReturnTicket"}[o]
+}
+
+type step struct {
+	actor int
+	op    op
+}
+
+type schedule []step
+
+func (s schedule) String() string {
+	var b strings.Builder
+	for i, st := range s {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "a%d.%s", st.actor, st.op)
+	}
+	return b.String()
+}
+
+// Check replays every schedule against a fresh mutex from newMutex and fails t
+// at the first divergence from the model. It returns the number of schedules run.
+func Check(t testing.TB, newMutex func() ordermutex.OrderMutex, cfg Config) int {
+	t.Helper()
+	if cfg.Actors <= 0 {
+		cfg.Actors = 3
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+
+	n := 0
+	for _, s := range schedules(cfg.Actors) {
+		if err := run(newMutex(), s, cfg); err != nil {
+			t.Fatalf("schedule [%s]: %v", s, err)
+		}
+		n++
+	}
+	return n
+}
+
+// schedules enumerates the interleavings the model allows: an actor whose
+// Lock is parked can't take its next step until it is admitted.
+func schedules(actors int) []schedule {
+	var out []schedule
+	plans := make([][]op, actors)
+	var choose func(i int)
+	choose = func(i int) {
+		if i == actors {
+			explore(plans, newModel(), make([]int, actors), nil, &out)
+			return
+		}
+		for _, p := range [][]op{{opGet, opLock, opUnlock}, {opGet, opReturn}} {
+			plans[i] = p
+			choose(i + 1)
+		}
+	}
+	choose(0)
+	return out
+}
+
+func explore(plans [][]op, m *model, pc []int, prefix schedule, out *[]schedule) {
+	done := true
+	for a, plan := range plans {
+		if pc[a] == len(plan) {
+			continue
+		}
+		done = false
+		if m.parked[a] {
+			continue
+		}
+		st := step{actor: a, op: plan[pc[a]]}
+		next := m.clone()
+		next.apply(st)
+		pc[a]++
+		explore(plans, next, pc, append(prefix[:len(prefix):len(prefix)], st), out)
+		pc[a]--
+	}
+	if done {
+		*out = append(*out, prefix)
+	}
+}
+
+// run replays s on mu, checking admissions against the model.
+func run(mu ordermutex.OrderMutex, s schedule, cfg Config) error {
+	m := newModel()
+	tickets := make(map[int]ordermutex.Ticket)
+	admitted := make(chan int, len(s))
+
+	for _, st := range s {
+		var want []int
+		switch st.op {
+		case opGet:
+			tickets[st.actor] = mu.GetTicket()
+		case opLock:
+			started := make(chan struct{})
+			go func(a int, t ordermutex.Ticket) {
+				close(started)
+				mu.Lock(t)
+				admitted <- a
+			}(st.actor, tickets[st.actor])
+			<-started
+		case opUnlock:
+			mu.Unlock(tickets[st.actor])
+		case opReturn:
+			mu.ReturnTicket(tickets[st.actor])
+		}
+		want = m.apply(st)
+
+		for _, a := range want {
+			select {
+			case got := <-admitted:
+				if got != a {
+					return fmt.Errorf("after a%d.%s: a%d was admitted, want a%d", st.actor, st.op, got, a)
+				}
+			case <-time.After(cfg.Timeout):
+				return fmt.Errorf("after a%d.%s: a%d was not admitted within %v", st.actor, st.op, a, cfg.Timeout)
+			}
+		}
+		if len(want) == 0 {
+			settle(cfg.Settle)
+		}
+		select {
+		case got := <-admitted:
+			return fmt.Errorf("after a%d.%s: a%d was admitted out of turn", st.actor, st.op, got)
+		default:
+		}
+	}
+	return nil
+}
+
+func settle(d time.Duration) {
+	if d > 0 {
+		time.Sleep(d)
+		return
+	}
+	for range 3 {
+		runtime.Gosched()
+	}
+}
+
+// model is the reference OrderMutex, keyed by actor.
+type model struct {
+	order  []int // actors in ticket order
+	cur    int   // index into order of the turn
+	held   bool
+	burned map[int]bool
+	parked map[int]bool
+}
+
+func newModel() *model {
+	return &model{burned: make(map[int]bool), parked: make(map[int]bool)}
+}
+
+func (m *model) clone() *model {
+	c := &model{
+		order:  append([]int(nil), m.order...),
+		cur:    m.cur,
+		held:   m.held,
+		burned: make(map[int]bool, len(m.burned)),
+		parked: make(map[int]bool, len(m.parked)),
+	}
+	for k, v := range m.burned {
+		c.burned[k] = v
+	}
+	for k, v := range m.parked {
+		c.parked[k] = v
+	}
+	return c
+}
+
+// apply performs st and returns the actors it admits, in order.
+func (m *model) apply(st step) []int {
+	switch st.op {
+	case opGet:
+		m.order = append(m.order, st.actor)
+	case opLock:
+		m.parked[st.actor] = true
+	case opUnlock:
+		m.held = false
+		m.cur++
+	case opReturn:
+		m.burned[st.actor] = true
+	}
+	for m.cur < len(m.order) && m.burned[m.order[m.cur]] {
+		m.cur++
+	}
+	if m.held || m.cur == len(m.order) {
+		return nil
+	}
+	if a := m.order[m.cur]; m.parked[a] {
+		delete(m.parked, a)
+		m.held = true
+		return []int{a}
+	}
+	return nil
+}
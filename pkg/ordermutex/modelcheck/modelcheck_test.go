@@ -0,0 +1,62 @@
+package modelcheck
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+func TestOrderMutex(t *testing.T) {
+	n := Check(t, func() ordermutex.OrderMutex { return ordermutex.NewMutex() }, Config{})
+	t.Logf("%d schedules", n)
+}
+
+func TestFast(t *testing.T) {
+	Check(t, func() ordermutex.OrderMutex { return ordermutex.NewFast() }, Config{})
+}
+
+func TestBounded(t *testing.T) {
+	Check(t, func() ordermutex.OrderMutex { return ordermutex.NewBounded(3) }, Config{})
+}
+
+// fifo ignores ticket order.
+type fifo struct{ sync.Mutex }
+
+type fifoTicket uint64
+
+func (t fifoTicket) ID() uint64                     { return uint64(t) }
+func (t fifoTicket) MarshalBinary() ([]byte, error) { return nil, nil }
+
+func (f *fifo) GetTicket() ordermutex.Ticket   { return fifoTicket(0) }
+func (f *fifo) Lock(ordermutex.Ticket)         { f.Mutex.Lock() }
+func (f *fifo) Unlock(ordermutex.Ticket)       { f.Mutex.Unlock() }
+func (f *fifo) ReturnTicket(ordermutex.Ticket) {}
+
+type recorder struct {
+	testing.TB
+	msg string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestCatchesDisorder(t *testing.T) {
+	var r recorder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Check(&r, func() ordermutex.OrderMutex { return new(fifo) }, Config{Actors: 2, Settle: time.Millisecond})
+	}()
+	<-done
+	if !strings.Contains(r.msg, "admitted") {
+		t.Fatalf("Check did not catch a mutex ignoring ticket order: %q", r.msg)
+	}
+}
@@ -0,0 +1,80 @@
+package ordermutex
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Pair binds a ticket to the mutex that issued it.
+type Pair struct {
+	M OrderMutex
+	T Ticket
+}
+
+// multiSequencer orders MultiGetTicket calls.
+var multiSequencer Sequencer
+
+// MultiGetTicket issues one ticket on each mutex, atomically with respect to
+// other MultiGetTicket calls.
+//
+// Ticket order is fixed at issue time, so acquisition order alone can't prevent
+// deadlocks between ordered mutexes: A may hold m1 waiting for m2 while B holds
+// m2 waiting for m1 if their tickets were issued in opposite orders.
+// Issuing all tickets under one lock rules that out. See Sequencer for
+// separate orders with their own global positions.
+func MultiGetTicket(ms ...OrderMutex) []Pair {
+	return multiSequencer.GetTickets(ms...).Pairs
+}
+
+// MultiLock locks all pairs in a canonical order and returns a func that
+// unlocks them in reverse. Tickets should come from MultiGetTicket.
+//
+// If any Lock panics, the locks already taken are released and the remaining
+// tickets are returned before the panic propagates.
+func MultiLock(pairs ...Pair) (unlock func()) {
+	sorted := make([]Pair, len(pairs))
+	copy(sorted, pairs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return identity(sorted[i].M) < identity(sorted[j].M)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].M == sorted[i-1].M {
+			panic("MultiLock called with the same mutex twice")
+		}
+	}
+
+	acquired := 0
+	defer func() {
+		if acquired == len(sorted) {
+			return
+		}
+		// Rollback: a Lock panicked.
+		for i := acquired - 1; i >= 0; i-- {
+			sorted[i].M.Unlock(sorted[i].T)
+		}
+		for _, p := range sorted[acquired:] {
+			p.M.ReturnTicket(p.T)
+		}
+	}()
+
+	for _, p := range sorted {
+		p.M.Lock(p.T)
+		acquired++
+	}
+
+	return func() {
+		for i := len(sorted) - 1; i >= 0; i-- {
+			sorted[i].M.Unlock(sorted[i].T)
+		}
+	}
+}
+
+// identity gives a stable ordering key for pointer-backed implementations;
+// others keep their argument order.
+func identity(m OrderMutex) uintptr {
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Pointer {
+		return v.Pointer()
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package ordermutex
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultiLockTransfers(t *testing.T) {
+	a, b := NewMutex(), NewMutex()
+	var balanceA, balanceB int
+
+	var wg sync.WaitGroup
+	for i := 0; i != 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// alternate argument order to provoke lock-order inversions
+			var pairs []Pair
+			if i%2 == 0 {
+				pairs = MultiGetTicket(a, b)
+			} else {
+				pairs = MultiGetTicket(b, a)
+			}
+			unlock := MultiLock(pairs...)
+			balanceA--
+			balanceB++
+			unlock()
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("MultiLock deadlocked")
+	}
+	if balanceA != -200 || balanceB != 200 {
+		t.Fatalf("balances %d/%d", balanceA, balanceB)
+	}
+}
+
+// panicMutex panics on Lock to exercise MultiLock's rollback.
+type panicMutex struct{ OrderMutex }
+
+func (panicMutex) Lock(Ticket) { panic("lock failed") }
+
+func TestMultiLockRollback(t *testing.T) {
+	a, b := NewMutex(), NewMutex()
+	pairs := MultiGetTicket(a, b, panicMutex{NewMutex()})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		MultiLock(pairs...)
+	}()
+
+	// both mutexes must be usable afterwards
+	ta, tb := a.GetTicket(), b.GetTicket()
+	a.Lock(ta)
+	a.Unlock(ta)
+	b.Lock(tb)
+	b.Unlock(tb)
+}
+
+func TestSequencer(t *testing.T) {
+	var seq Sequencer
+	a, b, c := NewMutex(), NewMutex(), NewMutex()
+
+	ab := seq.GetTickets(a, b)
+	bc := seq.GetTickets(b, c)
+	ca := seq.GetTickets(c, a)
+	if ab.Seq != 0 || bc.Seq != 1 || ca.Seq != 2 || seq.Len() != 3 {
+		t.Fatalf("positions %d %d %d, len %d", ab.Seq, bc.Seq, ca.Seq, seq.Len())
+	}
+
+	var (
+		mu    sync.Mutex
+		order []uint64
+		wg    sync.WaitGroup
+	)
+	// Later operations start first; each still waits for the earlier ones it overlaps.
+	for _, op := range []Sequenced{ca, bc, ab} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := op.Lock()
+			mu.Lock()
+			order = append(order, op.Seq)
+			mu.Unlock()
+			unlock()
+		}()
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+	for i, s := range order {
+		if s != uint64(i) {
+			t.Fatalf("ran in order %v, want global order", order)
+		}
+	}
+
+	skipped := seq.GetTickets(a, c)
+	skipped.ReturnTicket()
+	unlock := seq.GetTickets(c, a).Lock()
+	unlock()
+}
+
+func TestSequencerBounded(t *testing.T) {
+	var seq Sequencer
+	a, b := NewMutex(), NewBounded(1)
+	held := b.GetTicket()
+
+	done := make(chan Sequenced)
+	go func() { done <- seq.GetTickets(a, b) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// The full mutex must not hold off operations that don't need it.
+	other := seq.GetTickets(a)
+	b.ReturnTicket(held)
+	op := <-done
+	if other.Seq != 0 || op.Seq != 1 {
+		t.Fatalf("positions %d %d, want 0 1", other.Seq, op.Seq)
+	}
+	other.Lock()()
+	op.Lock()()
+}
@@ -2,6 +2,8 @@ package ordermutex
 
 import (
 	"sync"
+	"time"
+	"unsafe"
 
 	"go.uber.org/atomic"
 )
@@ -13,70 +15,338 @@ type OrderMutex interface {
 	ReturnTicket(Ticket)
 }
 
-// orderMutex implements a ticket-lock with precise wakeups.
+// Mutex implements a ticket-lock with precise wakeups.
 // Invariants:
 //   - next >= cur
-//   - cur is the ticket currently allowed to acquire the lock
+//   - cur is the next ticket in sequence order
+//   - the turn (ticket allowed to acquire the lock) is over if overActive, else cur
+//   - held reports whether the turn ticket has entered Lock
 //   - waiters holds at most one entry per ticket, only for tickets >= cur
-//   - burned marks tickets that will never lock (canceled)
+//   - burned marks tickets that will never lock (canceled or already served out of order)
+//   - promoted queues tickets to be served right after the current holder
+//   - slots, if set, holds one token per outstanding ticket (issued, not yet unlocked or burned)
+//   - producerOf holds the Producer of each outstanding coalescable ticket
 //
-// Wake-ups are per-ticket by closing that ticket's channel.
-type orderMutex struct {
-	next atomic.Uint64
+// Wake-ups are per-ticket through that ticket's pooled waiter.
+// cur and held are guarded by mu only while state has stateSlow set; see fast.go.
+type Mutex struct {
+	next  atomic.Uint64
+	state atomic.Uint64
+	fast  bool
 
 	mu      sync.Mutex
 	cur     uint64
-	waiters map[uint64]chan struct{}
-	burned  map[uint64]struct{}
+	held    bool
+	waiters waitQueue
+	burned  burnSet
+
+	promoted   []uint64
+	over       uint64
+	overActive bool
+
+	slots     chan struct{}
+	unslotted burnSet // outstanding tickets holding no slot: adopted or skipped by Adopt
+
+	hooks  []Hooks
+	heldAt time.Time
+
+	skipAfter time.Duration
+	skipTimer *time.Timer
+	skipFor   uint64
+	skipped   map[uint64]struct{}
+
+	watchdog   *Watchdog
+	watchTimer *time.Timer
+	watchGen   uint64 // bumped by unwatch, so a fired timer can tell its hold ended
+
+	stallPeriod time.Duration
+	onStall     func(*StallReport)
+	stallTimer  *time.Timer
+	stallTurn   uint64
+	stallSeen   uint64
+	turns       uint64
+	turnSince   time.Time
+
+	debug debugState
+
+	name        string
+	pprofLabels bool
+
+	strict   bool
+	onMisuse func(error)
+
+	sema     bool
+	spin     bool
+	spinDist uint64
+
+	exec func(func())
+
+	stats *stats
+
+	producers  atomic.Uint64
+	producerOf map[uint64]uint64 // ticket to Producer, for coalescable tickets
+}
+
+// New returns an OrderMutex. Its dynamic type is *Mutex; use NewMutex to get
+// the rest of the API without a type assertion.
+func New(opts ...Option) OrderMutex {
+	return NewMutex(opts...)
+}
+
+// NewMutex returns a Mutex configured by opts.
+func NewMutex(opts ...Option) *Mutex {
+	m := &Mutex{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.enableFast()
+	if m.onStall != nil {
+		m.noteTurn()
+	}
+	return m
+}
+
+// NewBounded returns a Mutex that allows at most max outstanding tickets:
+// GetTicket blocks and TryGetTicket fails until a ticket is unlocked or returned.
+// Tickets obtained through Adopt are not counted.
+func NewBounded(max int, opts ...Option) *Mutex {
+	if max <= 0 {
+		panic("NewBounded called with non-positive max")
+	}
+	m := NewMutex(opts...)
+	m.slots = make(chan struct{}, max)
+	return m
 }
 
-func New() OrderMutex {
-	return &orderMutex{
-		waiters: make(map[uint64]chan struct{}),
-		burned:  make(map[uint64]struct{}),
+func (m *Mutex) GetTicket() Ticket {
+	if m.slots != nil {
+		m.slots <- struct{}{}
+	}
+	return m.issue()
+}
+
+// TryGetTicket is like GetTicket but reports false instead of blocking
+// when a bounded mutex has no free slot.
+func (m *Mutex) TryGetTicket() (Ticket, bool) {
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+		default:
+			return nil, false
+		}
 	}
+	return m.issue(), true
 }
 
-func (m *orderMutex) GetTicket() Ticket {
+// waitSlot blocks until a bounded mutex has a free slot, without keeping it.
+func (m *Mutex) waitSlot() {
+	m.slots <- struct{}{}
+	<-m.slots
+}
+
+func (m *Mutex) issue() Ticket {
 	id := m.next.Add(1) - 1
+	if len(m.hooks) > 0 {
+		m.emit(hookIssued, id, time.Now(), 0)
+	}
+	m.debugRecord(id, debugIssued)
+	return m.ticket(id)
+}
+
+func (m *Mutex) ticket(id uint64) Ticket {
+	if m.strict {
+		return strictTicket{ticket: ticket(id), owner: m}
+	}
 	return ticket(id)
 }
 
-func (m *orderMutex) Lock(t Ticket) {
+// release frees the slot of finished ticket id, unless it never took one;
+// mu must be held.
+func (m *Mutex) release(id uint64) {
+	if m.slots == nil || m.unslotted.remove(id) {
+		return
+	}
+	<-m.slots
+}
+
+// Adopt rebinds a ticket serialized with MarshalBinary to m, so a ticket issued
+// by a peer sharing the same order can be locked here.
+// The issue counter is moved past the adopted ID so GetTicket never reissues it;
+// every lower ID must still be adopted or returned, or the queue stalls on it.
+func (m *Mutex) Adopt(data []byte) (Ticket, error) {
+	var t ticket
+	if err := t.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
 	id := t.ID()
 
-	// Fast path: grab mu, if it's our turn, enter immediately.
-	m.mu.Lock()
-	if id == m.cur {
-		m.mu.Unlock()
-		return
+	m.lock()
+	defer m.unlock()
+
+	if id < m.cur {
+		return nil, ErrTicketPassed
+	}
+	for {
+		n := m.next.Load()
+		if n > id {
+			break
+		}
+		if m.next.CompareAndSwap(n, id+1) {
+			if m.slots != nil {
+				// Neither id nor the IDs skipped to reach it were issued here.
+				m.unslotted.addRange(n, id+1)
+			}
+			break
+		}
+	}
+	return m.ticket(id), nil
+}
+
+func (m *Mutex) Lock(t Ticket) {
+	if err := m.LockErr(t); err == ErrSkipped || err == ErrCanceled {
+		panic(err)
+	}
+}
+
+// LockErr is like Lock, but returns ErrSkipped or ErrCanceled instead of
+// panicking when t lost its turn to WithSkipAbsent or CancelLock. On a
+// strict mutex with a misuse handler, it also returns the *MisuseError
+// the handler was given. Unless the error is nil, t does not hold the lock.
+func (m *Mutex) LockErr(t Ticket) error {
+	switch t := t.(type) {
+	case *sharedTicket:
+		return t.lock(m)
+	case *groupMember:
+		return t.lock(m)
+	case *subTicket:
+		return t.lock(m)
+	}
+	id := t.ID()
+	if m.tryLockFast(id) || m.spin && m.spinLock(id) {
+		return nil
 	}
 
-	// Otherwise, park on (or create) this ticket's waiter.
-	ch, ok := m.waiters[id]
-	if !ok {
-		ch = make(chan struct{})
-		m.waiters[id] = ch
+	start := m.now()
+
+	// Grab mu, if it's our turn, enter immediately.
+	m.lock()
+	if m.takeSkipped(id) {
+		m.unlock()
+		return ErrSkipped
+	}
+	if m.strict {
+		if err := m.checkLock(t); err != nil {
+			m.unlock()
+			m.misuse(err)
+			return err
+		}
+	}
+	if id == m.turn() {
+		m.held = true
+		m.heldAt = start
+		m.unlock()
+		raceAcquire(unsafe.Pointer(m))
+		m.emit(hookLockAcquired, id, start, 0)
+		m.debugRecord(id, debugLocked)
+		if m.watchdog != nil {
+			m.watch(id)
+		}
+		return nil
 	}
-	m.mu.Unlock()
+
+	// Otherwise, park on this ticket's waiter.
+	if _, ok := m.waiters.get(id); ok {
+		m.unlock()
+		panic("Lock called for a ticket that is already waiting")
+	}
+	w := m.newWaiter()
+	m.waiters.put(m.cur, id, w)
+	if m.skipAfter > 0 && !m.held {
+		m.armSkip(m.turn())
+	}
+	if m.onStall != nil {
+		m.armStall()
+	}
+	m.unlock()
+	m.emit(hookLockWait, id, start, 0)
 
 	// Precise blocking on own ticket only.
-	<-ch
+	if m.pprofLabels {
+		restore := m.labelWait(id)
+		m.wait(w)
+		restore()
+	} else {
+		m.wait(w)
+	}
+	raceAcquire(unsafe.Pointer(w))
+	canceled := w.canceled
+	w.canceled = false
+	waiterPool.Put(w)
+	if canceled {
+		return ErrCanceled
+	}
 	// After wake, it is our turn by construction.
+	raceAcquire(unsafe.Pointer(m))
+	if len(m.hooks) > 0 {
+		now := time.Now()
+		m.emit(hookLockAcquired, id, now, now.Sub(start))
+	}
+	m.debugRecord(id, debugLocked)
+	if m.watchdog != nil {
+		m.watch(id)
+	}
+	return nil
 }
 
-func (m *orderMutex) Unlock(t Ticket) {
+func (m *Mutex) Unlock(t Ticket) {
+	switch t := t.(type) {
+	case *sharedTicket:
+		t.unlock(m)
+		return
+	case *groupMember:
+		t.unlock(m)
+		return
+	case *subTicket:
+		t.unlock(m)
+		return
+	}
 	id := t.ID()
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if m.tryUnlockFast(id) {
+		return
+	}
+	m.lock()
+	if m.strict {
+		if err := m.checkUnlock(t); err != nil {
+			m.unlock()
+			m.misuse(err)
+			return
+		}
+	}
+	defer m.unlock()
 
 	// UB
-	if id != m.cur {
+	if id != m.turn() || !m.held {
 		panic("Unlock called for a ticket that does not hold the lock")
 	}
+	raceRelease(unsafe.Pointer(m))
+	m.held = false
+	m.unwatch()
+	delete(m.producerOf, id)
+	if len(m.hooks) > 0 {
+		now := time.Now()
+		m.emit(hookUnlock, id, now, now.Sub(m.heldAt))
+	}
+
+	if m.overActive {
+		// A promoted ticket was served out of order; its sequence slot is skipped later.
+		m.overActive = false
+		m.burned.add(id)
+	} else {
+		m.cur++
+	}
+	m.release(id)
 
 	// Advance to next live ticket and wake exactly that one (if any).
-	m.cur++
 	m.advanceAndWakeNext()
 }
 
@@ -84,51 +354,193 @@ func (m *orderMutex) Unlock(t Ticket) {
 //   - before Lock: cancel the ticket (burn it)
 //   - after Unlock: it's effectively a no-op
 //
-// Any call between Lock and Unlock is UB (caller responsibility).
-func (m *orderMutex) ReturnTicket(t Ticket) {
+// Any call between Lock and Unlock is UB (caller responsibility; NewStrict reports it).
+func (m *Mutex) ReturnTicket(t Ticket) {
+	switch t := t.(type) {
+	case *sharedTicket:
+		t.giveUp(m)
+		return
+	case *groupMember:
+		t.giveUp(m)
+		return
+	case *subTicket:
+		t.giveUp(m)
+		return
+	}
+	id := t.ID()
+	if m.passedFast(id) {
+		return
+	}
+
+	m.lock()
+	if m.strict {
+		if err := m.checkReturn("ReturnTicket", t); err != nil {
+			m.unlock()
+			m.misuse(err)
+			return
+		}
+	}
+	defer m.unlock()
+
+	delete(m.skipped, id)
+	if m.burn(id) {
+		m.release(id)
+	}
+}
+
+// Requeue gives up t's position and returns a fresh ticket at the tail of the
+// queue. Both happen under one critical section, so the caller never drops out
+// of the queue in between; on a bounded mutex t's slot passes to the new ticket.
+// The same rules as ReturnTicket apply to t. Requeue panics if t is a shared,
+// group or sub-ticket.
+func (m *Mutex) Requeue(t Ticket) Ticket {
+	if composite(t) {
+		panic("Requeue called with a composite ticket")
+	}
+	m.lock()
+	if m.strict {
+		if err := m.checkReturn("Requeue", t); err != nil {
+			m.unlock()
+			m.misuse(err)
+			return t
+		}
+	}
+	if m.burn(t.ID()) {
+		nt := m.issue()
+		if m.slots != nil && m.unslotted.remove(t.ID()) {
+			m.unslotted.add(nt.ID()) // t had no slot to pass on
+		}
+		m.unlock()
+		return nt
+	}
+	m.unlock()
+
+	// t was already finished and holds no slot.
+	return m.GetTicket()
+}
+
+// Promote moves t to be served right after the current holder, ahead of every
+// ticket queued in normal order. If nobody holds the lock, t gets the turn at once.
+// Several promoted tickets are served in the order they were promoted.
+// Promoting a ticket that already has the turn, has passed, or was returned is a no-op.
+// Like Requeue, Promote panics if t is a shared, group or sub-ticket.
+func (m *Mutex) Promote(t Ticket) {
+	if composite(t) {
+		panic("Promote called with a composite ticket")
+	}
 	id := t.ID()
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.lock()
+	defer m.unlock()
 
+	if id < m.cur || id == m.turn() {
+		return
+	}
+	if m.burned.has(id) {
+		return
+	}
+	for _, p := range m.promoted {
+		if p == id {
+			return
+		}
+	}
+	m.promoted = append(m.promoted, id)
+
+	m.advanceAndWakeNext()
+}
+
+// burn cancels ticket id and reports whether it was live; mu must be held.
+func (m *Mutex) burn(id uint64) bool {
 	// If already passed, nothing to do (allowed for defer after Unlock).
 	if id < m.cur {
-		return
+		return false
+	}
+	if m.burned.has(id) {
+		return false
 	}
 
 	// Mark as burned and clean up: if it was the current ticket,
 	// keep advancing until a non-burned ticket is found; then wake it.
-	m.burned[id] = struct{}{}
+	m.burned.add(id)
+	delete(m.producerOf, id)
+	if len(m.hooks) > 0 {
+		m.emit(hookBurned, id, time.Now(), 0)
+	}
+	m.debugRecord(id, debugBurned)
+
+	// A promoted ticket that got the turn but never locked gives it back.
+	if m.overActive && id == m.over {
+		m.overActive = false
+	}
 
 	// If the returning ticket was waiting, remove and close its waiter to avoid leaks.
-	if ch, ok := m.waiters[id]; ok {
+	if w, ok := m.waiters.take(id); ok {
 		// Do NOT wake it (it must not proceed) — instead close & delete to release waiter.
 		// Closing would wake it; but a burned ticket must not enter Lock. To avoid waking:
 		// we just delete without closing; the goroutine will be blocked only if it's in Lock.
 		// However, a goroutine that called Lock for a burned ticket is UB by spec.
-		delete(m.waiters, id)
-		_ = ch // intentionally not closed
+		_ = w // intentionally not woken
 	}
 
 	// If returning the current ticket (or a sequence including it), advance.
 	m.advanceAndWakeNext()
+	return true
+}
+
+// turn returns the ticket currently allowed to acquire the lock.
+func (m *Mutex) turn() uint64 {
+	if m.overActive {
+		return m.over
+	}
+	return m.cur
 }
 
 // advanceAndWakeNext advances cur over any burned tickets;
-// then if there is a waiter for m.cur, it wakes exactly that waiter.
-func (m *orderMutex) advanceAndWakeNext() {
+// then, unless the turn is already taken, hands it to the next promoted ticket
+// and wakes exactly the waiter for the turn, if any. If the turn ticket isn't
+// waiting yet but others are, the skip-absent timer starts counting.
+func (m *Mutex) advanceAndWakeNext() {
+	m.advance()
+	if m.onStall != nil {
+		m.noteTurn()
+	}
+}
+
+func (m *Mutex) advance() {
 	// Skip burned tickets strictly ahead of (or at) cur.
-	for {
-		if _, burned := m.burned[m.cur]; !burned {
-			break
+	m.cur = m.burned.skip(m.cur)
+	if len(m.skipped) > 0 {
+		m.pruneSkipped()
+	}
+
+	if m.held {
+		return
+	}
+
+	// Hand the turn to the oldest promoted ticket that is still live.
+	for !m.overActive && len(m.promoted) > 0 {
+		id := m.promoted[0]
+		m.promoted = m.promoted[1:]
+		if id < m.cur || m.burned.has(id) {
+			continue
 		}
-		delete(m.burned, m.cur)
-		m.cur++
+		if id != m.cur {
+			m.over = id
+			m.overActive = true
+		}
+		break
 	}
 
 	// Wake the exact next waiter, if any.
-	if ch, ok := m.waiters[m.cur]; ok {
-		delete(m.waiters, m.cur)
-		close(ch) // precise wake-up: only this goroutine proceeds
+	id := m.turn()
+	if w, ok := m.waiters.take(id); ok {
+		m.held = true
+		m.heldAt = m.now()
+		m.wake(w) // precise wake-up: only this goroutine proceeds
+		return
+	}
+
+	if m.skipAfter > 0 && m.waiters.len() > 0 {
+		m.armSkip(id)
 	}
 }
@@ -1,6 +1,7 @@
 package ordermutex
 
 import (
+	"encoding"
 	"fmt"
 	"math/rand"
 	"os"
@@ -235,3 +236,1069 @@ func BenchmarkOrderMutexWithBurnedTickets(b *testing.B) {
 	}
 	wg.Wait()
 }
+
+func TestTicketMarshalAdopt(t *testing.T) {
+	src := NewMutex()
+	dst := NewMutex()
+
+	t0 := src.GetTicket()
+	t1 := src.GetTicket()
+
+	d1, err := t1.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d0, err := t0.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a1, err := dst.Adopt(d1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a1.ID() != t1.ID() {
+		t.Fatalf("adopted id %d, want %d", a1.ID(), t1.ID())
+	}
+	a0, err := dst.Adopt(d0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		dst.Lock(a1)
+		dst.Unlock(a1)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("adopted t1 locked before t0")
+	default:
+	}
+
+	dst.Lock(a0)
+	dst.Unlock(a0)
+	<-done
+
+	if next := dst.GetTicket(); next.ID() != 2 {
+		t.Fatalf("GetTicket after Adopt = %d, want 2", next.ID())
+	}
+	if _, err := dst.Adopt(d0); err != ErrTicketPassed {
+		t.Fatalf("Adopt of passed ticket: %v", err)
+	}
+	if _, err := dst.Adopt([]byte{1, 2}); err != ErrInvalidTicket {
+		t.Fatalf("Adopt of garbage: %v", err)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	m := NewMutex()
+	tickets := make([]Ticket, 5)
+	for i := range tickets {
+		tickets[i] = m.GetTicket()
+	}
+
+	m.Lock(tickets[0])
+
+	var mu sync.Mutex
+	var order []uint64
+	var wg sync.WaitGroup
+	for _, tk := range tickets[1:] {
+		wg.Add(1)
+		go func(tk Ticket) {
+			defer wg.Done()
+			m.Lock(tk)
+			mu.Lock()
+			order = append(order, tk.ID())
+			mu.Unlock()
+			m.Unlock(tk)
+		}(tk)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	m.Promote(tickets[4])
+	m.Promote(tickets[3])
+	m.Unlock(tickets[0])
+	wg.Wait()
+
+	want := []uint64{4, 3, 1, 2}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Fatalf("order %v, want %v", order, want)
+	}
+
+	// served out of order tickets must not stall the sequence
+	next := m.GetTicket()
+	m.Lock(next)
+	m.Unlock(next)
+}
+
+func TestPromoteIdle(t *testing.T) {
+	m := NewMutex()
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+
+	// nobody holds the lock: t1 gets the turn at once
+	m.Promote(t1)
+	m.Lock(t1)
+	m.Unlock(t1)
+	m.Lock(t0)
+	m.Unlock(t0)
+}
+
+func TestRequeue(t *testing.T) {
+	m := NewMutex()
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+
+	r0 := m.Requeue(t0)
+	if r0.ID() != 2 {
+		t.Fatalf("requeued id %d, want 2", r0.ID())
+	}
+	defer m.ReturnTicket(r0)
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(r0)
+		m.Unlock(r0)
+		close(done)
+	}()
+
+	// t0's slot is gone, so t1 is current right away
+	m.Lock(t1)
+	select {
+	case <-done:
+		t.Fatal("requeued ticket ran before t1")
+	case <-time.After(50 * time.Millisecond):
+	}
+	m.Unlock(t1)
+	<-done
+}
+
+func TestBounded(t *testing.T) {
+	m := NewBounded(2)
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	if _, ok := m.TryGetTicket(); ok {
+		t.Fatal("TryGetTicket succeeded past the bound")
+	}
+
+	// requeue keeps the slot
+	t1 = m.Requeue(t1)
+	if _, ok := m.TryGetTicket(); ok {
+		t.Fatal("Requeue leaked a slot")
+	}
+
+	issued := make(chan Ticket)
+	go func() { issued <- m.GetTicket() }()
+	select {
+	case <-issued:
+		t.Fatal("GetTicket did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.Lock(t0)
+	m.Unlock(t0)
+	t2 := <-issued
+
+	m.ReturnTicket(t1)
+	m.ReturnTicket(t1) // double return must not free a second slot
+	t3, ok := m.TryGetTicket()
+	if !ok {
+		t.Fatal("returned ticket did not free a slot")
+	}
+	if _, ok := m.TryGetTicket(); ok {
+		t.Fatal("TryGetTicket succeeded past the bound")
+	}
+	m.ReturnTicket(t2)
+	m.ReturnTicket(t3)
+}
+
+func TestSkipAbsent(t *testing.T) {
+	m := NewMutex(WithSkipAbsent(30 * time.Millisecond))
+	t0 := m.GetTicket() // never locks
+	t1 := m.GetTicket()
+
+	start := time.Now()
+	m.Lock(t1)
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("t0 was skipped too early")
+	}
+	m.Unlock(t1)
+	m.mu.Lock()
+	kept := len(m.skipped)
+	m.mu.Unlock()
+	if kept != 0 {
+		t.Fatalf("%d skipped tickets kept after the turn moved past them", kept)
+	}
+
+	// The late Lock still fails once t0 is forgotten.
+	func() {
+		defer func() {
+			if r := recover(); r != ErrSkipped {
+				t.Fatalf("late Lock: recovered %v, want ErrSkipped", r)
+			}
+		}()
+		m.Lock(t0)
+	}()
+	m.ReturnTicket(t0)
+
+	// the next ticket is not issued yet and must not be skipped
+	time.Sleep(50 * time.Millisecond)
+	t2 := m.GetTicket()
+	m.Lock(t2)
+	m.Unlock(t2)
+}
+
+// TestCriticalSectionData shares unsynchronized data between critical sections
+// whose wake-ups come from different paths; run with -race.
+func TestCriticalSectionData(t *testing.T) {
+	m := NewMutex()
+	var data []int
+	var wg sync.WaitGroup
+	for i := 0; i != 50; i++ {
+		tk := m.GetTicket()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer m.ReturnTicket(tk)
+			if i%5 == 0 {
+				return // wake-up comes from ReturnTicket instead of Unlock
+			}
+			m.Lock(tk)
+			data = append(data, i)
+			m.Unlock(tk)
+		}(i)
+	}
+	wg.Wait()
+	if len(data) != 40 {
+		t.Fatalf("len %d, want 40", len(data))
+	}
+}
+
+func TestFastPathResumes(t *testing.T) {
+	if debugEnabled {
+		t.Skip("debug records disable the fast path")
+	}
+	m := NewMutex()
+
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	m.Lock(t0)
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if m.state.Load()&stateSlow == 0 {
+		t.Fatal("fast path enabled with a waiter")
+	}
+	m.Unlock(t0)
+	<-done
+
+	if s := m.state.Load(); s != 2<<stateShift {
+		t.Fatalf("state %#x after contention cleared, want cur 2 on the fast path", s)
+	}
+	t2 := m.GetTicket()
+	m.Lock(t2)
+	m.Unlock(t2)
+	m.ReturnTicket(t2)
+	if s := m.state.Load(); s != 3<<stateShift {
+		t.Fatalf("state %#x, want cur 3 on the fast path", s)
+	}
+}
+
+func TestWaitQueue(t *testing.T) {
+	var q waitQueue
+	ws := make(map[uint64]*waiter)
+	park := func(cur, id uint64) {
+		w := new(waiter)
+		ws[id] = w
+		q.put(cur, id, w)
+	}
+
+	park(0, 3)
+	park(0, 1)
+	park(0, 20)          // grows the ring
+	park(0, waitRingMax) // overflow
+	if got := fmt.Sprint(q.ids()); got != fmt.Sprint([]uint64{1, 3, 20, waitRingMax}) {
+		t.Fatalf("ids %s", got)
+	}
+	for _, id := range []uint64{1, 3} {
+		if w, ok := q.take(id); !ok || w != ws[id] {
+			t.Fatalf("take(%d) = %v, %v", id, w, ok)
+		}
+	}
+
+	// Sliding past the emptied slots wraps the ring.
+	park(15, 30)
+	park(15, 16)
+	for _, id := range []uint64{16, 20, 30, waitRingMax} {
+		if w, ok := q.get(id); !ok || w != ws[id] {
+			t.Fatalf("get(%d) = %v, %v", id, w, ok)
+		}
+	}
+	if _, ok := q.get(17); ok {
+		t.Fatal("get(17) found a waiter")
+	}
+	if q.len() != 4 {
+		t.Fatalf("len %d, want 4", q.len())
+	}
+}
+
+func TestLockTwiceWhileWaiting(t *testing.T) {
+	m := NewMutex()
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	m.Lock(t0)
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("second Lock of a waiting ticket did not panic")
+			}
+		}()
+		m.Lock(t1)
+	}()
+
+	m.Unlock(t0)
+	<-done
+}
+
+func TestNewFast(t *testing.T) {
+	m := NewFast()
+	const n = 100
+	tickets := make([]Ticket, n)
+	for i := range tickets {
+		tickets[i] = m.GetTicket()
+	}
+	m.ReturnTicket(tickets[n/2])
+
+	var (
+		wg  sync.WaitGroup
+		got []uint64
+	)
+	for i := n - 1; i >= 0; i-- {
+		if i == n/2 {
+			continue
+		}
+		wg.Add(1)
+		go func(tk Ticket) {
+			defer wg.Done()
+			m.Lock(tk)
+			got = append(got, tk.ID())
+			m.Unlock(tk)
+		}(tickets[i])
+	}
+	wg.Wait()
+
+	for i, id := range got {
+		want := uint64(i)
+		if i >= n/2 {
+			want++
+		}
+		if id != want {
+			t.Fatalf("lock %d went to ticket %d, want %d", i, id, want)
+		}
+	}
+}
+
+func BenchmarkFastContention(b *testing.B) {
+	m := NewFast()
+	var wg sync.WaitGroup
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := m.GetTicket()
+			m.Lock(t)
+			m.Unlock(t)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBurnSet(t *testing.T) {
+	var b burnSet
+	for _, id := range []uint64{5, 3, 9, 4, 7, 2, 8} {
+		b.add(id)
+	}
+	if got := fmt.Sprint(b.spans); got != "[{2 6} {7 10}]" {
+		t.Fatalf("spans %s", got)
+	}
+	if b.len() != 7 || !b.has(4) || b.has(6) || b.has(10) || b.has(1) {
+		t.Fatalf("len %d or membership wrong: %v", b.len(), b.spans)
+	}
+	var u burnSet
+	u.addRange(10, 20)
+	u.addRange(20, 25)
+	if !u.remove(15) || !u.remove(10) || !u.remove(24) || u.remove(15) {
+		t.Fatal("remove reported wrong membership")
+	}
+	if got := fmt.Sprint(u.spans); got != "[{11 15} {16 24}]" || u.len() != 12 {
+		t.Fatalf("spans %s, len %d", got, u.len())
+	}
+	if n := b.count(3, 9); n != 5 {
+		t.Fatalf("count(3, 9) = %d, want 5", n)
+	}
+	if cur := b.skip(1); cur != 1 {
+		t.Fatalf("skip(1) = %d", cur)
+	}
+	if cur := b.skip(2); cur != 6 || b.len() != 3 {
+		t.Fatalf("skip(2) = %d, len %d", cur, b.len())
+	}
+}
+
+func TestReturnFarAhead(t *testing.T) {
+	m := NewMutex()
+	tickets := make([]Ticket, 10000)
+	for i := range tickets {
+		tickets[i] = m.GetTicket()
+	}
+	for _, tk := range tickets[1:] {
+		m.ReturnTicket(tk)
+	}
+	if n := len(m.burned.spans); n != 1 {
+		t.Fatalf("%d spans for one burned range", n)
+	}
+	m.Lock(tickets[0])
+	m.Unlock(tickets[0])
+	if s := m.State(); s.Current != 10000 || s.Burned != 0 {
+		t.Fatalf("state %+v", s)
+	}
+}
+
+func TestSpin(t *testing.T) {
+	m := NewMutex(WithSpin(1))
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+	t2 := m.GetTicket()
+	m.Lock(t0)
+
+	locked := make(chan uint64, 2)
+	for _, tk := range []Ticket{t2, t1} {
+		go func(tk Ticket) {
+			m.Lock(tk)
+			locked <- tk.ID()
+			m.Unlock(tk)
+		}(tk)
+	}
+	time.Sleep(10 * time.Millisecond)
+	// t1 spun out its budget or is still spinning; t2 is too far behind and parked.
+	if m.state.Load()&stateSlow == 0 {
+		t.Fatal("far ticket did not park")
+	}
+	m.Unlock(t0)
+	if a, b := <-locked, <-locked; a != 1 || b != 2 {
+		t.Fatalf("locked %d then %d", a, b)
+	}
+}
+
+// BenchmarkPingPong alternates the lock between two goroutines.
+func BenchmarkPingPong(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"park", nil},
+		{"spin", []Option{WithSpin(1)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			m := NewMutex(bc.opts...)
+			tickets := make([]Ticket, b.N)
+			for i := range tickets {
+				tickets[i] = m.GetTicket()
+			}
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			for p := 0; p < 2; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for i := p; i < b.N; i += 2 {
+						m.Lock(tickets[i])
+						m.Unlock(tickets[i])
+					}
+				}(p)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestShare(t *testing.T) {
+	m := NewMutex()
+	t0 := m.Share(m.GetTicket(), 3)
+	t1 := m.GetTicket()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		inside  int
+		overlap bool
+		entered = make(chan struct{}, 2)
+	)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Lock(t0)
+			mu.Lock()
+			inside++
+			overlap = overlap || inside == 2
+			mu.Unlock()
+			entered <- struct{}{}
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			inside--
+			mu.Unlock()
+			m.Unlock(t0)
+		}()
+	}
+	<-entered
+	<-entered
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		close(done)
+	}()
+	wg.Wait()
+	select {
+	case <-done:
+		t.Fatal("next ticket ran before the last sharer dropped out")
+	case <-time.After(20 * time.Millisecond):
+	}
+	m.ReturnTicket(t0) // the third sharer
+	<-done
+	m.ReturnTicket(t0) // every sharer is done: no-op
+
+	if !overlap {
+		t.Fatal("sharers did not hold the turn together")
+	}
+}
+
+func TestShareAllReturned(t *testing.T) {
+	m := NewMutex()
+	t0 := m.Share(m.GetTicket(), 2)
+	t1 := m.GetTicket()
+	m.ReturnTicket(t0)
+	m.ReturnTicket(t0)
+	m.Lock(t1) // t0 burned, so t1 is current
+	m.Unlock(t1)
+}
+
+func TestShareSkipped(t *testing.T) {
+	m := NewMutex(WithSkipAbsent(20 * time.Millisecond))
+	t0 := m.Share(m.GetTicket(), 3)
+	next := m.GetTicket()
+
+	m.Lock(next) // waits until the shared ticket is skipped
+	m.Unlock(next)
+	for i := 0; i < 2; i++ {
+		if err := m.LockErr(t0); err != ErrSkipped {
+			t.Fatalf("sharer %d: LockErr = %v, want ErrSkipped", i, err)
+		}
+	}
+	m.ReturnTicket(t0) // the last sharer drops out; nothing is held to unlock
+
+	t2 := m.GetTicket()
+	m.Lock(t2)
+	m.Unlock(t2)
+}
+
+func TestRequeueComposite(t *testing.T) {
+	m := NewMutex()
+	for name, f := range map[string]func(Ticket){
+		"Requeue": func(t Ticket) { m.Requeue(t) },
+		"Promote": m.Promote,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s of a shared ticket did not panic", name)
+				}
+			}()
+			f(m.Share(m.GetTicket(), 2))
+		}()
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	m := NewMutex(WithStats())
+	p := m.NewProducer()
+	a, b, c := p.GetTicket(), p.GetTicket(), p.GetTicket()
+	other := m.GetTicket()
+	d := p.GetTicket()
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(other)
+		m.Unlock(other)
+		close(done)
+	}()
+
+	m.Lock(a)
+	cur := a
+	for _, want := range []Ticket{b, c} {
+		next, ok := m.Coalesce(cur)
+		if !ok || next.ID() != want.ID() {
+			t.Fatalf("Coalesce(%d) = %v, %v; want %d", cur.ID(), next, ok, want.ID())
+		}
+		cur = next
+	}
+	if _, ok := m.Coalesce(cur); ok {
+		t.Fatal("coalesced over another producer's ticket")
+	}
+	m.Unlock(cur)
+	<-done
+
+	// A ticket already parked in Lock is left to its waiter.
+	e := p.GetTicket()
+	locked := make(chan struct{})
+	go func() {
+		m.Lock(e)
+		close(locked)
+		m.Unlock(e)
+	}()
+	m.Lock(d)
+	for {
+		m.mu.Lock()
+		_, parked := m.waiters.get(e.ID())
+		m.mu.Unlock()
+		if parked {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := m.Coalesce(d); ok {
+		t.Fatal("coalesced over a ticket parked in Lock")
+	}
+	m.Unlock(d)
+	<-locked
+
+	if st := m.Stats(); st.Acquired != 6 {
+		t.Fatalf("Acquired = %d, want 6", st.Acquired)
+	}
+}
+
+func TestCoalescePromoted(t *testing.T) {
+	m := NewMutex()
+	p := m.NewProducer()
+	a, b := p.GetTicket(), p.GetTicket()
+	vip := m.GetTicket()
+
+	m.Lock(a)
+	m.Promote(vip)
+	if _, ok := m.Coalesce(a); ok {
+		t.Fatal("coalesced past a promoted ticket")
+	}
+	m.Unlock(a)
+	m.Lock(vip) // served before the producer's next ticket
+	m.Unlock(vip)
+	m.Lock(b)
+	if _, ok := m.Coalesce(b); ok {
+		t.Fatal("coalesced past the producer's last ticket")
+	}
+	m.Unlock(b)
+}
+
+func TestGroupTicket(t *testing.T) {
+	m := NewMutex()
+	before := m.GetTicket()
+	members := m.GetGroupTicket(3)
+	after := m.GetTicket()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		order    []string
+		released = make(chan struct{})
+	)
+	record := func(s string) {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+	}
+	lockMember := func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Lock(members[i])
+			record("member")
+			<-released
+			m.Unlock(members[i])
+		}()
+	}
+	m.Lock(before)
+	lockMember(0)
+	lockMember(1)
+	m.Unlock(before)
+
+	afterDone := make(chan struct{})
+	go func() {
+		m.Lock(after)
+		record("after")
+		m.Unlock(after)
+		close(afterDone)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	if len(order) != 0 {
+		t.Fatalf("ran %v before the whole group called Lock", order)
+	}
+	mu.Unlock()
+
+	lockMember(2)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(released)
+	wg.Wait()
+	<-afterDone
+	if got := fmt.Sprint(order); got != "[member member member after]" {
+		t.Fatalf("order %s", got)
+	}
+}
+
+func TestGroupTicketDropOut(t *testing.T) {
+	m := NewMutex()
+	members := m.GetGroupTicket(2)
+	next := m.GetTicket()
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(members[0])
+		m.Unlock(members[0])
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.ReturnTicket(members[1]) // the rest go ahead
+	<-done
+	m.ReturnTicket(members[0]) // after Unlock: no-op
+
+	all := m.GetGroupTicket(2)
+	m.Lock(next)
+	m.Unlock(next)
+	m.ReturnTicket(all[0])
+	m.ReturnTicket(all[1])
+	last := m.GetTicket()
+	m.Lock(last) // the fully returned group was burned
+	m.Unlock(last)
+}
+
+func TestGroupTicketSkipped(t *testing.T) {
+	m := NewMutex(WithSkipAbsent(20 * time.Millisecond))
+	members := m.GetGroupTicket(2)
+	next := m.GetTicket()
+
+	errc := make(chan error)
+	go func() { errc <- m.LockErr(members[0]) }()
+	m.Lock(next) // the group never arrives in full, so its turn is skipped
+	m.Unlock(next)
+	m.ReturnTicket(members[1]) // the dropout takes the lost turn for the rest
+	if err := <-errc; err != ErrSkipped {
+		t.Fatalf("LockErr = %v, want ErrSkipped", err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	m := NewMutex()
+	parent := m.GetTicket()
+	after := m.GetTicket()
+	subs := m.Split(parent, 3)
+	nested := m.Split(subs[1], 2)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order []string
+	)
+	run := func(name string, tk Ticket) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Lock(tk)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			m.Unlock(tk)
+		}()
+	}
+	// Start in reverse to show the order doesn't come from arrival.
+	run("after", after)
+	time.Sleep(5 * time.Millisecond)
+	run("sub2", subs[2])
+	run("sub1b", nested[1])
+	run("sub1a", nested[0])
+	time.Sleep(5 * time.Millisecond)
+	run("sub0", subs[0])
+	wg.Wait()
+
+	if got := fmt.Sprint(order); got != "[sub0 sub1a sub1b sub2 after]" {
+		t.Fatalf("order %s", got)
+	}
+}
+
+func TestSplitReturned(t *testing.T) {
+	m := NewMutex()
+	subs := m.Split(m.GetTicket(), 3)
+	next := m.GetTicket()
+
+	m.ReturnTicket(subs[0])
+	m.Lock(subs[1])
+	m.Unlock(subs[1])
+	m.ReturnTicket(subs[1]) // after Unlock: no-op
+	m.ReturnTicket(subs[2]) // last one done passes the turn on
+	m.Lock(next)
+	m.Unlock(next)
+
+	all := m.Split(m.GetTicket(), 2)
+	m.ReturnTicket(all[0])
+	m.ReturnTicket(all[1])
+	last := m.GetTicket()
+	m.Lock(last) // the fully returned ticket was burned
+	m.Unlock(last)
+}
+
+func TestCancelLock(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		m    *Mutex
+	}{{"chan", NewMutex()}, {"sema", NewFast()}} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.m
+			t0 := m.GetTicket()
+			stuck := m.GetTicket()
+			t2 := m.GetTicket()
+			if m.CancelLock(stuck) {
+				t.Fatal("CancelLock reported a ticket that never called Lock")
+			}
+
+			m.Lock(t0)
+			errc := make(chan error)
+			go func() { errc <- m.LockErr(stuck) }()
+			for !m.CancelLock(stuck) {
+				time.Sleep(time.Millisecond)
+			}
+			if err := <-errc; err != ErrCanceled {
+				t.Fatalf("LockErr = %v, want ErrCanceled", err)
+			}
+			m.Unlock(t0)
+
+			// The canceled ticket was burned, so t2 is next.
+			m.Lock(t2)
+			if m.CancelLock(t2) {
+				t.Fatal("CancelLock reported the holder")
+			}
+			m.Unlock(t2)
+
+			t3 := m.GetTicket()
+			t4 := m.GetTicket()
+			m.Lock(t3)
+			recovered := make(chan any)
+			go func() {
+				defer func() { recovered <- recover() }()
+				m.Lock(t4)
+			}()
+			for !m.CancelLock(t4) {
+				time.Sleep(time.Millisecond)
+			}
+			if r := <-recovered; r != ErrCanceled {
+				t.Fatalf("Lock recovered %v, want ErrCanceled", r)
+			}
+			m.Unlock(t3)
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	m := NewMutex(WithStats())
+	for range 3 {
+		tk := m.GetTicket()
+		m.Lock(tk)
+		time.Sleep(10 * time.Millisecond)
+		m.Unlock(tk)
+	}
+	hold := m.Stats().RecentHold
+	if hold < 10*time.Millisecond {
+		t.Fatalf("RecentHold = %v, want at least 10ms", hold)
+	}
+
+	holder := m.GetTicket()
+	returned := m.GetTicket()
+	t2 := m.GetTicket()
+	t3 := m.GetTicket()
+	promoted := m.GetTicket()
+	m.Lock(holder)
+	m.ReturnTicket(returned)
+	m.Promote(promoted)
+
+	for _, tc := range []struct {
+		name  string
+		t     Ticket
+		ahead int
+	}{
+		{"holder", holder, 0},
+		{"returned", returned, 0},
+		{"promoted", promoted, 1}, // just the holder
+		{"t2", t2, 2},             // the holder and the promoted ticket
+		{"t3", t3, 3},
+	} {
+		ahead, wait := m.Position(tc.t)
+		if ahead != tc.ahead {
+			t.Errorf("%s: ahead %d, want %d", tc.name, ahead, tc.ahead)
+		}
+		if ahead > 0 && (wait <= time.Duration(ahead-1)*hold/2 || wait > time.Duration(ahead)*hold) {
+			t.Errorf("%s: estimated wait %v with %d ahead at %v per hold", tc.name, wait, ahead, hold)
+		}
+	}
+	plain := NewMutex()
+	plain.GetTicket()
+	if ahead, wait := plain.Position(plain.GetTicket()); ahead != 1 || wait != 0 {
+		t.Fatalf("Position without WithStats = %d, %v; want 1, 0", ahead, wait)
+	}
+
+	m.Unlock(holder)
+	m.ReturnTicket(promoted)
+	m.ReturnTicket(t2)
+	m.ReturnTicket(t3)
+}
+
+func TestWhenMyTurn(t *testing.T) {
+	tasks := make(chan func(), 16)
+	m := NewMutex(WithExecutor(func(f func()) { tasks <- f }))
+	go func() {
+		for f := range tasks {
+			f()
+		}
+	}()
+	defer close(tasks)
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	tickets := make([]Ticket, 5)
+	for i := range tickets {
+		tickets[i] = m.GetTicket()
+	}
+	// Register in reverse; callbacks still run in ticket order.
+	for i := len(tickets) - 1; i >= 0; i-- {
+		if i == 2 {
+			continue
+		}
+		wg.Add(1)
+		m.WhenMyTurn(tickets[i], func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+	m.ReturnTicket(tickets[2])
+	wg.Wait()
+	if got := fmt.Sprint(order); got != "[0 1 3 4]" {
+		t.Fatalf("order %s", got)
+	}
+
+	// Each callback unlocked its ticket, so the next one goes straight in.
+	last := m.GetTicket()
+	m.Lock(last)
+
+	dropped := m.GetTicket()
+	m.WhenMyTurn(dropped, func() { t.Error("canceled callback ran") })
+	if !m.CancelLock(dropped) {
+		t.Fatal("CancelLock did not find the pending callback")
+	}
+	m.Unlock(last)
+
+	final := m.GetTicket()
+	m.Lock(final)
+	m.Unlock(final)
+}
+
+func TestWhenMyTurnPanic(t *testing.T) {
+	recovered := make(chan any)
+	m := NewMutex(WithExecutor(func(f func()) {
+		go func() {
+			defer func() { recovered <- recover() }()
+			f()
+		}()
+	}))
+	m.WhenMyTurn(m.GetTicket(), func() { panic("boom") })
+	if r := <-recovered; r != "boom" {
+		t.Fatalf("recovered %v", r)
+	}
+	// The panicking callback still unlocked.
+	next := m.GetTicket()
+	m.Lock(next)
+	m.Unlock(next)
+}
+
+func TestBoundedAdopt(t *testing.T) {
+	m := NewBounded(2)
+	t0, t1 := m.GetTicket(), m.GetTicket()
+	data, _ := ticket(2).MarshalBinary()
+	adopted, err := m.Adopt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Lock(t0)
+	m.Unlock(t0)
+	t3, ok := m.TryGetTicket()
+	if !ok {
+		t.Fatal("unlocked ticket did not free a slot")
+	}
+	m.Lock(t1)
+	m.Unlock(t1)
+	m.Lock(adopted)
+	m.Unlock(adopted) // holds no slot, so must not free t3's
+
+	t4, ok := m.TryGetTicket()
+	if !ok {
+		t.Fatal("TryGetTicket failed with a free slot")
+	}
+	if _, ok := m.TryGetTicket(); ok {
+		t.Fatal("adopted ticket freed a slot it never held")
+	}
+	m.ReturnTicket(t3)
+	m.ReturnTicket(t4)
+}
+
+func TestSplitSkipped(t *testing.T) {
+	m := NewMutex(WithSkipAbsent(20 * time.Millisecond))
+	subs := m.Split(m.GetTicket(), 2)
+	next := m.GetTicket()
+
+	m.Lock(next) // waits until the split ticket is skipped
+	m.Unlock(next)
+	for i, sub := range subs {
+		if err := m.LockErr(sub); err != ErrSkipped {
+			t.Fatalf("sub-ticket %d: LockErr = %v, want ErrSkipped", i, err)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/sawdustofmind/adv-sync/pkg/ordermutex/ordermutexcheck
+
+go 1.24.4
+
+require golang.org/x/tools v0.38.0
+
+require (
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/sync v0.18.0 // indirect
+)
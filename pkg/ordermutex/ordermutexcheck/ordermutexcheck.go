@@ -0,0 +1,248 @@
+// Package ordermutexcheck defines an Analyzer that reports misuse of
+// ordermutex tickets:
+//
+//   - Lock without a matching Unlock on every path out of the function
+//   - ReturnTicket between Lock and Unlock of the same ticket
+//   - a ticket from one mutex passed to another
+//
+// Checks are local to a function and match mutexes and tickets by expression,
+// like copylocks; tickets handed to other functions are not followed.
+package ordermutexcheck
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+)
+
+const ordermutexPath = "github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "ordermutexcheck",
+	Doc:      "check for OrderMutex ticket misuse",
+	Run:      run,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+}
+
+// ticketCall is a Lock, Unlock or ReturnTicket call on some mutex.
+type ticketCall struct {
+	method string
+	mutex  string // receiver expression
+	ticket string // argument expression
+	call   *ast.CallExpr
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+
+	checkForeignTickets(pass, insp)
+
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		var g *cfg.CFG
+		var body *ast.BlockStmt
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			g, body = cfgs.FuncDecl(fn), fn.Body
+		case *ast.FuncLit:
+			g, body = cfgs.FuncLit(fn), fn.Body
+		}
+		if g == nil || body == nil {
+			return
+		}
+		checkCriticalSections(pass, g, body)
+	})
+	return nil, nil
+}
+
+// checkForeignTickets tracks `t := m.GetTicket()` and reports t used with another mutex.
+func checkForeignTickets(pass *analysis.Pass, insp *inspector.Inspector) {
+	origin := make(map[types.Object]string)
+
+	insp.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return
+			}
+			for i, rhs := range n.Rhs {
+				call, ok := rhs.(*ast.CallExpr)
+				if !ok {
+					continue
+				}
+				mutex, ok := issuer(pass, call)
+				if !ok {
+					continue
+				}
+				if id, ok := n.Lhs[i].(*ast.Ident); ok {
+					if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+						origin[obj] = mutex
+					}
+				}
+			}
+		case *ast.CallExpr:
+			tc, ok := asTicketCall(pass, n)
+			if !ok {
+				return
+			}
+			id, ok := ast.Unparen(n.Args[0]).(*ast.Ident)
+			if !ok {
+				return
+			}
+			if from, ok := origin[pass.TypesInfo.ObjectOf(id)]; ok && from != tc.mutex {
+				pass.Reportf(n.Pos(), "ticket %s from %s passed to %s.%s", tc.ticket, from, tc.mutex, tc.method)
+			}
+		}
+	})
+}
+
+// checkCriticalSections walks every path from each Lock and reports paths that
+// leave the function without Unlock, or that call ReturnTicket on the way.
+func checkCriticalSections(pass *analysis.Pass, g *cfg.CFG, body *ast.BlockStmt) {
+	deferred := make(map[[2]string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // checked on its own
+		case *ast.DeferStmt:
+			if tc, ok := asTicketCall(pass, n.Call); ok && tc.method == "Unlock" {
+				deferred[[2]string{tc.mutex, tc.ticket}] = true
+			}
+		}
+		return true
+	})
+
+	for _, b := range g.Blocks {
+		if !b.Live {
+			continue
+		}
+		for i, node := range b.Nodes {
+			tc, ok := stmtTicketCall(pass, node)
+			if !ok || tc.method != "Lock" {
+				continue
+			}
+			key := [2]string{tc.mutex, tc.ticket}
+			w := walker{pass: pass, key: key, deferredUnlock: deferred[key], seen: make(map[*cfg.Block]bool)}
+			w.walk(b, i+1)
+			if w.leaks && !w.deferredUnlock {
+				pass.Reportf(tc.call.Pos(), "%s.Lock(%s) is not followed by Unlock on every path", tc.mutex, tc.ticket)
+			}
+		}
+	}
+}
+
+type walker struct {
+	pass           *analysis.Pass
+	key            [2]string
+	deferredUnlock bool
+	seen           map[*cfg.Block]bool
+	leaks          bool
+}
+
+// walk follows block b from node index from until the section is unlocked.
+func (w *walker) walk(b *cfg.Block, from int) {
+	for _, node := range b.Nodes[from:] {
+		tc, ok := stmtTicketCall(w.pass, node)
+		if !ok || [2]string{tc.mutex, tc.ticket} != w.key {
+			continue
+		}
+		switch tc.method {
+		case "Unlock":
+			return
+		case "ReturnTicket":
+			w.pass.Reportf(tc.call.Pos(), "%s.ReturnTicket(%s) called while the ticket holds the lock", tc.mutex, tc.ticket)
+		}
+	}
+	if len(b.Succs) == 0 && !endsInPanic(b) {
+		w.leaks = true
+	}
+	for _, s := range b.Succs {
+		if !w.seen[s] {
+			w.seen[s] = true
+			w.walk(s, 0)
+		}
+	}
+}
+
+func endsInPanic(b *cfg.Block) bool {
+	if len(b.Nodes) == 0 {
+		return false
+	}
+	es, ok := b.Nodes[len(b.Nodes)-1].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := es.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	return ok && id.Name == "panic"
+}
+
+// stmtTicketCall matches statements that are a bare ticket call.
+func stmtTicketCall(pass *analysis.Pass, n ast.Node) (ticketCall, bool) {
+	es, ok := n.(*ast.ExprStmt)
+	if !ok {
+		return ticketCall{}, false
+	}
+	call, ok := es.X.(*ast.CallExpr)
+	if !ok {
+		return ticketCall{}, false
+	}
+	return asTicketCall(pass, call)
+}
+
+// asTicketCall matches m.Lock(t), m.Unlock(t) and m.ReturnTicket(t) where t is an ordermutex.Ticket.
+func asTicketCall(pass *analysis.Pass, call *ast.CallExpr) (ticketCall, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 1 {
+		return ticketCall{}, false
+	}
+	switch sel.Sel.Name {
+	case "Lock", "Unlock", "ReturnTicket":
+	default:
+		return ticketCall{}, false
+	}
+	if !isTicket(pass.TypesInfo.TypeOf(call.Args[0])) {
+		return ticketCall{}, false
+	}
+	return ticketCall{
+		method: sel.Sel.Name,
+		mutex:  types.ExprString(sel.X),
+		ticket: types.ExprString(call.Args[0]),
+		call:   call,
+	}, true
+}
+
+// issuer matches m.GetTicket() and m.Requeue(t) returning an ordermutex.Ticket and returns m.
+func issuer(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case sel.Sel.Name == "GetTicket" && len(call.Args) == 0:
+	case sel.Sel.Name == "Requeue" && len(call.Args) == 1:
+	default:
+		return "", false
+	}
+	if !isTicket(pass.TypesInfo.TypeOf(call)) {
+		return "", false
+	}
+	return types.ExprString(sel.X), true
+}
+
+func isTicket(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == "Ticket" && obj.Pkg() != nil && obj.Pkg().Path() == ordermutexPath
+}
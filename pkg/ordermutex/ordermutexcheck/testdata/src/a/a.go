@@ -0,0 +1,59 @@
+package a
+
+import "github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+
+func good(m *ordermutex.Mutex) {
+	t := m.GetTicket()
+	defer m.ReturnTicket(t)
+	m.Lock(t)
+	defer m.Unlock(t)
+}
+
+func goodBranches(m *ordermutex.Mutex, fail bool) error {
+	t := m.GetTicket()
+	m.Lock(t)
+	if fail {
+		m.Unlock(t)
+		return nil
+	}
+	m.Unlock(t)
+	return nil
+}
+
+func goodPanic(m *ordermutex.Mutex, fail bool) {
+	t := m.GetTicket()
+	m.Lock(t)
+	if fail {
+		panic("boom")
+	}
+	m.Unlock(t)
+}
+
+func missingUnlock(m *ordermutex.Mutex, fail bool) {
+	t := m.GetTicket()
+	m.Lock(t) // want `m.Lock\(t\) is not followed by Unlock on every path`
+	if fail {
+		return
+	}
+	m.Unlock(t)
+}
+
+func returnInside(m *ordermutex.Mutex) {
+	t := m.GetTicket()
+	m.Lock(t)
+	m.ReturnTicket(t) // want `m.ReturnTicket\(t\) called while the ticket holds the lock`
+	m.Unlock(t)
+}
+
+func foreign(a, b *ordermutex.Mutex) {
+	t := a.GetTicket()
+	b.Lock(t)   // want `ticket t from a passed to b.Lock`
+	b.Unlock(t) // want `ticket t from a passed to b.Unlock`
+	a.ReturnTicket(t)
+}
+
+func requeued(a, b *ordermutex.Mutex) {
+	t := a.GetTicket()
+	r := a.Requeue(t)
+	b.ReturnTicket(r) // want `ticket r from a passed to b.ReturnTicket`
+}
@@ -0,0 +1,14 @@
+// Package ordermutex is a stub of the real package for analyzer tests.
+package ordermutex
+
+type Ticket interface{ ID() uint64 }
+
+type Mutex struct{}
+
+func New() *Mutex { return &Mutex{} }
+
+func (m *Mutex) GetTicket() Ticket     { return nil }
+func (m *Mutex) Lock(Ticket)           {}
+func (m *Mutex) Unlock(Ticket)         {}
+func (m *Mutex) ReturnTicket(Ticket)   {}
+func (m *Mutex) Requeue(Ticket) Ticket { return nil }
@@ -0,0 +1,169 @@
+// Package ordermutexmock provides an OrderMutex that records calls instead of
+// blocking, so sequencing logic can be unit-tested on a single goroutine.
+package ordermutexmock
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Mutex is a mock OrderMutex. Lock never blocks: a Lock that a real mutex
+// would park (the ticket does not have the turn) is recorded as a failure,
+// as are Unlock by a non-holder and Lock of a returned ticket.
+// Verify reports failures, unmet expectations and leaked tickets.
+type Mutex struct {
+	mu sync.Mutex
+
+	next     uint64
+	cur      uint64
+	held     bool
+	returned map[uint64]struct{}
+	done     map[uint64]struct{}
+
+	locks     []uint64
+	failures  []string
+	wantOrder []uint64
+	wantSet   bool
+}
+
+var _ ordermutex.OrderMutex = (*Mutex)(nil)
+
+func New() *Mutex {
+	return &Mutex{
+		returned: make(map[uint64]struct{}),
+		done:     make(map[uint64]struct{}),
+	}
+}
+
+type ticket uint64
+
+func (t ticket) ID() uint64 { return uint64(t) }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	return nil, fmt.Errorf("ordermutexmock: ticket %d can't be serialized", uint64(t))
+}
+
+// ExpectLockOrder sets the ticket IDs expected to lock, in order.
+// Tickets are numbered from zero in GetTicket order.
+func (m *Mutex) ExpectLockOrder(ids ...uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wantOrder = ids
+	m.wantSet = true
+}
+
+// Locks returns the IDs of the tickets locked so far, in order.
+func (m *Mutex) Locks() []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.locks)
+}
+
+func (m *Mutex) GetTicket() ordermutex.Ticket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.next
+	m.next++
+	return ticket(id)
+}
+
+func (m *Mutex) Lock(t ordermutex.Ticket) {
+	id := t.ID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case id >= m.next:
+		m.failf("Lock(%d): ticket was never issued", id)
+	case m.isReturned(id):
+		m.failf("Lock(%d): ticket was returned", id)
+	case id < m.cur:
+		m.failf("Lock(%d): ticket already passed", id)
+	case m.held:
+		m.failf("Lock(%d): would block, ticket %d holds the lock", id, m.cur)
+	case id != m.cur:
+		m.failf("Lock(%d): would block, ticket %d has the turn", id, m.cur)
+	}
+	m.locks = append(m.locks, id)
+	if id == m.cur {
+		m.held = true
+	}
+}
+
+func (m *Mutex) Unlock(t ordermutex.Ticket) {
+	id := t.ID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.held || id != m.cur {
+		m.failf("Unlock(%d): ticket does not hold the lock", id)
+		return
+	}
+	m.held = false
+	m.done[id] = struct{}{}
+	m.cur++
+	m.skipReturned()
+}
+
+func (m *Mutex) ReturnTicket(t ordermutex.Ticket) {
+	id := t.ID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.held && id == m.cur {
+		m.failf("ReturnTicket(%d): ticket holds the lock", id)
+		return
+	}
+	if _, ok := m.done[id]; ok || id < m.cur {
+		return
+	}
+	m.returned[id] = struct{}{}
+	m.skipReturned()
+}
+
+// Verify fails t for every recorded failure, an unmet lock order expectation,
+// a lock still held, and each issued ticket that was neither unlocked nor returned.
+func (m *Mutex) Verify(t testing.TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.failures {
+		t.Error(f)
+	}
+	if m.wantSet && !slices.Equal(m.locks, m.wantOrder) {
+		t.Errorf("lock order %v, want %v", m.locks, m.wantOrder)
+	}
+	if m.held {
+		t.Errorf("ticket %d still holds the lock", m.cur)
+	}
+	for id := m.cur; id < m.next; id++ {
+		if _, ok := m.done[id]; ok || m.isReturned(id) || (m.held && id == m.cur) {
+			continue
+		}
+		t.Errorf("ticket %d leaked: neither unlocked nor returned", id)
+	}
+}
+
+func (m *Mutex) isReturned(id uint64) bool {
+	_, ok := m.returned[id]
+	return ok
+}
+
+func (m *Mutex) skipReturned() {
+	for m.isReturned(m.cur) {
+		m.cur++
+	}
+}
+
+func (m *Mutex) failf(format string, args ...any) {
+	m.failures = append(m.failures, fmt.Sprintf(format, args...))
+}
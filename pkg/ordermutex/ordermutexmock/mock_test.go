@@ -0,0 +1,60 @@
+package ordermutexmock
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper()           {}
+func (r *recorder) Error(args ...any) { r.errs = append(r.errs, fmt.Sprint(args...)) }
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestMockInOrder(t *testing.T) {
+	m := New()
+	m.ExpectLockOrder(0, 2)
+
+	t0, t1, t2 := m.GetTicket(), m.GetTicket(), m.GetTicket()
+	m.Lock(t0)
+	m.Unlock(t0)
+	m.ReturnTicket(t1)
+	m.Lock(t2)
+	m.Unlock(t2)
+	m.ReturnTicket(t2) // after Unlock: no-op
+
+	m.Verify(t)
+}
+
+func TestMockFailures(t *testing.T) {
+	m := New()
+	m.ExpectLockOrder(0, 1)
+
+	t0, t1, _ := m.GetTicket(), m.GetTicket(), m.GetTicket()
+	m.Lock(t1)
+	m.Lock(t0)
+	m.Unlock(t0)
+
+	var r recorder
+	m.Verify(&r)
+	want := []string{
+		"Lock(1): would block, ticket 0 has the turn",
+		"lock order [1 0], want [0 1]",
+		"ticket 1 leaked",
+		"ticket 2 leaked",
+	}
+	if len(r.errs) != len(want) {
+		t.Fatalf("Verify reported %q, want %d errors", r.errs, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(r.errs[i], want[i]) {
+			t.Errorf("error %d = %q, want prefix %q", i, r.errs[i], want[i])
+		}
+	}
+}
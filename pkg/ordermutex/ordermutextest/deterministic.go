@@ -0,0 +1,239 @@
+// Package ordermutextest provides OrderMutex implementations for testing
+// order-dependent code without sleeps.
+package ordermutextest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Op names a recorded call.
+type Op string
+
+const (
+	OpIssue  Op = "issue"
+	OpWait   Op = "wait"
+	OpLock   Op = "lock"
+	OpUnlock Op = "unlock"
+	OpReturn Op = "return"
+)
+
+// Event is one entry of the history, stamped with virtual time.
+type Event struct {
+	Op     Op
+	Ticket uint64
+	Time   time.Time
+}
+
+func (e Event) String() string { return fmt.Sprintf("%s %d", e.Op, e.Ticket) }
+
+// Deterministic is an OrderMutex whose admissions are driven by the test:
+// a ticket parked in Lock proceeds only when it is its turn and the test
+// calls Step (or Release). Every call is recorded in History with the virtual
+// clock, which moves only through Advance.
+type Deterministic struct {
+	mu      sync.Mutex
+	changed chan struct{} // closed and replaced on every state change
+
+	next     uint64
+	cur      uint64
+	held     bool
+	released bool
+	burned   map[uint64]struct{}
+	parked   map[uint64]chan struct{}
+
+	now     time.Time
+	history []Event
+}
+
+var _ ordermutex.OrderMutex = (*Deterministic)(nil)
+
+// Epoch is the virtual time a Deterministic starts at.
+var Epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func NewDeterministic() *Deterministic {
+	return &Deterministic{
+		changed: make(chan struct{}),
+		burned:  make(map[uint64]struct{}),
+		parked:  make(map[uint64]chan struct{}),
+		now:     Epoch,
+	}
+}
+
+type ticket uint64
+
+func (t ticket) ID() uint64 { return uint64(t) }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	return nil, fmt.Errorf("ordermutextest: ticket %d can't be serialized", uint64(t))
+}
+
+func (d *Deterministic) GetTicket() ordermutex.Ticket {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := d.next
+	d.next++
+	d.record(OpIssue, id)
+	return ticket(id)
+}
+
+func (d *Deterministic) Lock(t ordermutex.Ticket) {
+	id := t.ID()
+
+	d.mu.Lock()
+	if d.released && id == d.cur && !d.held {
+		d.admit(id)
+		d.mu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	d.parked[id] = ch
+	d.record(OpWait, id)
+	d.mu.Unlock()
+
+	<-ch
+}
+
+func (d *Deterministic) Unlock(t ordermutex.Ticket) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if id := t.ID(); id != d.cur || !d.held {
+		panic(fmt.Sprintf("ordermutextest: Unlock(%d) by a ticket that does not hold the lock", id))
+	}
+	d.record(OpUnlock, d.cur)
+	d.held = false
+	d.cur++
+	d.skipBurned()
+}
+
+func (d *Deterministic) ReturnTicket(t ordermutex.Ticket) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := t.ID()
+	if id < d.cur {
+		return
+	}
+	if _, ok := d.burned[id]; ok {
+		return
+	}
+	d.burned[id] = struct{}{}
+	delete(d.parked, id)
+	d.record(OpReturn, id)
+	d.skipBurned()
+}
+
+// Step admits the ticket whose turn it is and returns its ID, first waiting
+// for the current holder to unlock and for that ticket to park in Lock.
+// It reports false if the turn ticket has not been issued.
+func (d *Deterministic) Step() (uint64, bool) {
+	for {
+		d.mu.Lock()
+		if !d.held && d.cur >= d.next {
+			d.mu.Unlock()
+			return 0, false
+		}
+		if _, ok := d.parked[d.cur]; ok && !d.held {
+			id := d.cur
+			d.admit(id)
+			d.mu.Unlock()
+			return id, true
+		}
+		changed := d.changed
+		d.mu.Unlock()
+		<-changed
+	}
+}
+
+// WaitParked blocks until ticket id is parked in Lock.
+func (d *Deterministic) WaitParked(id uint64) {
+	for {
+		d.mu.Lock()
+		_, ok := d.parked[id]
+		changed := d.changed
+		d.mu.Unlock()
+		if ok {
+			return
+		}
+		<-changed
+	}
+}
+
+// Release stops driving admissions: from now on tickets are admitted as soon
+// as it is their turn, like a regular OrderMutex.
+func (d *Deterministic) Release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.released = true
+	d.wakeReleased()
+}
+
+// Held returns the ticket holding the lock, if any.
+func (d *Deterministic) Held() (uint64, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cur, d.held
+}
+
+// Advance moves the virtual clock forward.
+func (d *Deterministic) Advance(dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = d.now.Add(dur)
+}
+
+// Now returns the virtual clock.
+func (d *Deterministic) Now() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.now
+}
+
+// History returns every recorded call in order.
+func (d *Deterministic) History() []Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Event(nil), d.history...)
+}
+
+// admit lets parked ticket id (or an arriving one) take the lock; mu must be held.
+func (d *Deterministic) admit(id uint64) {
+	d.held = true
+	if ch, ok := d.parked[id]; ok {
+		delete(d.parked, id)
+		close(ch)
+	}
+	d.record(OpLock, id)
+}
+
+func (d *Deterministic) skipBurned() {
+	for {
+		if _, ok := d.burned[d.cur]; !ok {
+			break
+		}
+		delete(d.burned, d.cur)
+		d.cur++
+	}
+	if d.released {
+		d.wakeReleased()
+	}
+}
+
+func (d *Deterministic) wakeReleased() {
+	if _, ok := d.parked[d.cur]; ok && !d.held {
+		d.admit(d.cur)
+	}
+}
+
+// record appends to the history and wakes anyone waiting for a state change.
+func (d *Deterministic) record(op Op, id uint64) {
+	d.history = append(d.history, Event{Op: op, Ticket: id, Time: d.now})
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
@@ -0,0 +1,72 @@
+package ordermutextest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeterministicStep(t *testing.T) {
+	d := NewDeterministic()
+	t0 := d.GetTicket()
+	t1 := d.GetTicket()
+	t2 := d.GetTicket()
+
+	// Park in reverse order; admission still follows ticket order.
+	done := make(chan struct{})
+	go func() {
+		d.Lock(t2)
+		d.Unlock(t2)
+		close(done)
+	}()
+	d.WaitParked(t2.ID())
+	go func() {
+		d.Lock(t0)
+		d.Advance(time.Second)
+		d.Unlock(t0)
+	}()
+	d.WaitParked(t0.ID())
+
+	d.ReturnTicket(t1)
+	if id, ok := d.Step(); !ok || id != 0 {
+		t.Fatalf("Step = %d, %v; want 0, true", id, ok)
+	}
+	if id, ok := d.Step(); !ok || id != 2 {
+		t.Fatalf("Step = %d, %v; want 2, true", id, ok)
+	}
+	<-done
+	if _, ok := d.Step(); ok {
+		t.Fatal("Step admitted an unissued ticket")
+	}
+
+	h := d.History()
+	want := "[issue 0 issue 1 issue 2 wait 2 wait 0 return 1 lock 0 unlock 0 lock 2 unlock 2]"
+	if got := fmt.Sprint(h); got != want {
+		t.Fatalf("history = %s, want %s", got, want)
+	}
+	if at := h[len(h)-1].Time; !at.Equal(Epoch.Add(time.Second)) {
+		t.Fatalf("unlock at %v, want %v", at, Epoch.Add(time.Second))
+	}
+}
+
+func TestDeterministicRelease(t *testing.T) {
+	d := NewDeterministic()
+	d.Release()
+
+	t0 := d.GetTicket()
+	d.Lock(t0)
+	if id, held := d.Held(); !held || id != 0 {
+		t.Fatalf("Held = %d, %v; want 0, true", id, held)
+	}
+
+	t1 := d.GetTicket()
+	done := make(chan struct{})
+	go func() {
+		d.Lock(t1)
+		d.Unlock(t1)
+		close(done)
+	}()
+	d.WaitParked(t1.ID())
+	d.Unlock(t0)
+	<-done
+}
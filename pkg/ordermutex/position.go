@@ -0,0 +1,52 @@
+package ordermutex
+
+import "time"
+
+// Position returns how many tickets will hold the lock before t, counting
+// the current holder, and an estimate of how long they will take, from the
+// recent hold times tracked by WithStats; without it the estimate is zero.
+// A ticket that holds the lock, has passed or was returned reports zero.
+func (m *Mutex) Position(t Ticket) (ahead int, estimatedWait time.Duration) {
+	id := t.ID()
+	if m.passedFast(id) {
+		return 0, 0
+	}
+
+	m.lock()
+	ahead = m.ahead(id)
+	var heldFor time.Duration
+	if m.held && !m.heldAt.IsZero() {
+		heldFor = time.Since(m.heldAt)
+	}
+	m.unlock()
+
+	if ahead == 0 || m.stats == nil {
+		return ahead, 0
+	}
+	hold := m.stats.recentHold()
+	// The holder is partway through its hold.
+	estimatedWait = time.Duration(ahead)*hold - min(heldFor, hold)
+	return ahead, estimatedWait
+}
+
+// ahead counts the live tickets to be served before id; mu must be held.
+func (m *Mutex) ahead(id uint64) int {
+	if id < m.cur || id == m.turn() || m.burned.has(id) {
+		return 0
+	}
+	for i, p := range m.promoted {
+		if p == id {
+			return i + 1
+		}
+	}
+	n := id - m.cur - m.burned.count(m.cur, id)
+	if m.overActive && m.over > id {
+		n++
+	}
+	for _, p := range m.promoted {
+		if p > id && !m.burned.has(p) {
+			n++
+		}
+	}
+	return int(n)
+}
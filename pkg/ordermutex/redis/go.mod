@@ -0,0 +1,18 @@
+module github.com/sawdustofmind/adv-sync/pkg/ordermutex/redis
+
+go 1.24.4
+
+require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/redis/go-redis/v9 v9.22.0
+	github.com/sawdustofmind/adv-sync v0.0.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	go.uber.org/atomic v1.11.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+)
+
+replace github.com/sawdustofmind/adv-sync => ../../..
@@ -0,0 +1,442 @@
+// Package redis implements an OrderMutex shared by processes through Redis,
+// so replicas serialize work in one global ticket order.
+//
+//	m := redis.New(rdb, redis.Config{Name: "ingest"})
+//	defer m.Close()
+//	t := m.GetTicket()
+//	m.Lock(t)
+//	defer m.Unlock(t)
+//
+// The ticket counter, the current turn and the returned tickets live in Redis
+// and change only through Lua scripts, so every transition is atomic. Each
+// turn change is published on a channel; a replica wakes only the local Lock
+// waiting for that ticket, and polls the turn as a fallback for missed
+// messages.
+//
+// A replica that crashes between GetTicket and Unlock stalls the order; any
+// replica can unblock it by returning the stuck ticket with ReturnTicket.
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+var (
+	// ErrTicketPassed is returned by Lock for a ticket whose turn is over.
+	ErrTicketPassed = errors.New("ordermutex/redis: ticket already passed")
+	// ErrNotHeld is returned by Unlock for a ticket that does not have the turn.
+	ErrNotHeld = errors.New("ordermutex/redis: ticket does not hold the lock")
+	// ErrClosed is returned by Lock after Close.
+	ErrClosed = errors.New("ordermutex/redis: mutex closed")
+)
+
+// Config configures a Mutex.
+type Config struct {
+	// Name identifies the order; mutexes with the same Name on the same
+	// Redis share it. Keys are hash-tagged by Name, so it works on a cluster.
+	Name string
+	// PollInterval is how often waiting replicas re-read the turn in case a
+	// wakeup message was lost. Zero means one second.
+	PollInterval time.Duration
+	// RetryBackoff is the first delay before the OrderMutex methods retry a
+	// failed Redis call; it doubles up to 32 times that. Zero means 50ms.
+	RetryBackoff time.Duration
+	// OnError, if set, is called with every Redis error the OrderMutex
+	// methods retry.
+	OnError func(error)
+	// IssueTTL is how long GetTicket remembers a request so that retrying it
+	// does not issue a second ticket. Zero means one minute.
+	IssueTTL time.Duration
+}
+
+// Mutex is an OrderMutex backed by Redis. The OrderMutex methods retry Redis
+// errors until they succeed; the Context variants return them instead.
+type Mutex struct {
+	rdb     goredis.UniversalClient
+	cfg     Config
+	keys    []string // next, cur, burned
+	channel string
+
+	mu      sync.Mutex
+	waiters map[uint64]chan uint64
+	sub     *goredis.PubSub
+	closed  bool
+	done    chan struct{}
+}
+
+var _ ordermutex.OrderMutex = (*Mutex)(nil)
+
+// New returns a Mutex for the order cfg.Name on rdb.
+func New(rdb goredis.UniversalClient, cfg Config) *Mutex {
+	if cfg.Name == "" {
+		panic("redis: New called with empty Name")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	if cfg.IssueTTL <= 0 {
+		cfg.IssueTTL = time.Minute
+	}
+	prefix := "ordermutex:{" + cfg.Name + "}:"
+	return &Mutex{
+		rdb:     rdb,
+		cfg:     cfg,
+		keys:    []string{prefix + "next", prefix + "cur", prefix + "burned"},
+		channel: prefix + "turn",
+		waiters: make(map[uint64]chan uint64),
+		done:    make(chan struct{}),
+	}
+}
+
+// Wire format, shared with ordermutex: version byte, big-endian ticket ID.
+const (
+	ticketVersion = 1
+	ticketSize    = 1 + 8
+)
+
+type ticket uint64
+
+func (t ticket) ID() uint64 { return uint64(t) }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, ticketSize)
+	b[0] = ticketVersion
+	binary.BigEndian.PutUint64(b[1:], uint64(t))
+	return b, nil
+}
+
+// Adopt decodes a ticket serialized with MarshalBinary, typically by another
+// replica, so it can be locked or returned here.
+func (m *Mutex) Adopt(data []byte) (ordermutex.Ticket, error) {
+	if len(data) != ticketSize || data[0] != ticketVersion {
+		return nil, ordermutex.ErrInvalidTicket
+	}
+	return ticket(binary.BigEndian.Uint64(data[1:])), nil
+}
+
+// advance moves the turn to cur, past any returned tickets, and publishes it.
+// Numbers are formatted by hand: Lua prints large ones in exponent form.
+const advance = `
+local function str(n) return string.format('%.0f', n) end
+local function advance(cur)
+	while redis.call('ZSCORE', KEYS[3], str(cur)) do
+		redis.call('ZREM', KEYS[3], str(cur))
+		cur = cur + 1
+	end
+	redis.call('SET', KEYS[2], str(cur))
+	redis.call('PUBLISH', ARGV[2], str(cur))
+end
+local cur = tonumber(redis.call('GET', KEYS[2]) or '0')
+local id = tonumber(ARGV[1])
+`
+
+var (
+	// issueScript issues the next ticket, or the one already issued for the
+	// request nonce in KEYS[4].
+	issueScript = goredis.NewScript(`
+local id = redis.call('GET', KEYS[4])
+if id then return tonumber(id) end
+id = redis.call('INCR', KEYS[1]) - 1
+redis.call('SET', KEYS[4], id, 'PX', ARGV[1])
+return id
+`)
+	// unlockScript passes the turn on from ARGV[1]; unlocking again is a no-op.
+	unlockScript = goredis.NewScript(advance + `
+if id < cur then return 0 end
+if id > cur then return -1 end
+advance(cur + 1)
+return 1
+`)
+	// returnScript burns ARGV[1], passing the turn on if it is current.
+	returnScript = goredis.NewScript(advance + `
+if id < cur then return 0 end
+if id == cur then
+	advance(cur + 1)
+else
+	redis.call('ZADD', KEYS[3], id, str(id))
+end
+return 1
+`)
+)
+
+func (m *Mutex) GetTicket() ordermutex.Ticket {
+	nonce := newNonce()
+	var t ordermutex.Ticket
+	m.retry(func(ctx context.Context) (err error) {
+		t, err = m.issue(ctx, nonce)
+		return err
+	})
+	return t
+}
+
+// GetTicketContext issues a ticket. If it fails the ticket may still have
+// been issued, and the order stalls on it until it is returned; GetTicket
+// avoids this by retrying the same request.
+func (m *Mutex) GetTicketContext(ctx context.Context) (ordermutex.Ticket, error) {
+	return m.issue(ctx, newNonce())
+}
+
+func (m *Mutex) issue(ctx context.Context, nonce string) (ordermutex.Ticket, error) {
+	keys := append(m.keys[:3:3], m.keys[0]+":req:"+nonce)
+	id, err := issueScript.Run(ctx, m.rdb, keys, m.cfg.IssueTTL.Milliseconds()).Uint64()
+	if err != nil {
+		return nil, err
+	}
+	return ticket(id), nil
+}
+
+func newNonce() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Lock blocks until t has the turn. It panics if t's turn is already over.
+func (m *Mutex) Lock(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		err := m.lock(ctx, t.ID())
+		if errors.Is(err, ErrTicketPassed) || errors.Is(err, ErrClosed) {
+			panic(err)
+		}
+		return err
+	})
+}
+
+// LockContext blocks until t has the turn or ctx is done. If ctx ends first,
+// t is returned as by ReturnTicket, so the order does not stall on it; if
+// that fails too, the error says so and t is still outstanding.
+func (m *Mutex) LockContext(ctx context.Context, t ordermutex.Ticket) error {
+	return m.lock(ctx, t.ID())
+}
+
+func (m *Mutex) lock(ctx context.Context, id uint64) error {
+	ch := make(chan uint64, 1) // the turn seen when woken
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	if _, ok := m.waiters[id]; ok {
+		m.mu.Unlock()
+		panic("Lock called for a ticket that is already waiting")
+	}
+	m.waiters[id] = ch
+	m.mu.Unlock()
+
+	// Subscribe before reading the turn, so no change is missed in between.
+	err := m.subscribe(ctx)
+	var cur uint64
+	if err == nil {
+		cur, err = m.turn(ctx)
+	}
+	switch {
+	case err != nil:
+	case cur == id:
+		m.forget(id, ch)
+		return nil
+	case cur > id:
+		err = ErrTicketPassed
+	default:
+		select {
+		case cur := <-ch:
+			return woken(id, cur)
+		case <-m.done:
+			err = ErrClosed
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if !m.forget(id, ch) {
+		return woken(id, <-ch) // woken as we gave up
+	}
+	if err == ctx.Err() {
+		if rerr := m.ReturnTicketContext(context.WithoutCancel(ctx), ticket(id)); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+	}
+	return err
+}
+
+// woken reports whether waiter id, woken on seeing turn cur, holds the lock.
+// A poll also wakes waiters whose turn went by, for instance because another
+// replica returned their ticket; they must not enter.
+func woken(id, cur uint64) error {
+	if cur != id {
+		return ErrTicketPassed
+	}
+	return nil
+}
+
+// forget removes the waiter for id and reports whether it was still waiting.
+func (m *Mutex) forget(id uint64, ch chan uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.waiters[id] != ch {
+		return false
+	}
+	delete(m.waiters, id)
+	return true
+}
+
+func (m *Mutex) turn(ctx context.Context) (uint64, error) {
+	cur, err := m.rdb.Get(ctx, m.keys[1]).Uint64()
+	if err == goredis.Nil {
+		return 0, nil
+	}
+	return cur, err
+}
+
+// subscribe starts listening for turn changes, once.
+func (m *Mutex) subscribe(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sub != nil || m.closed {
+		return nil
+	}
+	sub := m.rdb.Subscribe(ctx, m.channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return err
+	}
+	m.sub = sub
+	go m.listen(sub)
+	return nil
+}
+
+func (m *Mutex) listen(sub *goredis.PubSub) {
+	poll := time.NewTicker(m.cfg.PollInterval)
+	defer poll.Stop()
+	msgs := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if cur, err := strconv.ParseUint(msg.Payload, 10, 64); err == nil {
+				m.wake(cur, false)
+			}
+		case <-poll.C:
+			m.mu.Lock()
+			idle := len(m.waiters) == 0
+			m.mu.Unlock()
+			if idle {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), m.cfg.PollInterval)
+			cur, err := m.turn(ctx)
+			cancel()
+			if err != nil {
+				m.report(err)
+				continue
+			}
+			m.wake(cur, true)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// wake wakes the waiter for ticket cur and, after a poll, those whose turn
+// went by unnoticed so they can fail.
+func (m *Mutex) wake(cur uint64, passed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ch, ok := m.waiters[cur]; ok {
+		delete(m.waiters, cur)
+		ch <- cur
+	}
+	if !passed {
+		return
+	}
+	for id, ch := range m.waiters {
+		if id < cur {
+			delete(m.waiters, id)
+			ch <- cur
+		}
+	}
+}
+
+// Unlock passes the turn on. It panics if t does not hold the lock.
+func (m *Mutex) Unlock(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		err := m.UnlockContext(ctx, t)
+		if errors.Is(err, ErrNotHeld) {
+			panic(err)
+		}
+		return err
+	})
+}
+
+// UnlockContext passes the turn on. Unlocking a ticket again is a no-op, so
+// a failed call can be retried.
+func (m *Mutex) UnlockContext(ctx context.Context, t ordermutex.Ticket) error {
+	n, err := unlockScript.Run(ctx, m.rdb, m.keys, t.ID(), m.channel).Int()
+	if err == nil && n < 0 {
+		return ErrNotHeld
+	}
+	return err
+}
+
+// ReturnTicket cancels a ticket that has not locked, from any replica.
+// Returning a ticket whose turn is over is a no-op.
+func (m *Mutex) ReturnTicket(t ordermutex.Ticket) {
+	m.retry(func(ctx context.Context) error {
+		return m.ReturnTicketContext(ctx, t)
+	})
+}
+
+// ReturnTicketContext is like ReturnTicket but gives up with ctx.
+func (m *Mutex) ReturnTicketContext(ctx context.Context, t ordermutex.Ticket) error {
+	return returnScript.Run(ctx, m.rdb, m.keys, t.ID(), m.channel).Err()
+}
+
+// Close stops listening for turn changes; pending and later Locks fail.
+// It does not close the Redis client.
+func (m *Mutex) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	if m.sub != nil {
+		return m.sub.Close()
+	}
+	return nil
+}
+
+// retry runs op until it succeeds, backing off between failures.
+func (m *Mutex) retry(op func(context.Context) error) {
+	backoff := m.cfg.RetryBackoff
+	for {
+		err := op(context.Background())
+		if err == nil {
+			return
+		}
+		m.report(err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 32*m.cfg.RetryBackoff)
+	}
+}
+
+func (m *Mutex) report(err error) {
+	if m.cfg.OnError != nil {
+		m.cfg.OnError(err)
+	}
+}
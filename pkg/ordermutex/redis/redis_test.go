@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// replicas returns n mutexes sharing one order, each with its own client.
+func replicas(t *testing.T, n int) []*Mutex {
+	srv := miniredis.RunT(t)
+	ms := make([]*Mutex, n)
+	for i := range ms {
+		rdb := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+		ms[i] = New(rdb, Config{Name: "test", PollInterval: 50 * time.Millisecond})
+		t.Cleanup(func() {
+			ms[i].Close()
+			rdb.Close()
+		})
+	}
+	return ms
+}
+
+func TestOrderAcrossReplicas(t *testing.T) {
+	ms := replicas(t, 3)
+	var (
+		mu    sync.Mutex
+		order []uint64
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 12; i++ {
+		m := ms[i%3]
+		tk := m.GetTicket()
+		if tk.ID() != uint64(i) {
+			t.Fatalf("ticket %d issued as %d", i, tk.ID())
+		}
+		if i == 5 {
+			ms[0].ReturnTicket(tk) // returned by another replica
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Duration(12-i) * time.Millisecond) // lock in reverse
+			m.Lock(tk)
+			mu.Lock()
+			order = append(order, tk.ID())
+			mu.Unlock()
+			m.Unlock(tk)
+		}()
+	}
+	wg.Wait()
+	if got := fmt.Sprint(order); got != "[0 1 2 3 4 6 7 8 9 10 11]" {
+		t.Fatalf("locked in order %s", got)
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	ms := replicas(t, 2)
+	a, b := ms[0], ms[1]
+	t0 := a.GetTicket()
+	t1 := b.GetTicket()
+	t2 := b.GetTicket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := b.LockContext(ctx, t1); err != context.DeadlineExceeded {
+		t.Fatalf("LockContext = %v", err)
+	}
+
+	// The abandoned ticket was returned: t2 follows t0 directly.
+	a.Lock(t0)
+	a.Unlock(t0)
+	if err := b.LockContext(context.Background(), t2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UnlockContext(context.Background(), t2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.UnlockContext(context.Background(), t2); err != nil {
+		t.Fatalf("repeated unlock = %v", err)
+	}
+	if err := a.LockContext(context.Background(), t0); err != ErrTicketPassed {
+		t.Fatalf("Lock of a passed ticket = %v", err)
+	}
+	t3 := a.GetTicket()
+	t4 := a.GetTicket()
+	if err := a.UnlockContext(context.Background(), t4); err != ErrNotHeld {
+		t.Fatalf("Unlock out of turn = %v", err)
+	}
+	a.ReturnTicket(t3)
+	a.ReturnTicket(t4)
+}
+
+func TestIssueRetry(t *testing.T) {
+	m := replicas(t, 1)[0]
+	ctx := context.Background()
+	t0, _ := m.issue(ctx, "req")
+	again, _ := m.issue(ctx, "req")
+	t1, _ := m.issue(ctx, "other")
+	if t0.ID() != 0 || again.ID() != 0 || t1.ID() != 1 {
+		t.Fatalf("issued %d, %d, %d; want 0, 0, 1", t0.ID(), again.ID(), t1.ID())
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	ms := replicas(t, 2)
+	t0 := ms[0].GetTicket()
+	data, _ := t0.(encoding.BinaryMarshaler).MarshalBinary()
+	tk, err := ms[1].Adopt(data)
+	if err != nil || tk.ID() != t0.ID() {
+		t.Fatalf("Adopt = %v, %v", tk, err)
+	}
+	ms[1].Lock(tk)
+	ms[1].Unlock(tk)
+	if _, err := ms[1].Adopt(data[1:]); err == nil {
+		t.Fatal("Adopt accepted bad data")
+	}
+}
+
+func TestPollFallback(t *testing.T) {
+	m := replicas(t, 1)[0]
+	m.GetTicket() // never locked
+	t1 := m.GetTicket()
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	// Move the turn without publishing, as if the message was lost.
+	m.rdb.Set(context.Background(), m.keys[1], t1.ID(), 0)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken by polling")
+	}
+}
+
+func TestPassedWhileWaiting(t *testing.T) {
+	ms := replicas(t, 2)
+	t0 := ms[0].GetTicket()
+	t1 := ms[0].GetTicket()
+	t2 := ms[0].GetTicket()
+	errc := make(chan error)
+	go func() { errc <- ms[0].LockContext(context.Background(), t1) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// Another replica gives up the stuck t1 while t0 holds, then t0 unlocks:
+	// the turn skips t1, and its waiter must not enter alongside t2.
+	ms[1].Lock(t0)
+	ms[1].ReturnTicket(t1)
+	ms[1].Unlock(t0)
+	if err := <-errc; !errors.Is(err, ErrTicketPassed) {
+		t.Fatalf("LockContext = %v, want ErrTicketPassed", err)
+	}
+	ms[1].Lock(t2)
+	ms[1].Unlock(t2)
+}
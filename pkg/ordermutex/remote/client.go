@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex/remote/remotepb"
+)
+
+// Client talks to a Server.
+type Client struct {
+	c remotepb.SequencerClient
+}
+
+// NewClient returns a client using conn.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{c: remotepb.NewSequencerClient(conn)}
+}
+
+// Mutex returns a handle on the named domain.
+func (c *Client) Mutex(domain string) *Mutex {
+	return &Mutex{c: c.c, domain: domain, held: make(map[uint64]context.CancelFunc)}
+}
+
+// Mutex is a remote domain. Its methods mirror ordermutex.OrderMutex, but
+// take a context and return the RPC error.
+type Mutex struct {
+	c      remotepb.SequencerClient
+	domain string
+
+	mu   sync.Mutex
+	held map[uint64]context.CancelFunc // ends the Lock stream of each held ticket
+}
+
+// Wire format, shared with ordermutex: version byte, big-endian ticket ID.
+const (
+	ticketVersion = 1
+	ticketSize    = 1 + 8
+)
+
+type ticket uint64
+
+func (t ticket) ID() uint64 { return uint64(t) }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, ticketSize)
+	b[0] = ticketVersion
+	binary.BigEndian.PutUint64(b[1:], uint64(t))
+	return b, nil
+}
+
+// GetTicket issues a ticket.
+func (m *Mutex) GetTicket(ctx context.Context) (ordermutex.Ticket, error) {
+	resp, err := m.c.GetTicket(ctx, &remotepb.GetTicketRequest{Domain: m.domain})
+	if err != nil {
+		return nil, err
+	}
+	return ticket(resp.GetTicket()), nil
+}
+
+// Lock blocks until t has the turn or ctx is done; in the latter case the
+// ticket is given up. The lock is held until Unlock, or until the connection
+// to the server is lost.
+func (m *Mutex) Lock(ctx context.Context, t ordermutex.Ticket) error {
+	// The stream outlives ctx: it carries the lock until Unlock.
+	sctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+	stream, err := m.c.Lock(sctx, &remotepb.LockRequest{Domain: m.domain, Ticket: t.ID()})
+	for err == nil {
+		var ev *remotepb.LockEvent
+		if ev, err = stream.Recv(); err == nil && ev.GetState() == remotepb.LockEvent_STATE_ACQUIRED {
+			break
+		}
+	}
+	if !stop() || err != nil {
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	m.mu.Lock()
+	m.held[t.ID()] = cancel
+	m.mu.Unlock()
+	return nil
+}
+
+// Unlock releases t.
+func (m *Mutex) Unlock(ctx context.Context, t ordermutex.Ticket) error {
+	m.mu.Lock()
+	cancel, ok := m.held[t.ID()]
+	delete(m.held, t.ID())
+	m.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.FailedPrecondition, "ticket %d is not held here", t.ID())
+	}
+	defer cancel()
+	_, err := m.c.Unlock(ctx, &remotepb.UnlockRequest{Domain: m.domain, Ticket: t.ID()})
+	return err
+}
+
+// ReturnTicket cancels a ticket that has not been locked.
+func (m *Mutex) ReturnTicket(ctx context.Context, t ordermutex.Ticket) error {
+	_, err := m.c.ReturnTicket(ctx, &remotepb.ReturnTicketRequest{Domain: m.domain, Ticket: t.ID()})
+	return err
+}
@@ -0,0 +1,19 @@
+module github.com/sawdustofmind/adv-sync/pkg/ordermutex/remote
+
+go 1.24.4
+
+require (
+	github.com/sawdustofmind/adv-sync v0.0.0
+	google.golang.org/grpc v1.71.1
+	google.golang.org/protobuf v1.36.8
+)
+
+require (
+	go.uber.org/atomic v1.11.0 // indirect
+	golang.org/x/net v0.47.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250303144028-a0af3efb3deb // indirect
+)
+
+replace github.com/sawdustofmind/adv-sync => ../../..
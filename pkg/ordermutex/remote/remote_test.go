@@ -0,0 +1,128 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex/remote/remotepb"
+)
+
+// serve starts srv in memory and returns a connected client.
+func serve(t *testing.T, srv *Server) *Client {
+	lis := bufconn.Listen(1 << 16)
+	gs := grpc.NewServer()
+	remotepb.RegisterSequencerServer(gs, srv)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestOrder(t *testing.T) {
+	srv := NewServer()
+	rm := serve(t, srv).Mutex("jobs")
+	local := srv.Mutex("jobs")
+	ctx := context.Background()
+
+	var (
+		mu    sync.Mutex
+		order []uint64
+		wg    sync.WaitGroup
+	)
+	record := func(id uint64) {
+		mu.Lock()
+		order = append(order, id)
+		mu.Unlock()
+	}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		delay := time.Duration(6-i) * 5 * time.Millisecond // lock in reverse
+		if i%2 == 0 {
+			tk, err := rm.GetTicket(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() {
+				defer wg.Done()
+				time.Sleep(delay)
+				if err := rm.Lock(ctx, tk); err != nil {
+					t.Error(err)
+					return
+				}
+				record(tk.ID())
+				if err := rm.Unlock(ctx, tk); err != nil {
+					t.Error(err)
+				}
+			}()
+		} else {
+			tk := local.GetTicket() // Go code in the server shares the order
+			go func() {
+				defer wg.Done()
+				time.Sleep(delay)
+				local.Lock(tk)
+				record(tk.ID())
+				local.Unlock(tk)
+			}()
+		}
+	}
+	wg.Wait()
+	if got := fmt.Sprint(order); got != "[0 1 2 3 4 5]" {
+		t.Fatalf("locked in order %s", got)
+	}
+}
+
+func TestLostClient(t *testing.T) {
+	srv := NewServer()
+	rm := serve(t, srv).Mutex("jobs")
+	ctx := context.Background()
+
+	t0, _ := rm.GetTicket(ctx)
+	t1, _ := rm.GetTicket(ctx)
+	t2, _ := rm.GetTicket(ctx)
+	t3, _ := rm.GetTicket(ctx)
+	if err := rm.ReturnTicket(ctx, t1); err != nil {
+		t.Fatal(err)
+	}
+
+	// t2 gives up waiting: its ticket must not stall t3.
+	wctx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if err := rm.Lock(wctx, t2); err != context.DeadlineExceeded {
+		t.Fatalf("Lock = %v", err)
+	}
+
+	if err := rm.Lock(ctx, t0); err != nil {
+		t.Fatal(err)
+	}
+	// A holder whose stream ends releases the lock.
+	rm.mu.Lock()
+	rm.held[t0.ID()]()
+	rm.mu.Unlock()
+
+	lctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := rm.Lock(lctx, t3); err != nil {
+		t.Fatalf("order stalled: %v", err)
+	}
+	if err := rm.Unlock(ctx, t3); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.Unlock(ctx, t3); err == nil {
+		t.Fatal("second Unlock succeeded")
+	}
+}
@@ -0,0 +1,4 @@
+// Package remotepb holds the protocol of the remote order mutex service.
+package remotepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative remote.proto
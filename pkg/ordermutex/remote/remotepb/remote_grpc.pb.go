@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: remote.proto
+
+package remotepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Sequencer_GetTicket_FullMethodName    = "/advsync.ordermutex.remote.v1.Sequencer/GetTicket"
+	Sequencer_Lock_FullMethodName         = "/advsync.ordermutex.remote.v1.Sequencer/Lock"
+	Sequencer_Unlock_FullMethodName       = "/advsync.ordermutex.remote.v1.Sequencer/Unlock"
+	Sequencer_ReturnTicket_FullMethodName = "/advsync.ordermutex.remote.v1.Sequencer/ReturnTicket"
+)
+
+// SequencerClient is the client API for Sequencer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Sequencer serves order mutexes over the network. Each domain is one
+// order; tickets are numbered per domain from zero.
+type SequencerClient interface {
+	// GetTicket issues the next ticket in a domain.
+	GetTicket(ctx context.Context, in *GetTicketRequest, opts ...grpc.CallOption) (*GetTicketResponse, error)
+	// Lock waits for a ticket's turn. The server sends QUEUED, then ACQUIRED
+	// once the turn arrives. The lock is held until Unlock or until the stream
+	// ends; a stream that ends before ACQUIRED gives the ticket up.
+	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockEvent], error)
+	// Unlock releases a ticket held through Lock.
+	Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error)
+	// ReturnTicket cancels a ticket that has not been locked.
+	ReturnTicket(ctx context.Context, in *ReturnTicketRequest, opts ...grpc.CallOption) (*ReturnTicketResponse, error)
+}
+
+type sequencerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSequencerClient(cc grpc.ClientConnInterface) SequencerClient {
+	return &sequencerClient{cc}
+}
+
+func (c *sequencerClient) GetTicket(ctx context.Context, in *GetTicketRequest, opts ...grpc.CallOption) (*GetTicketResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTicketResponse)
+	err := c.cc.Invoke(ctx, Sequencer_GetTicket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LockEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Sequencer_ServiceDesc.Streams[0], Sequencer_Lock_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LockRequest, LockEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sequencer_LockClient = grpc.ServerStreamingClient[LockEvent]
+
+func (c *sequencerClient) Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnlockResponse)
+	err := c.cc.Invoke(ctx, Sequencer_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sequencerClient) ReturnTicket(ctx context.Context, in *ReturnTicketRequest, opts ...grpc.CallOption) (*ReturnTicketResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReturnTicketResponse)
+	err := c.cc.Invoke(ctx, Sequencer_ReturnTicket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SequencerServer is the server API for Sequencer service.
+// All implementations must embed UnimplementedSequencerServer
+// for forward compatibility.
+//
+// Sequencer serves order mutexes over the network. Each domain is one
+// order; tickets are numbered per domain from zero.
+type SequencerServer interface {
+	// GetTicket issues the next ticket in a domain.
+	GetTicket(context.Context, *GetTicketRequest) (*GetTicketResponse, error)
+	// Lock waits for a ticket's turn. The server sends QUEUED, then ACQUIRED
+	// once the turn arrives. The lock is held until Unlock or until the stream
+	// ends; a stream that ends before ACQUIRED gives the ticket up.
+	Lock(*LockRequest, grpc.ServerStreamingServer[LockEvent]) error
+	// Unlock releases a ticket held through Lock.
+	Unlock(context.Context, *UnlockRequest) (*UnlockResponse, error)
+	// ReturnTicket cancels a ticket that has not been locked.
+	ReturnTicket(context.Context, *ReturnTicketRequest) (*ReturnTicketResponse, error)
+	mustEmbedUnimplementedSequencerServer()
+}
+
+// UnimplementedSequencerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSequencerServer struct{}
+
+func (UnimplementedSequencerServer) GetTicket(context.Context, *GetTicketRequest) (*GetTicketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTicket not implemented")
+}
+func (UnimplementedSequencerServer) Lock(*LockRequest, grpc.ServerStreamingServer[LockEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (UnimplementedSequencerServer) Unlock(context.Context, *UnlockRequest) (*UnlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedSequencerServer) ReturnTicket(context.Context, *ReturnTicketRequest) (*ReturnTicketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReturnTicket not implemented")
+}
+func (UnimplementedSequencerServer) mustEmbedUnimplementedSequencerServer() {}
+func (UnimplementedSequencerServer) testEmbeddedByValue()                   {}
+
+// UnsafeSequencerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SequencerServer will
+// result in compilation errors.
+type UnsafeSequencerServer interface {
+	mustEmbedUnimplementedSequencerServer()
+}
+
+func RegisterSequencerServer(s grpc.ServiceRegistrar, srv SequencerServer) {
+	// If the following call pancis, it indicates UnimplementedSequencerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Sequencer_ServiceDesc, srv)
+}
+
+func _Sequencer_GetTicket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTicketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).GetTicket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sequencer_GetTicket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).GetTicket(ctx, req.(*GetTicketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_Lock_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LockRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequencerServer).Lock(m, &grpc.GenericServerStream[LockRequest, LockEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sequencer_LockServer = grpc.ServerStreamingServer[LockEvent]
+
+func _Sequencer_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sequencer_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).Unlock(ctx, req.(*UnlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sequencer_ReturnTicket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReturnTicketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SequencerServer).ReturnTicket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sequencer_ReturnTicket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SequencerServer).ReturnTicket(ctx, req.(*ReturnTicketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Sequencer_ServiceDesc is the grpc.ServiceDesc for Sequencer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sequencer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "advsync.ordermutex.remote.v1.Sequencer",
+	HandlerType: (*SequencerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTicket",
+			Handler:    _Sequencer_GetTicket_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _Sequencer_Unlock_Handler,
+		},
+		{
+			MethodName: "ReturnTicket",
+			Handler:    _Sequencer_ReturnTicket_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Lock",
+			Handler:       _Sequencer_Lock_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "remote.proto",
+}
@@ -0,0 +1,180 @@
+// Package remote serves order mutexes over gRPC, so processes in any language
+// can take part in the same order as Go code; see remotepb/remote.proto.
+//
+// A Server hosts named domains, each an ordermutex.Mutex. Go code in the
+// server process can use a domain's Mutex directly, and remote callers go
+// through Client.
+//
+// A remote lock is held for as long as its Lock stream stays open. If a
+// client disconnects while waiting, its ticket is given up; if it disconnects
+// while holding the lock, the lock is released. Either way, the order does
+// not stall on a lost client.
+package remote
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex/remote/remotepb"
+)
+
+// Server implements remotepb.SequencerServer.
+type Server struct {
+	remotepb.UnimplementedSequencerServer
+
+	opts []ordermutex.Option
+
+	mu      sync.Mutex
+	domains map[string]*domain
+}
+
+// domain tracks the tickets issued to remote callers.
+type domain struct {
+	m *ordermutex.Mutex
+
+	mu      sync.Mutex
+	tickets map[uint64]*entry
+}
+
+type entry struct {
+	t       ordermutex.Ticket
+	locking bool
+	release chan struct{} // set while held; closed by Unlock
+}
+
+// NewServer returns a Server whose domains are created with opts.
+func NewServer(opts ...ordermutex.Option) *Server {
+	return &Server{opts: opts, domains: make(map[string]*domain)}
+}
+
+// Mutex returns the mutex of the named domain, creating it if needed.
+func (s *Server) Mutex(name string) *ordermutex.Mutex {
+	return s.domain(name).m
+}
+
+func (s *Server) domain(name string) *domain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.domains[name]
+	if !ok {
+		d = &domain{m: ordermutex.NewMutex(s.opts...), tickets: make(map[uint64]*entry)}
+		s.domains[name] = d
+	}
+	return d
+}
+
+func (s *Server) GetTicket(ctx context.Context, req *remotepb.GetTicketRequest) (*remotepb.GetTicketResponse, error) {
+	d := s.domain(req.GetDomain())
+	t := d.m.GetTicket()
+	d.mu.Lock()
+	d.tickets[t.ID()] = &entry{t: t}
+	d.mu.Unlock()
+	return &remotepb.GetTicketResponse{Ticket: t.ID()}, nil
+}
+
+func (s *Server) Lock(req *remotepb.LockRequest, stream remotepb.Sequencer_LockServer) error {
+	d := s.domain(req.GetDomain())
+	id := req.GetTicket()
+
+	d.mu.Lock()
+	e, ok := d.tickets[id]
+	if !ok || e.locking {
+		d.mu.Unlock()
+		return status.Errorf(codes.FailedPrecondition, "ticket %d is not issued or already locking", id)
+	}
+	e.locking = true
+	d.mu.Unlock()
+
+	acquired := make(chan struct{})
+	go func() {
+		d.m.Lock(e.t)
+		close(acquired)
+	}()
+
+	ctx := stream.Context()
+	err := stream.Send(&remotepb.LockEvent{State: remotepb.LockEvent_STATE_QUEUED})
+	if err == nil {
+		select {
+		case <-acquired:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		// A parked Lock can't be canceled: give the turn up once it comes.
+		go func() {
+			<-acquired
+			d.unlock(id, e)
+		}()
+		return err
+	}
+
+	release := make(chan struct{})
+	d.mu.Lock()
+	e.release = release
+	d.mu.Unlock()
+	if err := stream.Send(&remotepb.LockEvent{State: remotepb.LockEvent_STATE_ACQUIRED}); err != nil {
+		d.unlock(id, e)
+		return err
+	}
+	select {
+	case <-release:
+	case <-ctx.Done():
+		d.unlock(id, e)
+	}
+	return nil
+}
+
+// unlock unlocks e unless Unlock already did, and reports whether it did.
+func (d *domain) unlock(id uint64, e *entry) bool {
+	d.mu.Lock()
+	if d.tickets[id] != e {
+		d.mu.Unlock()
+		return false
+	}
+	delete(d.tickets, id)
+	release := e.release
+	d.mu.Unlock()
+
+	d.m.Unlock(e.t)
+	if release != nil {
+		close(release)
+	}
+	return true
+}
+
+func (s *Server) Unlock(ctx context.Context, req *remotepb.UnlockRequest) (*remotepb.UnlockResponse, error) {
+	d := s.domain(req.GetDomain())
+	id := req.GetTicket()
+
+	d.mu.Lock()
+	e, ok := d.tickets[id]
+	held := ok && e.release != nil
+	d.mu.Unlock()
+	if !held || !d.unlock(id, e) {
+		return nil, status.Errorf(codes.FailedPrecondition, "ticket %d does not hold the lock", id)
+	}
+	return &remotepb.UnlockResponse{}, nil
+}
+
+func (s *Server) ReturnTicket(ctx context.Context, req *remotepb.ReturnTicketRequest) (*remotepb.ReturnTicketResponse, error) {
+	d := s.domain(req.GetDomain())
+	id := req.GetTicket()
+
+	d.mu.Lock()
+	e, ok := d.tickets[id]
+	if ok && e.locking {
+		d.mu.Unlock()
+		return nil, status.Errorf(codes.FailedPrecondition, "ticket %d is locking", id)
+	}
+	delete(d.tickets, id)
+	d.mu.Unlock()
+	if ok {
+		d.m.ReturnTicket(e.t)
+	}
+	return &remotepb.ReturnTicketResponse{}, nil
+}
@@ -0,0 +1,19 @@
+package ordermutex
+
+import _ "unsafe" // for go:linkname
+
+// NewFast returns a Mutex whose waiters park on runtime semaphores, as
+// sync.Mutex does, rather than on channels: wake-ups skip the channel
+// machinery and waiters carry no channel at all. It behaves like New otherwise.
+//
+// It depends on runtime internals reached through go:linkname; New remains
+// the portable default.
+func NewFast(opts ...Option) *Mutex {
+	return NewMutex(append([]Option{func(m *Mutex) { m.sema = true }}, opts...)...)
+}
+
+//go:linkname runtime_Semacquire sync.runtime_Semacquire
+func runtime_Semacquire(s *uint32)
+
+//go:linkname runtime_Semrelease sync.runtime_Semrelease
+func runtime_Semrelease(s *uint32, handoff bool, skipframes int)
@@ -0,0 +1,95 @@
+package ordermutex
+
+import "sync"
+
+// Sequencer gives operations spanning several mutexes one global order:
+// each GetTickets call takes the next position in the order and a ticket on
+// every mutex it names, atomically with respect to other calls on the same
+// Sequencer. Two operations sharing any mutexes are then ordered the same
+// way on all of them, so each operation locks only the mutexes it needs,
+// in any order, without deadlocking against the others. The zero value is
+// ready to use.
+type Sequencer struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+// Sequenced is one operation's place in a Sequencer's order.
+type Sequenced struct {
+	// Seq is the operation's position in the global order, from 0.
+	Seq   uint64
+	Pairs []Pair
+}
+
+// GetTickets takes the next position in the order and issues a ticket on
+// each of ms for it. A mutex must not appear twice.
+//
+// Other calls are held off only while the tickets are issued, never while
+// waiting for a bounded mutex to free a slot: if one of ms is full,
+// GetTickets returns the tickets it took, waits for a slot outside the
+// Sequencer, and tries again.
+func (s *Sequencer) GetTickets(ms ...OrderMutex) Sequenced {
+	for i := range ms {
+		for j := range i {
+			if ms[i] == ms[j] {
+				panic("GetTickets called with the same mutex twice")
+			}
+		}
+	}
+
+	for {
+		s.mu.Lock()
+		op, full := s.tryGetTickets(ms)
+		s.mu.Unlock()
+		if full == nil {
+			return op
+		}
+		full.waitSlot()
+	}
+}
+
+// tryGetTickets issues the tickets of the next position. If a bounded
+// mutex has no free slot, it returns the tickets taken so far and reports
+// that mutex instead. mu must be held.
+func (s *Sequencer) tryGetTickets(ms []OrderMutex) (Sequenced, *Mutex) {
+	pairs := make([]Pair, len(ms))
+	for i, m := range ms {
+		bm, ok := m.(*Mutex)
+		if !ok {
+			pairs[i] = Pair{M: m, T: m.GetTicket()}
+			continue
+		}
+		t, ok := bm.TryGetTicket()
+		if !ok {
+			for _, p := range pairs[:i] {
+				p.M.ReturnTicket(p.T)
+			}
+			return Sequenced{}, bm
+		}
+		pairs[i] = Pair{M: m, T: t}
+	}
+	op := Sequenced{Seq: s.next, Pairs: pairs}
+	s.next++
+	return op, nil
+}
+
+// Len returns the number of positions taken so far.
+func (s *Sequencer) Len() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
+
+// Lock locks every mutex of the operation, as MultiLock does, and returns
+// a func that unlocks them.
+func (op Sequenced) Lock() (unlock func()) {
+	return MultiLock(op.Pairs...)
+}
+
+// ReturnTicket gives up the operation's tickets, as ReturnTicket does on
+// each mutex.
+func (op Sequenced) ReturnTicket() {
+	for _, p := range op.Pairs {
+		p.M.ReturnTicket(p.T)
+	}
+}
@@ -0,0 +1,106 @@
+package ordermutex
+
+import "sync"
+
+// Share turns t into a ticket for n sharers, for fan-out work where several
+// goroutines make up one step of the order. Each sharer calls Lock and
+// Unlock on the returned ticket, or ReturnTicket if it drops out before
+// Lock. The turn is taken once, by the first Lock: every sharer's Lock
+// returns once it begins, so sharers run concurrently with each other, and
+// the turn passes on only when the last sharer has unlocked or returned.
+// If every sharer returns, t is burned.
+//
+// Share must be called before t is used, and the shared ticket replaces it.
+// A ReturnTicket stands in for one sharer's Lock and Unlock; once every
+// sharer has locked or returned, further ReturnTicket calls are no-ops and
+// further Locks panic. If the wrapped ticket's Lock fails, every sharer's
+// Lock returns the same error, and none of them holds the lock.
+func (m *Mutex) Share(t Ticket, n int) Ticket {
+	if n <= 0 {
+		panic("Share called with non-positive n")
+	}
+	if _, ok := t.(*sharedTicket); ok {
+		panic("Share called with a shared ticket")
+	}
+	return &sharedTicket{Ticket: t, n: n, entered: make(chan struct{})}
+}
+
+// sharedTicket counts its sharers and forwards the first Lock and the last
+// Unlock or ReturnTicket to the ticket it wraps.
+type sharedTicket struct {
+	Ticket
+
+	mu                      sync.Mutex
+	n                       int
+	locks, unlocks, returns int
+	entered                 chan struct{} // closed once the wrapped ticket's Lock returns
+	err                     error         // what it returned; set before entered is closed
+}
+
+func (s *sharedTicket) lock(m *Mutex) error {
+	s.mu.Lock()
+	if s.locks+s.returns == s.n {
+		s.mu.Unlock()
+		panic("Lock called more times than the ticket is shared")
+	}
+	s.locks++
+	first := s.locks == 1
+	s.mu.Unlock()
+
+	if first {
+		err := m.LockErr(s.Ticket)
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.entered)
+		return err
+	}
+	<-s.entered
+	return s.err
+}
+
+func (s *sharedTicket) unlock(m *Mutex) {
+	s.mu.Lock()
+	if s.unlocks == s.locks || s.err != nil {
+		s.mu.Unlock()
+		panic("Unlock called for a ticket that does not hold the lock")
+	}
+	s.unlocks++
+	last := s.unlocks+s.returns == s.n
+	s.mu.Unlock()
+
+	if last {
+		m.Unlock(s.Ticket)
+	}
+}
+
+func (s *sharedTicket) giveUp(m *Mutex) {
+	s.mu.Lock()
+	if s.locks+s.returns == s.n {
+		s.mu.Unlock()
+		return
+	}
+	s.returns++
+	last := s.unlocks+s.returns == s.n
+	locked := s.locks > 0
+	failed := s.err != nil
+	s.mu.Unlock()
+
+	switch {
+	case !last, failed:
+	case locked:
+		m.Unlock(s.Ticket)
+	default:
+		m.ReturnTicket(s.Ticket)
+	}
+}
+
+// composite reports whether t is a shared, group or sub-ticket, which stand
+// for a wrapped ticket and cannot be moved in the order themselves.
+func composite(t Ticket) bool {
+	switch t.(type) {
+	case *sharedTicket, *groupMember, *subTicket:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package ordermutex
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSkipped is the panic value of Lock, and the error of LockErr, for a
+// ticket burned by the skip-absent policy.
+var ErrSkipped = errors.New("ordermutex: ticket skipped after missing its turn")
+
+// WithSkipAbsent burns the ticket whose turn it is if it has not called Lock
+// within d while later tickets are waiting, so one slow producer delays the
+// queue instead of halting it. Tickets that have not been issued are never skipped.
+//
+// A late Lock on a skipped ticket panics with ErrSkipped rather than blocking
+// forever; ReturnTicket on it stays a no-op. Skipped tickets are forgotten
+// once the turn moves past them, so a Lock on any ticket whose turn is over
+// fails with ErrSkipped on such a mutex, even in strict mode.
+func WithSkipAbsent(d time.Duration) Option {
+	return func(m *Mutex) {
+		m.skipAfter = d
+		m.skipped = make(map[uint64]struct{})
+	}
+}
+
+// armSkip starts the skip timer for turn id unless it is already running for it;
+// mu must be held.
+func (m *Mutex) armSkip(id uint64) {
+	if m.skipTimer != nil {
+		if m.skipFor == id {
+			return
+		}
+		m.skipTimer.Stop()
+	}
+	m.skipFor = id
+	m.skipTimer = time.AfterFunc(m.skipAfter, func() { m.skipExpired(id) })
+}
+
+// takeSkipped reports whether ticket id lost its turn to the skip-absent
+// policy, and forgets it; mu must be held.
+func (m *Mutex) takeSkipped(id uint64) bool {
+	if m.skipAfter == 0 {
+		return false
+	}
+	if _, ok := m.skipped[id]; ok {
+		delete(m.skipped, id)
+		return true
+	}
+	return id < m.cur // passed, and perhaps pruned
+}
+
+// pruneSkipped forgets the skipped tickets the turn has moved past, whose
+// owners may never come back for them; mu must be held.
+func (m *Mutex) pruneSkipped() {
+	for id := range m.skipped {
+		if id < m.cur {
+			delete(m.skipped, id)
+		}
+	}
+}
+
+func (m *Mutex) skipExpired(id uint64) {
+	m.lock()
+	defer m.unlock()
+
+	if m.skipFor != id {
+		return // superseded by a newer timer
+	}
+	m.skipTimer = nil
+	if m.held || m.turn() != id {
+		return
+	}
+	if id >= m.next.Load() {
+		return // not issued yet: nobody is late
+	}
+
+	m.skipped[id] = struct{}{}
+	if m.burn(id) {
+		m.release(id)
+	}
+}
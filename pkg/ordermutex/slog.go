@@ -0,0 +1,31 @@
+package ordermutex
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger logs every ticket state transition to l at debug level,
+// with the ticket ID and, where it applies, the wait or hold duration.
+func WithLogger(l *slog.Logger) Option {
+	log := func(msg string, durKey string) func(Event) {
+		return func(e Event) {
+			ctx := context.Background()
+			if !l.Enabled(ctx, slog.LevelDebug) {
+				return
+			}
+			attrs := []slog.Attr{slog.Uint64("ticket", e.ID)}
+			if durKey != "" {
+				attrs = append(attrs, slog.Duration(durKey, e.Elapsed))
+			}
+			l.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+		}
+	}
+	return WithHooks(Hooks{
+		OnIssued:       log("ticket issued", ""),
+		OnLockWait:     log("ticket waiting", ""),
+		OnLockAcquired: log("ticket locked", "wait"),
+		OnUnlock:       log("ticket unlocked", "held"),
+		OnBurned:       log("ticket burned", ""),
+	})
+}
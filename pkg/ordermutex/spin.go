@@ -0,0 +1,34 @@
+package ordermutex
+
+import "runtime"
+
+// spinBudget bounds how many times a spinning Lock polls before parking.
+const spinBudget = 64
+
+// WithSpin makes Lock spin briefly instead of parking at once when its ticket
+// is at most distance tickets behind the turn, for critical sections short
+// enough that a park/wake round trip costs more than the wait.
+//
+// Spinning polls the fast path, so it has no effect on mutexes whose options
+// disable it, and stops as soon as another Lock has parked.
+func WithSpin(distance uint64) Option {
+	return func(m *Mutex) {
+		m.spin = true
+		m.spinDist = distance
+	}
+}
+
+// spinLock polls for the turn of ticket id and reports whether it got the lock.
+func (m *Mutex) spinLock(id uint64) bool {
+	for range spinBudget {
+		s := m.state.Load()
+		if s&stateSlow != 0 || id < s>>stateShift || id-s>>stateShift > m.spinDist {
+			return false
+		}
+		if m.tryLockFast(id) {
+			return true
+		}
+		runtime.Gosched()
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package ordermutex
+
+import "sync"
+
+// Split refines t into k sub-tickets that take turns, in order, within t's
+// turn: sub-ticket i locks only once sub-tickets 0 to i-1 have unlocked or
+// been returned, and no ticket after t runs until all k are done. The first
+// sub-ticket to lock takes t's turn, and the last one to finish passes it on;
+// if all are returned, t is burned.
+//
+// If t's turn is lost, to WithSkipAbsent or CancelLock, every sub-ticket's
+// Lock fails as t's would.
+//
+// Sub-tickets are used like tickets, and may themselves be split or shared.
+// Their ID is t's. Split must be called before t is used, and the
+// sub-tickets replace it.
+func (m *Mutex) Split(t Ticket, k int) []Ticket {
+	if k <= 0 {
+		panic("Split called with non-positive k")
+	}
+	s := &split{Ticket: t, k: k, inner: NewMutex()}
+	subs := make([]Ticket, k)
+	for i := range subs {
+		subs[i] = &subTicket{split: s, inner: s.inner.GetTicket()}
+	}
+	return subs
+}
+
+// split orders its sub-tickets on an inner mutex and holds the ticket it
+// wraps from the first sub-ticket's Lock to the last one's Unlock.
+type split struct {
+	Ticket
+	inner *Mutex
+
+	mu   sync.Mutex
+	k    int
+	done int   // sub-tickets unlocked or returned
+	held bool  // the wrapped ticket was locked
+	err  error // why the wrapped ticket could not be locked
+}
+
+type subTicket struct {
+	*split
+	inner Ticket
+	state memberState // guarded by split.mu
+}
+
+func (t *subTicket) lock(m *Mutex) error {
+	s := t.split
+	s.mu.Lock()
+	if t.state != memberIdle {
+		s.mu.Unlock()
+		panic("Lock called twice for a sub-ticket")
+	}
+	t.state = memberLocking
+	s.mu.Unlock()
+
+	s.inner.Lock(t.inner)
+	s.mu.Lock()
+	err := s.err
+	first := !s.held && err == nil
+	s.mu.Unlock()
+	if first {
+		err = m.LockErr(s.Ticket)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		// t's turn is lost, and with it every sub-ticket's.
+		s.err = err
+		t.state = memberReturned
+		s.inner.Unlock(t.inner)
+		return err
+	}
+	s.held = true
+	return nil
+}
+
+func (t *subTicket) unlock(m *Mutex) {
+	s := t.split
+	s.mu.Lock()
+	if t.state != memberLocking {
+		s.mu.Unlock()
+		panic("Unlock called for a ticket that does not hold the lock")
+	}
+	t.state = memberUnlocked
+	s.done++
+	last := s.done == s.k
+	s.mu.Unlock()
+
+	if last {
+		m.Unlock(s.Ticket)
+	}
+	s.inner.Unlock(t.inner)
+}
+
+func (t *subTicket) giveUp(m *Mutex) {
+	s := t.split
+	s.mu.Lock()
+	if t.state != memberIdle {
+		// Returning after Unlock is a no-op, as for plain tickets.
+		s.mu.Unlock()
+		return
+	}
+	t.state = memberReturned
+	s.done++
+	last := s.done == s.k
+	held := s.held
+	failed := s.err != nil
+	s.mu.Unlock()
+
+	s.inner.ReturnTicket(t.inner)
+	switch {
+	case !last, failed:
+	case held:
+		m.Unlock(s.Ticket)
+	default:
+		m.ReturnTicket(s.Ticket)
+	}
+}
@@ -0,0 +1,101 @@
+package ordermutex
+
+import (
+	"fmt"
+	"time"
+)
+
+// StallReport describes a queue that made no progress for a whole detector period.
+type StallReport struct {
+	// Turn is the ticket every waiter is blocked behind.
+	Turn uint64
+	// Issued reports whether Turn was handed out by GetTicket (or Adopt).
+	Issued bool
+	// Locked reports whether Turn entered Lock and has not unlocked yet.
+	Locked bool
+	// Since is how long Turn has been current; HeldFor how long it has held the lock.
+	Since   time.Duration
+	HeldFor time.Duration
+	// Waiters lists the tickets parked in Lock, in order.
+	Waiters []uint64
+	// BurnedAhead counts returned tickets waiting to be skipped once Turn is done.
+	BurnedAhead int
+}
+
+func (r *StallReport) Error() string {
+	var state string
+	switch {
+	case r.Locked:
+		state = fmt.Sprintf("locked for %v and never unlocked", r.HeldFor)
+	case r.Issued:
+		state = "issued but never locked or returned"
+	default:
+		state = "never issued"
+	}
+	return fmt.Sprintf("ordermutex: stalled for %v on ticket %d (%s); %d waiters blocked: %v",
+		r.Since, r.Turn, state, len(r.Waiters), r.Waiters)
+}
+
+// WithStallDetector checks every period, while tickets are waiting, whether the
+// turn has moved; if not, it calls fn with a report. fn runs on its own goroutine
+// and is called again every period for as long as the stall lasts.
+func WithStallDetector(period time.Duration, fn func(*StallReport)) Option {
+	return func(m *Mutex) {
+		m.stallPeriod = period
+		m.onStall = fn
+	}
+}
+
+// noteTurn records turn changes for the stall detector; mu must be held.
+func (m *Mutex) noteTurn() {
+	if t := m.turn(); t != m.stallTurn || m.turns == 0 {
+		m.stallTurn = t
+		m.turns++
+		m.turnSince = time.Now()
+	}
+}
+
+// armStall starts the detector when a ticket parks; mu must be held.
+func (m *Mutex) armStall() {
+	if m.stallTimer == nil {
+		m.stallSeen = m.turns
+		m.stallTimer = time.AfterFunc(m.stallPeriod, m.checkStall)
+	}
+}
+
+func (m *Mutex) checkStall() {
+	m.lock()
+	if m.waiters.len() == 0 {
+		m.stallTimer = nil
+		m.unlock()
+		return
+	}
+	var report *StallReport
+	if m.turns == m.stallSeen {
+		report = m.stallReport()
+	}
+	m.stallSeen = m.turns
+	m.stallTimer.Reset(m.stallPeriod)
+	m.unlock()
+
+	if report != nil {
+		m.onStall(report)
+	}
+}
+
+// stallReport snapshots the queue; mu must be held.
+func (m *Mutex) stallReport() *StallReport {
+	now := time.Now()
+	r := &StallReport{
+		Turn:        m.turn(),
+		Issued:      m.turn() < m.next.Load(),
+		Locked:      m.held,
+		Since:       now.Sub(m.turnSince),
+		BurnedAhead: m.burned.len(),
+	}
+	if m.held {
+		r.HeldFor = now.Sub(m.heldAt)
+	}
+	r.Waiters = m.waiters.ids()
+	return r
+}
@@ -0,0 +1,146 @@
+package ordermutex
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats summarizes how fairly a Mutex has served its tickets.
+type Stats struct {
+	Issued   uint64
+	Acquired uint64
+	Burned   uint64
+	// BurnRatio is Burned over Issued.
+	BurnRatio float64
+
+	// Queue wait runs from GetTicket to lock acquisition; tickets obtained
+	// through Adopt are not counted.
+	MaxQueueWait  time.Duration
+	MeanQueueWait time.Duration
+	// MaxLockWait is the longest any ticket spent parked in Lock.
+	MaxLockWait time.Duration
+	// OldestWaiter is how long the longest-parked current waiter has been in Lock.
+	OldestWaiter time.Duration
+	// RecentHold is a moving average of hold times, weighted to the latest
+	// ones; Position estimates waits from it.
+	RecentHold time.Duration
+}
+
+type stats struct {
+	mu sync.Mutex
+
+	issued, acquired, burned uint64
+	queued                   uint64 // acquisitions with a known issue time
+	queueWait, maxQueueWait  time.Duration
+	maxLockWait              time.Duration
+	hold                     time.Duration // moving average
+
+	issuedAt  map[uint64]time.Time
+	waitingAt map[uint64]time.Time
+}
+
+// WithStats makes the Mutex keep the statistics returned by Stats.
+// It timestamps every transition, and disables the lock-free fast path.
+func WithStats() Option {
+	return func(m *Mutex) {
+		s := &stats{
+			issuedAt:  make(map[uint64]time.Time),
+			waitingAt: make(map[uint64]time.Time),
+		}
+		m.stats = s
+		WithHooks(Hooks{
+			OnIssued:       s.onIssued,
+			OnLockWait:     s.onLockWait,
+			OnLockAcquired: s.onLockAcquired,
+			OnUnlock:       s.onUnlock,
+			OnBurned:       s.onBurned,
+		})(m)
+	}
+}
+
+// Stats returns the statistics collected so far; they are all zero unless
+// the Mutex was created with WithStats.
+func (m *Mutex) Stats() Stats {
+	s := m.stats
+	if s == nil {
+		return Stats{}
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{
+		Issued:       s.issued,
+		Acquired:     s.acquired,
+		Burned:       s.burned,
+		MaxQueueWait: s.maxQueueWait,
+		MaxLockWait:  s.maxLockWait,
+		RecentHold:   s.hold,
+	}
+	if s.issued > 0 {
+		st.BurnRatio = float64(s.burned) / float64(s.issued)
+	}
+	if s.queued > 0 {
+		st.MeanQueueWait = s.queueWait / time.Duration(s.queued)
+	}
+	for _, at := range s.waitingAt {
+		st.OldestWaiter = max(st.OldestWaiter, now.Sub(at))
+	}
+	return st
+}
+
+func (s *stats) onIssued(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.issued++
+	s.issuedAt[e.ID] = e.Time
+}
+
+func (s *stats) onLockWait(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitingAt[e.ID] = e.Time
+}
+
+func (s *stats) onLockAcquired(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acquired++
+	delete(s.waitingAt, e.ID)
+	s.maxLockWait = max(s.maxLockWait, e.Elapsed)
+	if at, ok := s.issuedAt[e.ID]; ok {
+		delete(s.issuedAt, e.ID)
+		w := e.Time.Sub(at)
+		s.queued++
+		s.queueWait += w
+		s.maxQueueWait = max(s.maxQueueWait, w)
+	}
+}
+
+// holdWeight is the weight of each new hold time in the moving average, 1/holdWeight.
+const holdWeight = 8
+
+func (s *stats) onUnlock(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hold == 0 {
+		s.hold = e.Elapsed
+	} else {
+		s.hold += (e.Elapsed - s.hold) / holdWeight
+	}
+}
+
+func (s *stats) recentHold() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hold
+}
+
+func (s *stats) onBurned(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.burned++
+	delete(s.issuedAt, e.ID)
+	delete(s.waitingAt, e.ID)
+}
@@ -0,0 +1,102 @@
+package ordermutex
+
+import "fmt"
+
+// MisuseError reports a call that breaks the ticket protocol,
+// detected by a Mutex created with NewStrict.
+type MisuseError struct {
+	Op     string
+	Ticket uint64
+	Reason string
+}
+
+func (e *MisuseError) Error() string {
+	return fmt.Sprintf("ordermutex: %s(%d): %s", e.Op, e.Ticket, e.Reason)
+}
+
+// NewStrict returns a Mutex that checks every call against the ticket protocol
+// instead of leaving misuse undefined: double Lock, Lock after ReturnTicket,
+// ReturnTicket between Lock and Unlock, Unlock by a non-holder and tickets
+// issued by another mutex are reported as *MisuseError.
+//
+// By default a misuse panics; see WithMisuseHandler.
+func NewStrict(opts ...Option) *Mutex {
+	return NewMutex(append([]Option{func(m *Mutex) { m.strict = true }}, opts...)...)
+}
+
+// WithMisuseHandler makes a strict Mutex report misuse to fn instead of panicking.
+// The offending call returns without effect once fn returns.
+func WithMisuseHandler(fn func(error)) Option {
+	return func(m *Mutex) {
+		m.onMisuse = fn
+	}
+}
+
+// strictTicket remembers its issuer so foreign tickets can be detected.
+type strictTicket struct {
+	ticket
+	owner *Mutex
+}
+
+func (m *Mutex) misuse(err error) {
+	if m.onMisuse != nil {
+		m.onMisuse(err)
+		return
+	}
+	panic(err)
+}
+
+// The checks below run with mu held.
+
+func (m *Mutex) checkOwner(op string, t Ticket) error {
+	if st, ok := t.(strictTicket); !ok || st.owner != m {
+		return &MisuseError{Op: op, Ticket: t.ID(), Reason: "ticket was issued by a different mutex"}
+	}
+	return nil
+}
+
+func (m *Mutex) checkLock(t Ticket) error {
+	if err := m.checkOwner("Lock", t); err != nil {
+		return err
+	}
+	id := t.ID()
+	var reason string
+	if _, waiting := m.waiters.get(id); waiting {
+		reason = "ticket is already waiting in Lock"
+	} else if id == m.turn() && m.held {
+		reason = "ticket already holds the lock"
+	} else if id < m.cur || m.burned.has(id) {
+		reason = "ticket was already used or returned"
+	}
+	if reason != "" {
+		return &MisuseError{Op: "Lock", Ticket: id, Reason: reason}
+	}
+	return nil
+}
+
+func (m *Mutex) checkUnlock(t Ticket) error {
+	if err := m.checkOwner("Unlock", t); err != nil {
+		return err
+	}
+	if id := t.ID(); id != m.turn() || !m.held {
+		return &MisuseError{Op: "Unlock", Ticket: id, Reason: "ticket does not hold the lock"}
+	}
+	return nil
+}
+
+func (m *Mutex) checkReturn(op string, t Ticket) error {
+	if err := m.checkOwner(op, t); err != nil {
+		return err
+	}
+	id := t.ID()
+	var reason string
+	if _, waiting := m.waiters.get(id); waiting {
+		reason = "ticket is waiting in Lock"
+	} else if id == m.turn() && m.held {
+		reason = "ticket holds the lock; Unlock it first"
+	}
+	if reason != "" {
+		return &MisuseError{Op: op, Ticket: id, Reason: reason}
+	}
+	return nil
+}
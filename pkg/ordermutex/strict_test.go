@@ -0,0 +1,79 @@
+package ordermutex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictMisuse(t *testing.T) {
+	var errs []error
+	m := NewStrict(WithMisuseHandler(func(err error) { errs = append(errs, err) }))
+	other := NewStrict()
+
+	expect := func(op, reason string) {
+		t.Helper()
+		if len(errs) != 1 {
+			t.Fatalf("got %d errors %v, want one %s misuse", len(errs), errs, op)
+		}
+		var me *MisuseError
+		if !errors.As(errs[0], &me) || me.Op != op || !strings.Contains(me.Reason, reason) {
+			t.Fatalf("got %v, want %s: %s", errs[0], op, reason)
+		}
+		errs = nil
+	}
+
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+
+	m.Lock(t0)
+	var me *MisuseError
+	if err := m.LockErr(t0); !errors.As(err, &me) {
+		t.Fatalf("LockErr = %v, want the *MisuseError", err)
+	}
+	expect("Lock", "already holds")
+
+	m.ReturnTicket(t0)
+	expect("ReturnTicket", "holds the lock")
+
+	m.Unlock(t1)
+	expect("Unlock", "does not hold")
+
+	m.Lock(other.GetTicket())
+	expect("Lock", "different mutex")
+
+	m.Lock(ticket(t1.ID()))
+	expect("Lock", "different mutex")
+
+	m.Unlock(t0)
+	m.Lock(t0)
+	expect("Lock", "already used")
+
+	m.ReturnTicket(t1)
+	m.Lock(t1)
+	expect("Lock", "already used or returned")
+
+	t2 := m.GetTicket()
+	t3 := m.GetTicket()
+	go m.Lock(t3)
+	time.Sleep(20 * time.Millisecond)
+	m.ReturnTicket(t3)
+	expect("ReturnTicket", "waiting in Lock")
+
+	m.Lock(t2)
+	m.Unlock(t2)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestStrictPanics(t *testing.T) {
+	m := NewStrict()
+	defer func() {
+		if _, ok := recover().(*MisuseError); !ok {
+			t.Fatal("expected a *MisuseError panic")
+		}
+	}()
+	m.Unlock(m.GetTicket())
+}
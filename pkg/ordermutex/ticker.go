@@ -1,9 +1,49 @@
 package ordermutex
 
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrInvalidTicket is returned when ticket data can't be decoded.
+	ErrInvalidTicket = errors.New("ordermutex: invalid ticket data")
+	// ErrTicketPassed is returned by Adopt when the ticket's turn is already over.
+	ErrTicketPassed = errors.New("ordermutex: ticket already passed")
+)
+
+// Ticket is a position in the mutex queue.
+// Tickets issued by a Mutex also implement encoding.BinaryMarshaler, and can
+// be rebound with Mutex.Adopt so the turn can be taken by another process
+// sharing the same order.
 type Ticket interface {
 	ID() uint64
 }
 
+var _ encoding.BinaryMarshaler = ticket(0)
+
+// Wire format: version byte followed by the big-endian ticket ID.
+const (
+	ticketVersion = 1
+	ticketSize    = 1 + 8
+)
+
 type ticket uint64
 
 func (t ticket) ID() uint64 { return uint64(t) }
+
+func (t ticket) MarshalBinary() ([]byte, error) {
+	b := make([]byte, ticketSize)
+	b[0] = ticketVersion
+	binary.BigEndian.PutUint64(b[1:], uint64(t))
+	return b, nil
+}
+
+func (t *ticket) UnmarshalBinary(data []byte) error {
+	if len(data) != ticketSize || data[0] != ticketVersion {
+		return ErrInvalidTicket
+	}
+	*t = ticket(binary.BigEndian.Uint64(data[1:]))
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package tracing wraps an OrderMutex with OpenTelemetry spans for the two
+// phases of an ordered section: waiting in the queue and holding the lock.
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+const instrumentationName = "github.com/sawdustofmind/adv-sync/pkg/ordermutex/tracing"
+
+// Mutex traces an OrderMutex. A "queue wait" span runs from GetTicket (or Lock,
+// for tickets obtained elsewhere) until the lock is acquired, and a
+// "critical section" span from acquisition until Unlock.
+// Both are children of the context passed to GetTicket.
+type Mutex struct {
+	m      ordermutex.OrderMutex
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+
+	mu      sync.Mutex
+	tickets map[uint64]*spans
+}
+
+type spans struct {
+	parent context.Context
+	wait   trace.Span
+	hold   trace.Span
+}
+
+// New wraps m; name identifies the mutex in span attributes.
+func New(m ordermutex.OrderMutex, tp trace.TracerProvider, name string) *Mutex {
+	return &Mutex{
+		m:       m,
+		tracer:  tp.Tracer(instrumentationName),
+		attrs:   []attribute.KeyValue{attribute.String("ordermutex.name", name)},
+		tickets: make(map[uint64]*spans),
+	}
+}
+
+func (m *Mutex) GetTicket(ctx context.Context) ordermutex.Ticket {
+	_, wait := m.tracer.Start(ctx, "queue wait", trace.WithAttributes(m.attrs...))
+	t := m.m.GetTicket()
+	wait.SetAttributes(attribute.Int64("ordermutex.ticket", int64(t.ID())))
+
+	m.mu.Lock()
+	m.tickets[t.ID()] = &spans{parent: ctx, wait: wait}
+	m.mu.Unlock()
+	return t
+}
+
+// Lock acquires t and returns a context carrying the critical section span,
+// for work done while holding the lock. ctx is only used for tickets that
+// were not issued by this wrapper.
+func (m *Mutex) Lock(ctx context.Context, t ordermutex.Ticket) context.Context {
+	m.mu.Lock()
+	s, ok := m.tickets[t.ID()]
+	if !ok {
+		s = &spans{parent: ctx}
+		_, s.wait = m.tracer.Start(ctx, "queue wait", trace.WithAttributes(m.ticketAttrs(t)...))
+		m.tickets[t.ID()] = s
+	}
+	m.mu.Unlock()
+
+	m.m.Lock(t)
+	s.wait.End()
+
+	holdCtx, hold := m.tracer.Start(s.parent, "critical section", trace.WithAttributes(m.ticketAttrs(t)...))
+	m.mu.Lock()
+	s.hold = hold
+	m.mu.Unlock()
+	return holdCtx
+}
+
+func (m *Mutex) Unlock(t ordermutex.Ticket) {
+	m.mu.Lock()
+	s := m.tickets[t.ID()]
+	delete(m.tickets, t.ID())
+	m.mu.Unlock()
+
+	m.m.Unlock(t)
+	if s != nil && s.hold != nil {
+		s.hold.End()
+	}
+}
+
+// ReturnTicket ends a pending wait span with an error status,
+// since the ticket gave up its place without locking.
+func (m *Mutex) ReturnTicket(t ordermutex.Ticket) {
+	m.mu.Lock()
+	s := m.tickets[t.ID()]
+	if s != nil && s.hold == nil {
+		delete(m.tickets, t.ID())
+	} else {
+		s = nil
+	}
+	m.mu.Unlock()
+
+	m.m.ReturnTicket(t)
+	if s != nil {
+		s.wait.SetStatus(codes.Error, "ticket returned")
+		s.wait.End()
+	}
+}
+
+func (m *Mutex) ticketAttrs(t ordermutex.Ticket) []attribute.KeyValue {
+	return append(m.attrs[:len(m.attrs):len(m.attrs)], attribute.Int64("ordermutex.ticket", int64(t.ID())))
+}
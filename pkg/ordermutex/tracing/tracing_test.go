@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+func TestSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	m := New(ordermutex.NewMutex(), tp, "ingest")
+
+	ctx, root := tp.Tracer("test").Start(context.Background(), "request")
+
+	t0 := m.GetTicket(ctx)
+	t1 := m.GetTicket(ctx)
+	m.ReturnTicket(t1)
+
+	lockCtx := m.Lock(ctx, t0)
+	_, work := tp.Tracer("test").Start(lockCtx, "work")
+	work.End()
+	m.Unlock(t0)
+	m.ReturnTicket(t0)
+	root.End()
+
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	if len(byName["queue wait"]) != 2 || len(byName["critical section"]) != 1 {
+		t.Fatalf("unexpected spans: %v", byName)
+	}
+	rootID := root.SpanContext().SpanID()
+	for _, s := range append(byName["queue wait"], byName["critical section"]...) {
+		if s.Parent().SpanID() != rootID {
+			t.Fatalf("%s is not a child of the caller's span", s.Name())
+		}
+	}
+	hold := byName["critical section"][0]
+	if byName["work"][0].Parent().SpanID() != hold.SpanContext().SpanID() {
+		t.Fatal("work done under the lock is not nested in the critical section")
+	}
+}
@@ -0,0 +1,96 @@
+package ordermutex
+
+import (
+	"time"
+	"unsafe"
+)
+
+// WithExecutor makes WhenMyTurn run its callbacks through exec instead of
+// on a new goroutine each. exec is called with the mutex's internal lock
+// held: it must hand f off, for example to an event loop or worker pool,
+// and not run it itself.
+func WithExecutor(exec func(f func())) Option {
+	return func(m *Mutex) {
+		m.exec = exec
+	}
+}
+
+// WhenMyTurn locks t without blocking the caller: fn runs once t's turn
+// comes, holding the lock, and t is unlocked when fn returns. Until then
+// nothing is parked, so a server can have any number of tickets pending
+// without a goroutine each. fn runs on a new goroutine, or through the
+// executor set by WithExecutor.
+//
+// A panic in fn unlocks t on its way out, but is not recovered: on a new
+// goroutine it crashes the program like any other. Only an executor that
+// recovers panics keeps the program, and the mutex, running.
+//
+// fn never runs if t is returned, skipped or canceled with CancelLock first.
+// Tickets made by Share, GetGroupTicket and Split wait on a goroutine.
+func (m *Mutex) WhenMyTurn(t Ticket, fn func()) {
+	switch t.(type) {
+	case *sharedTicket, *groupMember, *subTicket:
+		go func() {
+			m.Lock(t)
+			defer m.Unlock(t)
+			fn()
+		}()
+		return
+	}
+	id := t.ID()
+	start := m.now()
+	run := func() {
+		raceAcquire(unsafe.Pointer(m))
+		if len(m.hooks) > 0 {
+			now := time.Now()
+			m.emit(hookLockAcquired, id, now, now.Sub(start))
+		}
+		m.debugRecord(id, debugLocked)
+		if m.watchdog != nil {
+			m.watch(id)
+		}
+		defer m.Unlock(t)
+		fn()
+	}
+	if m.tryLockFast(id) {
+		m.execute(run)
+		return
+	}
+
+	m.lock()
+	defer m.unlock()
+	if m.takeSkipped(id) {
+		return
+	}
+	if m.strict {
+		if err := m.checkLock(t); err != nil {
+			m.misuse(err)
+			return
+		}
+	}
+	if id == m.turn() {
+		m.held = true
+		m.heldAt = start
+		m.execute(run)
+		return
+	}
+	if _, ok := m.waiters.get(id); ok {
+		panic("WhenMyTurn called for a ticket that is already waiting")
+	}
+	m.waiters.put(m.cur, id, &waiter{run: run})
+	if m.skipAfter > 0 && !m.held {
+		m.armSkip(m.turn())
+	}
+	if m.onStall != nil {
+		m.armStall()
+	}
+	m.emit(hookLockWait, id, start, 0)
+}
+
+func (m *Mutex) execute(f func()) {
+	if m.exec != nil {
+		m.exec(f)
+		return
+	}
+	go f()
+}
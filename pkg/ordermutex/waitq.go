@@ -0,0 +1,152 @@
+package ordermutex
+
+import (
+	"sort"
+	"sync"
+)
+
+// waiter parks one Lock call, on a channel or, for NewFast, a runtime semaphore.
+// Waiters are pooled: the woken goroutine puts its waiter back. WhenMyTurn
+// waiters park no goroutine and are not pooled.
+type waiter struct {
+	ch       chan struct{} // room for the one signal it carries, so waking never blocks
+	sema     uint32
+	canceled bool   // set by CancelLock before the wake-up
+	run      func() // set for WhenMyTurn, which runs it instead of parking
+}
+
+var waiterPool = sync.Pool{
+	New: func() any { return new(waiter) },
+}
+
+func (m *Mutex) newWaiter() *waiter {
+	w := waiterPool.Get().(*waiter)
+	if !m.sema && w.ch == nil {
+		w.ch = make(chan struct{}, 1)
+	}
+	return w
+}
+
+func (m *Mutex) wait(w *waiter) {
+	if m.sema {
+		runtime_Semacquire(&w.sema)
+	} else {
+		<-w.ch
+	}
+}
+
+func (m *Mutex) wake(w *waiter) {
+	if w.run != nil {
+		m.execute(w.run)
+		return
+	}
+	if m.sema {
+		runtime_Semrelease(&w.sema, false, 0)
+	} else {
+		w.ch <- struct{}{}
+	}
+}
+
+// waitRingMax bounds the ring; waiters further ahead of cur than that
+// (e.g. adopted tickets far in the future) go to an overflow map.
+const waitRingMax = 1 << 16
+
+// waitQueue maps parked tickets to their waiters.
+// Tickets are dense and close to cur, so they live in a power-of-two ring
+// indexed by offset from base, which trails cur; no slot below cur is in use.
+type waitQueue struct {
+	buf  []*waiter
+	head int    // slot of base
+	base uint64 // ticket stored at buf[head]
+	n    int
+	far  map[uint64]*waiter
+}
+
+func (q *waitQueue) len() int { return q.n }
+
+func (q *waitQueue) slot(id uint64) (int, bool) {
+	if id < q.base || id-q.base >= uint64(len(q.buf)) {
+		return 0, false
+	}
+	return (q.head + int(id-q.base)) & (len(q.buf) - 1), true
+}
+
+func (q *waitQueue) get(id uint64) (*waiter, bool) {
+	if i, ok := q.slot(id); ok && q.buf[i] != nil {
+		return q.buf[i], true
+	}
+	w, ok := q.far[id]
+	return w, ok
+}
+
+// take removes and returns the channel of id, if parked.
+func (q *waitQueue) take(id uint64) (*waiter, bool) {
+	if i, ok := q.slot(id); ok && q.buf[i] != nil {
+		w := q.buf[i]
+		q.buf[i] = nil
+		q.n--
+		return w, true
+	}
+	w, ok := q.far[id]
+	if ok {
+		delete(q.far, id)
+		q.n--
+	}
+	return w, ok
+}
+
+// put parks id, which must not be parked yet; cur is the current sequence position.
+func (q *waitQueue) put(cur, id uint64, w *waiter) {
+	q.slide(cur)
+	off := id - q.base
+	if off >= uint64(len(q.buf)) {
+		if off >= waitRingMax {
+			if q.far == nil {
+				q.far = make(map[uint64]*waiter)
+			}
+			q.far[id] = w
+			q.n++
+			return
+		}
+		q.grow(int(off) + 1)
+	}
+	q.buf[(q.head+int(off))&(len(q.buf)-1)] = w
+	q.n++
+}
+
+// slide moves base up to cur; the slots it passes are empty.
+func (q *waitQueue) slide(cur uint64) {
+	if cur-q.base >= uint64(len(q.buf)) {
+		q.head, q.base = 0, cur
+		return
+	}
+	q.head = (q.head + int(cur-q.base)) & (len(q.buf) - 1)
+	q.base = cur
+}
+
+func (q *waitQueue) grow(min int) {
+	size := max(len(q.buf), 8)
+	for size < min {
+		size <<= 1
+	}
+	buf := make([]*waiter, size)
+	for i := range q.buf {
+		buf[i] = q.buf[(q.head+i)&(len(q.buf)-1)]
+	}
+	q.buf, q.head = buf, 0
+}
+
+// ids returns the parked tickets in ascending order.
+func (q *waitQueue) ids() []uint64 {
+	ids := make([]uint64, 0, q.n)
+	for i := range q.buf {
+		if q.buf[(q.head+i)&(len(q.buf)-1)] != nil {
+			ids = append(ids, q.base+uint64(i))
+		}
+	}
+	for id := range q.far {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
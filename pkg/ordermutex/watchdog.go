@@ -0,0 +1,59 @@
+package ordermutex
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Watchdog reports tickets that hold the lock longer than Threshold.
+type Watchdog struct {
+	Threshold time.Duration
+	// Stack captures the holder's stack at Lock time and passes it to OnLongHold.
+	// It costs a stack walk per acquisition.
+	Stack bool
+	// OnLongHold is called once per offending hold, from its own goroutine,
+	// if the ticket still held the lock when the threshold passed. The hold
+	// may end while OnLongHold runs.
+	OnLongHold func(id uint64, held time.Duration, stack []byte)
+}
+
+// WithWatchdog installs a hold-time watchdog.
+func WithWatchdog(w Watchdog) Option {
+	return func(m *Mutex) {
+		m.watchdog = &w
+	}
+}
+
+// watch arms the watchdog for the ticket that just acquired the lock.
+// It must run on the acquiring goroutine before Lock returns.
+func (m *Mutex) watch(id uint64) {
+	w := m.watchdog
+	var stack []byte
+	if w.Stack {
+		stack = debug.Stack()
+	}
+	start := time.Now()
+
+	m.lock()
+	gen := m.watchGen
+	m.watchTimer = time.AfterFunc(w.Threshold, func() {
+		// Stop can't recall a timer that already fired: check that the hold
+		// it was armed for is still the current one.
+		m.lock()
+		held := m.watchGen == gen
+		m.unlock()
+		if held {
+			w.OnLongHold(id, time.Since(start), stack)
+		}
+	})
+	m.unlock()
+}
+
+// unwatch disarms the watchdog on Unlock; mu must be held.
+func (m *Mutex) unwatch() {
+	m.watchGen++
+	if m.watchTimer != nil {
+		m.watchTimer.Stop()
+		m.watchTimer = nil
+	}
+}
@@ -0,0 +1,54 @@
+// Package pipeline provides order-preserving building blocks for concurrent
+// channel pipelines, built on ordermutex tickets.
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Stamped is a value carrying the ordermutex ticket that fixes its place in
+// the output.
+type Stamped[T any] struct {
+	Ticket ordermutex.Ticket
+	Value  T
+}
+
+// FanIn merges ins into one channel ordered by ticket, all tickets having
+// been issued by m. Each item is sent as soon as the item of the previous
+// ticket has been, and tickets returned to m are skipped, so a producer that
+// drops an item must return its ticket. Each input must carry its items in
+// ticket order, as a shard fed in issue order does.
+//
+// The output is closed once every input is closed. After ctx is done nothing
+// more is sent, but the inputs are still drained, returning their tickets,
+// so producers don't block.
+func FanIn[T any](ctx context.Context, m *ordermutex.Mutex, ins ...<-chan Stamped[T]) <-chan Stamped[T] {
+	out := make(chan Stamped[T])
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func() {
+			defer wg.Done()
+			for it := range in {
+				if ctx.Err() != nil {
+					m.ReturnTicket(it.Ticket)
+					continue
+				}
+				m.Lock(it.Ticket)
+				select {
+				case out <- it:
+				case <-ctx.Done():
+				}
+				m.Unlock(it.Ticket)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
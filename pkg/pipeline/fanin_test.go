@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+func TestFanIn(t *testing.T) {
+	const n, shards = 200, 4
+	m := ordermutex.NewMutex()
+	ins := make([]chan Stamped[int], shards)
+	for i := range ins {
+		ins[i] = make(chan Stamped[int])
+	}
+
+	// Issue tickets in order, spread them over shards, and drop every tenth.
+	go func() {
+		type work struct {
+			t ordermutex.Ticket
+			v int
+		}
+		queues := make([][]work, shards)
+		for v := 0; v < n; v++ {
+			t := m.GetTicket()
+			if v%10 == 9 {
+				m.ReturnTicket(t)
+				continue
+			}
+			s := rand.IntN(shards)
+			queues[s] = append(queues[s], work{t, v})
+		}
+		for s, q := range queues {
+			go func() {
+				for _, w := range q {
+					time.Sleep(time.Duration(rand.IntN(50)) * time.Microsecond)
+					ins[s] <- Stamped[int]{Ticket: w.t, Value: w.v}
+				}
+				close(ins[s])
+			}()
+		}
+	}()
+
+	chans := make([]<-chan Stamped[int], shards)
+	for i, c := range ins {
+		chans[i] = c
+	}
+	want, got := 0, 0
+	for it := range FanIn(context.Background(), m, chans...) {
+		if want%10 == 9 {
+			want++
+		}
+		if it.Value != want {
+			t.Fatalf("got %d, want %d", it.Value, want)
+		}
+		want++
+		got++
+	}
+	if got != n-n/10 {
+		t.Fatalf("got %d items, want %d", got, n-n/10)
+	}
+}
+
+func TestFanInCancel(t *testing.T) {
+	m := ordermutex.NewMutex()
+	a, b := make(chan Stamped[int]), make(chan Stamped[int])
+	ctx, cancel := context.WithCancel(context.Background())
+	out := FanIn(ctx, m, a, b)
+
+	t0, t1, t2 := m.GetTicket(), m.GetTicket(), m.GetTicket()
+	a <- Stamped[int]{Ticket: t0, Value: 0}
+	if it := <-out; it.Value != 0 {
+		t.Fatalf("got %d", it.Value)
+	}
+	cancel()
+	// Producers are never left blocked, and the output still closes.
+	a <- Stamped[int]{Ticket: t2, Value: 2}
+	b <- Stamped[int]{Ticket: t1, Value: 1}
+	close(a)
+	close(b)
+	for it := range out {
+		t.Fatalf("got %d after cancel", it.Value)
+	}
+}
@@ -0,0 +1,43 @@
+package pipeline
+
+import "context"
+
+// OrderedMap applies fn to items with up to parallelism calls at once and
+// returns the outputs in input order. It stops starting new calls at the
+// first error or when ctx is done, and returns that error.
+func OrderedMap[In, Out any](ctx context.Context, items []In, parallelism int, fn func(In) (Out, error)) ([]Out, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan In)
+	go func() {
+		defer close(in)
+		for _, it := range items {
+			select {
+			case in <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s := Stage[In, Out]{Workers: parallelism, Fn: func(_ context.Context, v In) (Out, error) { return fn(v) }}
+	out, errc := s.Run(ctx, in)
+	res := make([]Out, 0, len(items))
+	for v := range out {
+		res = append(res, v)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ForEach calls fn for every item with up to parallelism calls at once,
+// stopping at the first error, which it returns.
+func ForEach[In any](ctx context.Context, items []In, parallelism int, fn func(In) error) error {
+	_, err := OrderedMap(ctx, items, parallelism, func(v In) (struct{}, error) {
+		return struct{}{}, fn(v)
+	})
+	return err
+}
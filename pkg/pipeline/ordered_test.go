@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrderedMap(t *testing.T) {
+	items := []int{5, 1, 4, 2, 3}
+	out, err := OrderedMap(context.Background(), items, 3, func(v int) (string, error) {
+		time.Sleep(time.Duration(v) * time.Millisecond)
+		return fmt.Sprint(v * 10), nil
+	})
+	if err != nil || fmt.Sprint(out) != "[50 10 40 20 30]" {
+		t.Fatalf("OrderedMap = %v, %v", out, err)
+	}
+}
+
+func TestForEachError(t *testing.T) {
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	err := ForEach(context.Background(), items, 2, func(v int) error {
+		calls.Add(1)
+		if v == 3 {
+			return boom
+		}
+		return nil
+	})
+	if err != boom {
+		t.Fatalf("ForEach = %v", err)
+	}
+	if n := calls.Load(); n > 10 {
+		t.Fatalf("%d calls after the first error", n)
+	}
+}
@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Stage transforms a channel concurrently while keeping its order.
+type Stage[In, Out any] struct {
+	// Workers is the number of items transformed at once; default 1.
+	Workers int
+	// Fn transforms one item. An error stops the stage.
+	Fn func(context.Context, In) (Out, error)
+}
+
+// Run starts the stage on in. Each item takes an ordermutex ticket as it is
+// received and its result is sent once the previous item's has been, so out
+// carries results in input order.
+//
+// The stage stops when in is closed, ctx is done or Fn fails. It then closes
+// out and sends the first error, if any, on the error channel before closing
+// it. Stages sharing a context stop together: upstream stages see the
+// cancellation, and downstream ones see out close.
+func (s Stage[In, Out]) Run(ctx context.Context, in <-chan In) (<-chan Out, <-chan error) {
+	workers := max(s.Workers, 1)
+	ctx, cancel := context.WithCancelCause(ctx)
+	out := make(chan Out)
+	errc := make(chan error, 1)
+
+	var (
+		m    = ordermutex.NewMutex()
+		recv sync.Mutex // receives and ticket issues happen together
+		wg   sync.WaitGroup
+	)
+	next := func() (In, ordermutex.Ticket, bool) {
+		recv.Lock()
+		defer recv.Unlock()
+		select {
+		case v, ok := <-in:
+			if ok {
+				return v, m.GetTicket(), true
+			}
+		case <-ctx.Done():
+		}
+		var zero In
+		return zero, nil, false
+	}
+
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for {
+				v, t, ok := next()
+				if !ok {
+					return
+				}
+				r, err := s.Fn(ctx, v)
+
+				m.Lock(t)
+				if err != nil {
+					cancel(err)
+				} else if ctx.Err() == nil {
+					select {
+					case out <- r:
+					case <-ctx.Done():
+					}
+				}
+				m.Unlock(t)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		if err := context.Cause(ctx); err != nil {
+			errc <- err
+		}
+		cancel(nil)
+		close(out)
+		close(errc)
+	}()
+	return out, errc
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func source(ctx context.Context, n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestStage(t *testing.T) {
+	ctx := context.Background()
+	double := Stage[int, int]{Workers: 4, Fn: func(_ context.Context, v int) (int, error) {
+		time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+		return v * 2, nil
+	}}
+	format := Stage[int, string]{Workers: 3, Fn: func(_ context.Context, v int) (string, error) {
+		return strconv.Itoa(v), nil
+	}}
+
+	mid, err1 := double.Run(ctx, source(ctx, 100))
+	out, err2 := format.Run(ctx, mid)
+	i := 0
+	for s := range out {
+		if s != strconv.Itoa(i*2) {
+			t.Fatalf("item %d = %s", i, s)
+		}
+		i++
+	}
+	if i != 100 || <-err1 != nil || <-err2 != nil {
+		t.Fatalf("%d items", i)
+	}
+}
+
+func TestStageError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	boom := errors.New("boom")
+	s := Stage[int, int]{Workers: 4, Fn: func(_ context.Context, v int) (int, error) {
+		if v == 10 {
+			return 0, boom
+		}
+		return v, nil
+	}}
+	out, errc := s.Run(ctx, source(ctx, 100))
+	n := 0
+	for v := range out {
+		if v != n {
+			t.Fatalf("item %d = %d", n, v)
+		}
+		n++
+	}
+	// Everything before the failing item is delivered, nothing after it.
+	if n != 10 {
+		t.Fatalf("%d items before the error, want 10", n)
+	}
+	if err := <-errc; err != boom {
+		t.Fatalf("error %v", err)
+	}
+}
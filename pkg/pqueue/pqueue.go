@@ -0,0 +1,135 @@
+// Package pqueue provides a bounded blocking priority queue.
+package pqueue
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/notify"
+)
+
+// Queue is a bounded concurrent priority queue. Dequeue returns the item with
+// the highest priority; items of equal priority, including the common case of
+// all zero, come out in the order they were put.
+type Queue[T any] struct {
+	size int
+
+	mu    sync.Mutex
+	items items[T]
+	seq   uint64
+
+	notFull, notEmpty notify.Notifier
+}
+
+type item[T any] struct {
+	v    T
+	prio int
+	seq  uint64
+}
+
+// New returns a queue holding at most size items.
+func New[T any](size int) *Queue[T] {
+	if size <= 0 {
+		panic("pqueue: New called with non-positive size")
+	}
+	return &Queue[T]{size: size}
+}
+
+// Put adds v with priority prio, waiting while the queue is full until there
+// is room or ctx is done.
+func (q *Queue[T]) Put(ctx context.Context, v T, prio int) error {
+	for {
+		q.mu.Lock()
+		if q.put(v, prio) {
+			q.mu.Unlock()
+			return nil
+		}
+		version := q.notFull.Version()
+		q.mu.Unlock()
+		if _, err := q.notFull.Wait(ctx, version); err != nil {
+			return err
+		}
+	}
+}
+
+// TryPut adds v with priority prio if the queue isn't full.
+func (q *Queue[T]) TryPut(v T, prio int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.put(v, prio)
+}
+
+// put adds v if there is room; mu must be held.
+func (q *Queue[T]) put(v T, prio int) bool {
+	if len(q.items) == q.size {
+		return false
+	}
+	heap.Push(&q.items, item[T]{v: v, prio: prio, seq: q.seq})
+	q.seq++
+	q.notEmpty.Signal()
+	return true
+}
+
+// Dequeue removes and returns the highest-priority item, waiting while the
+// queue is empty until there is one or ctx is done.
+func (q *Queue[T]) Dequeue(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		if v, ok := q.take(); ok {
+			q.mu.Unlock()
+			return v, nil
+		}
+		version := q.notEmpty.Version()
+		q.mu.Unlock()
+		if _, err := q.notEmpty.Wait(ctx, version); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+}
+
+// TryDequeue removes and returns the highest-priority item if there is one.
+func (q *Queue[T]) TryDequeue() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.take()
+}
+
+// take pops the top item if there is one; mu must be held.
+func (q *Queue[T]) take() (T, bool) {
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	it := heap.Pop(&q.items).(item[T])
+	q.notFull.Signal()
+	return it.v, true
+}
+
+// Len returns the number of queued items.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// items is a max-heap on priority, then a min-heap on sequence.
+type items[T any] []item[T]
+
+func (h items[T]) Len() int { return len(h) }
+func (h items[T]) Less(i, j int) bool {
+	if h[i].prio != h[j].prio {
+		return h[i].prio > h[j].prio
+	}
+	return h[i].seq < h[j].seq
+}
+func (h items[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *items[T]) Push(x any)   { *h = append(*h, x.(item[T])) }
+func (h *items[T]) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	old[len(old)-1] = item[T]{}
+	*h = old[:len(old)-1]
+	return it
+}
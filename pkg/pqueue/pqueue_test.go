@@ -0,0 +1,65 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	q := New[string](8)
+	for _, p := range []struct {
+		v    string
+		prio int
+	}{{"a", 0}, {"retry1", 5}, {"b", 0}, {"urgent", 9}, {"retry2", 5}, {"c", 0}} {
+		if !q.TryPut(p.v, p.prio) {
+			t.Fatalf("TryPut %s failed", p.v)
+		}
+	}
+	var got []string
+	for q.Len() > 0 {
+		v, _ := q.TryDequeue()
+		got = append(got, v)
+	}
+	if want := "[urgent retry1 retry2 a b c]"; fmt.Sprint(got) != want {
+		t.Fatalf("order %v, want %s", got, want)
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	q := New[int](1)
+	ctx := context.Background()
+	if err := q.Put(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := q.Put(short, 2, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Put into full queue = %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- q.Put(ctx, 2, 0) }()
+	time.Sleep(5 * time.Millisecond)
+	if v, err := q.Dequeue(ctx); v != 1 || err != nil {
+		t.Fatalf("Dequeue = %d, %v", v, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan int)
+	go func() {
+		q.Dequeue(ctx) // takes 2
+		v, _ := q.Dequeue(ctx)
+		got <- v
+	}()
+	time.Sleep(5 * time.Millisecond)
+	q.Put(ctx, 3, 0)
+	if v := <-got; v != 3 {
+		t.Fatalf("blocked Dequeue = %d, want 3", v)
+	}
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDeadline is returned by Pacer.Wait when ctx's deadline falls before the
+// caller's slot, so waiting could only end in failure.
+var ErrDeadline = errors.New("ratelimit: slot is past the context deadline")
+
+// Pacer is a leaky bucket: it spaces admissions evenly, one per interval, and
+// never bursts. Slots are handed out in call order.
+type Pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // earliest free slot
+}
+
+// NewPacer returns a pacer admitting one caller per interval.
+func NewPacer(interval time.Duration) *Pacer {
+	if interval <= 0 {
+		panic("ratelimit: NewPacer called with non-positive interval")
+	}
+	return &Pacer{interval: interval}
+}
+
+// Reservation is a slot booked with Reserve.
+type Reservation struct {
+	p  *Pacer
+	at time.Time
+}
+
+// At returns the time of the slot.
+func (r *Reservation) At() time.Time { return r.at }
+
+// Delay returns how long until the slot, or 0 if it has come.
+func (r *Reservation) Delay() time.Duration {
+	return max(time.Until(r.at), 0)
+}
+
+// Cancel gives the slot back if no later slot was booked since; otherwise
+// the slot stays spent, as later callers are already spaced behind it.
+func (r *Reservation) Cancel() {
+	p := r.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next.Equal(r.at.Add(p.interval)) {
+		p.next = r.at
+	}
+}
+
+// Reserve books the next slot without blocking. The caller should act after
+// Delay has passed, or Cancel the reservation.
+func (p *Pacer) Reserve() *Reservation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reserve(time.Now())
+}
+
+// reserve books the first slot at or after now; mu must be held.
+func (p *Pacer) reserve(now time.Time) *Reservation {
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	return &Reservation{p: p, at: at}
+}
+
+// Wait books the next slot and blocks until it comes. If ctx is done first,
+// or its deadline is before the slot, Wait gives the slot back if it can and
+// returns an error.
+func (p *Pacer) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	now := time.Now()
+	r := p.reserve(now)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.at) {
+		p.next = r.at // nobody booked since, the slot can be returned
+		p.mu.Unlock()
+		return ErrDeadline
+	}
+	p.mu.Unlock()
+
+	d := r.at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// SetInterval changes the spacing for slots booked from now on.
+func (p *Pacer) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		panic("ratelimit: SetInterval called with non-positive interval")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interval = interval
+}
+
+// Interval returns the current spacing between slots.
+func (p *Pacer) Interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval
+}
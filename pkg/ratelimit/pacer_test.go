@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPacerSpacing(t *testing.T) {
+	p := NewPacer(10 * time.Millisecond)
+	var prev time.Time
+	for i := 0; i < 5; i++ {
+		if err := p.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		if i > 0 && now.Sub(prev) < 9*time.Millisecond {
+			t.Fatalf("admission %d after %v, want >= 10ms", i, now.Sub(prev))
+		}
+		prev = now
+	}
+}
+
+func TestPacerReserve(t *testing.T) {
+	p := NewPacer(time.Second)
+	r0 := p.Reserve()
+	if r0.Delay() != 0 {
+		t.Fatalf("first slot delayed %v", r0.Delay())
+	}
+	r1 := p.Reserve()
+	if got := r1.At().Sub(r0.At()); got != time.Second {
+		t.Fatalf("slots %v apart, want 1s", got)
+	}
+
+	// Cancelling the last reservation frees its slot for the next caller.
+	r1.Cancel()
+	if r2 := p.Reserve(); !r2.At().Equal(r1.At()) {
+		t.Fatalf("slot after cancel at %v, want %v", r2.At(), r1.At())
+	}
+}
+
+func TestPacerDeadline(t *testing.T) {
+	p := NewPacer(time.Second)
+	p.Reserve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx); !errors.Is(err, ErrDeadline) {
+		t.Fatalf("Wait past deadline = %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := p.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Wait = %v", err)
+	}
+	// Neither failed wait kept its slot.
+	if d := p.Reserve().Delay(); d > time.Second {
+		t.Fatalf("next slot in %v, want <= 1s", d)
+	}
+}
@@ -0,0 +1,239 @@
+// Package ratelimit provides a token-bucket rate limiter that admits waiting
+// callers strictly in arrival order.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// ErrExceedsBurst is returned by WaitN for a request larger than the burst,
+// which could never be satisfied.
+var ErrExceedsBurst = errors.New("ratelimit: request exceeds burst")
+
+// Limiter is a token bucket refilled at a steady rate up to a burst size.
+// Waiters queue on an ordermutex ticket taken on arrival: only the head of the
+// queue waits for tokens, so a large request holds back every request behind
+// it, however small, instead of racing them for each refill.
+type Limiter struct {
+	q *ordermutex.Mutex
+
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   int
+	tokens  float64
+	last    time.Time
+	waiting int           // callers between GetTicket and leaving WaitN
+	changed chan struct{} // closed when rate or burst changes
+}
+
+// New returns a limiter that refills rate tokens per second up to burst,
+// starting full. A rate of zero or less never refills.
+func New(rate float64, burst int) *Limiter {
+	if burst <= 0 {
+		panic("ratelimit: New called with non-positive burst")
+	}
+	return &Limiter{
+		q:       ordermutex.NewMutex(),
+		rate:    rate,
+		burst:   burst,
+		tokens:  float64(burst),
+		last:    time.Now(),
+		changed: make(chan struct{}),
+	}
+}
+
+// Wait is WaitN(ctx, 1).
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until every earlier caller has been admitted and n tokens are
+// available, then takes them. If ctx is done first it returns ctx.Err(),
+// takes nothing and lets the callers behind it move up.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	if n > l.burst {
+		l.mu.Unlock()
+		return ErrExceedsBurst
+	}
+	l.waiting++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.waiting--
+		l.mu.Unlock()
+	}()
+
+	unlock, ok := l.lock(ctx, l.q.GetTicket())
+	if !ok {
+		return ctx.Err()
+	}
+	defer unlock()
+
+	// At the head of the queue: wait for the bucket to fill, rechecking when
+	// the rate or burst changes.
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		l.mu.Lock()
+		if n > l.burst {
+			l.mu.Unlock()
+			return ErrExceedsBurst
+		}
+		d, ok := l.take(time.Now(), n)
+		changed := l.changed
+		l.mu.Unlock()
+		if ok {
+			return nil
+		}
+
+		var timeout <-chan time.Time
+		if d > 0 {
+			if timer == nil {
+				timer = time.NewTimer(d)
+			} else {
+				timer.Reset(d)
+			}
+			timeout = timer.C
+		}
+		select {
+		case <-timeout:
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// lock takes t's turn unless ctx is done first, and returns a func that
+// passes it on. The turn is awaited with WhenMyTurn rather than Lock, so
+// nothing is parked for t until it comes, and a canceled wait leaves the
+// queue at once through CancelLock.
+func (l *Limiter) lock(ctx context.Context, t ordermutex.Ticket) (unlock func(), ok bool) {
+	if ctx.Done() == nil {
+		l.q.Lock(t)
+		return func() { l.q.Unlock(t) }, true
+	}
+	turn := make(chan struct{})
+	done := make(chan struct{})
+	l.q.WhenMyTurn(t, func() {
+		close(turn)
+		<-done
+	})
+	unlock = func() { close(done) }
+	select {
+	case <-turn:
+		return unlock, true
+	case <-ctx.Done():
+	}
+	if l.q.CancelLock(t) {
+		return nil, false
+	}
+	// The turn came first and the callback is already on its way.
+	<-turn
+	unlock()
+	return nil, false
+}
+
+// Allow takes one token if one is available and nobody is waiting.
+func (l *Limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN takes n tokens if they are available and nobody is waiting.
+func (l *Limiter) AllowN(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.waiting > 0 {
+		return false
+	}
+	_, ok := l.take(time.Now(), n)
+	return ok
+}
+
+// take refills the bucket up to now and takes n tokens if there are enough.
+// Otherwise it reports how long until there will be, or 0 if never at the
+// current rate. mu must be held.
+func (l *Limiter) take(now time.Time, n int) (time.Duration, bool) {
+	l.refill(now)
+	if l.tokens >= float64(n) {
+		l.tokens -= float64(n)
+		return 0, true
+	}
+	if l.rate <= 0 {
+		return 0, false
+	}
+	d := time.Duration((float64(n) - l.tokens) / l.rate * float64(time.Second))
+	return max(d, time.Nanosecond), false
+}
+
+// refill adds the tokens accrued since the last refill; mu must be held.
+func (l *Limiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 && l.rate > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.rate, float64(l.burst))
+	}
+	l.last = now
+}
+
+// SetRate changes the refill rate. Tokens accrued so far are kept.
+func (l *Limiter) SetRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = rate
+	l.notify()
+}
+
+// SetBurst changes the bucket size, dropping tokens above the new size.
+// A waiter asking for more than the new burst gets ErrExceedsBurst.
+func (l *Limiter) SetBurst(burst int) {
+	if burst <= 0 {
+		panic("ratelimit: SetBurst called with non-positive burst")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.burst = burst
+	l.tokens = min(l.tokens, float64(burst))
+	l.notify()
+}
+
+// notify wakes the head waiter to recompute its wait; mu must be held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Rate returns the current refill rate in tokens per second.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Burst returns the current bucket size.
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.burst
+}
+
+// Tokens returns the number of tokens available now.
+func (l *Limiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	return l.tokens
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFIFO(t *testing.T) {
+	l := New(200, 10)
+	if !l.AllowN(10) {
+		t.Fatal("full bucket refused its burst")
+	}
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	// The large request arrives first; the small ones must not overtake it
+	// even though a single token comes sooner.
+	for _, n := range []int{10, 1, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.WaitN(context.Background(), n); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, n)
+			mu.Unlock()
+		}()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if l.Allow() {
+		t.Fatal("Allow jumped the queue")
+	}
+	wg.Wait()
+	if got := fmt.Sprint(order); got != "[10 1 2]" {
+		t.Fatalf("admitted %s, want [10 1 2]", got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	l := New(0, 1)
+	l.Allow()
+
+	// The head waits for a token that never comes; a waiter behind it gives up.
+	head := make(chan error)
+	go func() { head <- l.Wait(context.Background()) }()
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("queued Wait = %v, want deadline exceeded", err)
+	}
+
+	// Raising the rate wakes the head; the canceled waiter's turn is skipped.
+	l.SetRate(1000)
+	if err := <-head; err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCancelLeavesNothingBehind(t *testing.T) {
+	l := New(0, 1)
+	l.Allow()
+	head, stop := context.WithCancel(context.Background())
+	defer stop()
+	go l.Wait(head)
+	time.Sleep(5 * time.Millisecond)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Wait = %v, want deadline exceeded", err)
+		}
+		cancel()
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines left behind by canceled waiters", n-before)
+	}
+}
+
+func TestBurst(t *testing.T) {
+	l := New(1000, 5)
+	if err := l.WaitN(context.Background(), 6); err != ErrExceedsBurst {
+		t.Fatalf("WaitN over burst = %v", err)
+	}
+
+	l.AllowN(5)
+	l.SetRate(0)
+	done := make(chan error)
+	go func() { done <- l.WaitN(context.Background(), 5) }()
+	time.Sleep(5 * time.Millisecond)
+	l.SetBurst(2)
+	if err := <-done; err != ErrExceedsBurst {
+		t.Fatalf("waiter after SetBurst = %v, want ErrExceedsBurst", err)
+	}
+	if l.Burst() != 2 || l.Tokens() > 2 {
+		t.Fatalf("burst %d tokens %v", l.Burst(), l.Tokens())
+	}
+}
+
+func TestRate(t *testing.T) {
+	l := New(100, 1)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One token up front, then five at 10ms each.
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Fatalf("6 tokens in %v, want >= 50ms", d)
+	}
+}
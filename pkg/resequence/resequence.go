@@ -0,0 +1,217 @@
+// Package resequence restores the order of a stream of sequence-numbered
+// items that arrive out of order.
+package resequence
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// Item is a value tagged with its position in the stream.
+type Item[T any] struct {
+	Seq   uint64
+	Value T
+}
+
+// Overflow decides what happens when the buffer is full and the item
+// everyone is waiting for still hasn't arrived.
+type Overflow int
+
+const (
+	// SkipGap gives up on the missing items and moves on to the lowest
+	// buffered one.
+	SkipGap Overflow = iota
+	// DropNewest discards the arriving item and keeps waiting.
+	DropNewest
+)
+
+// Config configures a Resequencer. The zero value expects sequence 0 first,
+// buffers up to 1024 items, waits forever for gaps and flushes on close.
+type Config struct {
+	// Start is the first sequence number expected.
+	Start uint64
+	// Buffer bounds the items held back behind a gap; default 1024.
+	Buffer int
+	// Overflow applies when the buffer is full; default SkipGap.
+	Overflow Overflow
+	// GapTimeout, if positive, is how long to wait for a missing item while
+	// later ones are buffered before skipping it.
+	GapTimeout time.Duration
+	// DropOnClose discards buffered items when the input closes, instead of
+	// emitting them in order across the gaps.
+	DropOnClose bool
+	// OnSkip, if set, is called with each range of sequence numbers given up
+	// on, [from, to).
+	OnSkip func(from, to uint64)
+	// OnDrop, if set, is called with each discarded item: duplicates, items
+	// behind the stream, and items dropped by policy.
+	OnDrop func(seq uint64)
+}
+
+// Resequencer emits items strictly in sequence order.
+type Resequencer[T any] struct {
+	cfg  Config
+	next uint64
+	buf  map[uint64]T
+	seqs seqHeap
+}
+
+// New returns a resequencer configured by cfg.
+func New[T any](cfg Config) *Resequencer[T] {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 1024
+	}
+	return &Resequencer[T]{cfg: cfg, next: cfg.Start, buf: make(map[uint64]T)}
+}
+
+// Run reads items from in and writes them to out in sequence order until in
+// is closed or ctx is done, then closes out. It returns ctx.Err() if ctx
+// ended it.
+func (r *Resequencer[T]) Run(ctx context.Context, in <-chan Item[T], out chan<- Item[T]) error {
+	defer close(out)
+	emit := func(it Item[T]) error {
+		select {
+		case out <- it:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var (
+		gap   *time.Timer
+		gapC  <-chan time.Time
+		armed uint64 // next when the gap timer was armed
+	)
+	defer func() {
+		if gap != nil {
+			gap.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case it, ok := <-in:
+			if !ok {
+				if r.cfg.DropOnClose {
+					for len(r.seqs) > 0 {
+						seq := heap.Pop(&r.seqs).(uint64)
+						delete(r.buf, seq)
+						r.drop(seq)
+					}
+					return nil
+				}
+				for len(r.seqs) > 0 {
+					r.skipTo(r.seqs[0])
+					if err := r.drain(emit); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			if err := r.push(it, emit); err != nil {
+				return err
+			}
+		case <-gapC:
+			gapC = nil
+			if len(r.seqs) > 0 && r.next == armed {
+				r.skipTo(r.seqs[0])
+				if err := r.drain(emit); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// Time the gap from the last progress, while anything waits behind it.
+		if r.cfg.GapTimeout > 0 && len(r.seqs) > 0 && (gapC == nil || armed != r.next) {
+			if gap == nil {
+				gap = time.NewTimer(r.cfg.GapTimeout)
+			} else {
+				gap.Reset(r.cfg.GapTimeout)
+			}
+			gapC, armed = gap.C, r.next
+		}
+	}
+}
+
+// push takes one arriving item.
+func (r *Resequencer[T]) push(it Item[T], emit func(Item[T]) error) error {
+	if _, dup := r.buf[it.Seq]; dup || it.Seq < r.next {
+		r.drop(it.Seq)
+		return nil
+	}
+	if it.Seq == r.next {
+		if err := emit(it); err != nil {
+			return err
+		}
+		r.next++
+		return r.drain(emit)
+	}
+	if len(r.buf) >= r.cfg.Buffer {
+		if r.cfg.Overflow == DropNewest {
+			r.drop(it.Seq)
+			return nil
+		}
+		r.hold(it)
+		r.skipTo(r.seqs[0])
+		return r.drain(emit)
+	}
+	r.hold(it)
+	return nil
+}
+
+func (r *Resequencer[T]) hold(it Item[T]) {
+	r.buf[it.Seq] = it.Value
+	heap.Push(&r.seqs, it.Seq)
+}
+
+// drain emits buffered items continuing the sequence.
+func (r *Resequencer[T]) drain(emit func(Item[T]) error) error {
+	for len(r.seqs) > 0 && r.seqs[0] == r.next {
+		heap.Pop(&r.seqs)
+		v := r.buf[r.next]
+		delete(r.buf, r.next)
+		if err := emit(Item[T]{Seq: r.next, Value: v}); err != nil {
+			return err
+		}
+		r.next++
+	}
+	return nil
+}
+
+// skipTo gives up on every sequence number below seq.
+func (r *Resequencer[T]) skipTo(seq uint64) {
+	if seq > r.next {
+		if r.cfg.OnSkip != nil {
+			r.cfg.OnSkip(r.next, seq)
+		}
+		r.next = seq
+	}
+}
+
+// drop reports a discarded item. A duplicate leaves the buffered original alone.
+func (r *Resequencer[T]) drop(seq uint64) {
+	if r.cfg.OnDrop != nil {
+		r.cfg.OnDrop(seq)
+	}
+}
+
+// Next returns the sequence number the resequencer is waiting for. It must
+// not be called concurrently with Run.
+func (r *Resequencer[T]) Next() uint64 { return r.next }
+
+type seqHeap []uint64
+
+func (h seqHeap) Len() int           { return len(h) }
+func (h seqHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h seqHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *seqHeap) Push(x any)        { *h = append(*h, x.(uint64)) }
+func (h *seqHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,73 @@
+package resequence
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// run feeds seqs through a resequencer and returns what comes out.
+func run(t *testing.T, cfg Config, seqs []uint64, pause time.Duration) []uint64 {
+	t.Helper()
+	in := make(chan Item[string])
+	out := make(chan Item[string], len(seqs))
+	go func() {
+		for _, s := range seqs {
+			in <- Item[string]{Seq: s, Value: fmt.Sprint(s)}
+		}
+		time.Sleep(pause)
+		close(in)
+	}()
+	if err := New[string](cfg).Run(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	var got []uint64
+	for it := range out {
+		if it.Value != fmt.Sprint(it.Seq) {
+			t.Fatalf("item %d carries %q", it.Seq, it.Value)
+		}
+		got = append(got, it.Seq)
+	}
+	return got
+}
+
+func TestReorder(t *testing.T) {
+	var dropped []uint64
+	got := run(t, Config{Start: 10, OnDrop: func(s uint64) { dropped = append(dropped, s) }},
+		[]uint64{12, 10, 14, 14, 11, 11, 9, 13}, 0) // 14 is a duplicate of a buffered item
+	if fmt.Sprint(got) != "[10 11 12 13 14]" || fmt.Sprint(dropped) != "[14 11 9]" {
+		t.Fatalf("emitted %v dropped %v", got, dropped)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var skipped []string
+	onSkip := func(from, to uint64) { skipped = append(skipped, fmt.Sprintf("%d-%d", from, to)) }
+	if got := run(t, Config{OnSkip: onSkip}, []uint64{0, 2, 5}, 0); fmt.Sprint(got) != "[0 2 5]" {
+		t.Fatalf("flush on close emitted %v", got)
+	}
+	if fmt.Sprint(skipped) != "[1-2 3-5]" {
+		t.Fatalf("skipped %v", skipped)
+	}
+	if got := run(t, Config{DropOnClose: true}, []uint64{0, 2, 5}, 0); fmt.Sprint(got) != "[0]" {
+		t.Fatalf("drop on close emitted %v", got)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	seqs := []uint64{3, 2, 4, 1}
+	if got := run(t, Config{Buffer: 2, DropOnClose: true}, seqs, 0); fmt.Sprint(got) != "[2 3 4]" {
+		t.Fatalf("SkipGap emitted %v", got)
+	}
+	if got := run(t, Config{Buffer: 2, Overflow: DropNewest, DropOnClose: true}, seqs, 0); len(got) != 0 {
+		t.Fatalf("DropNewest emitted %v", got)
+	}
+}
+
+func TestGapTimeout(t *testing.T) {
+	got := run(t, Config{GapTimeout: 10 * time.Millisecond, DropOnClose: true}, []uint64{1, 2}, 50*time.Millisecond)
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("emitted %v, want the gap skipped before close", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Package rwmutex provides a reader/writer mutex whose fairness policy is
+// chosen at construction, unlike sync.RWMutex's fixed one.
+package rwmutex
+
+import "sync"
+
+// Policy decides who goes first when readers and writers contend.
+type Policy int
+
+const (
+	// WriterPreferred holds back new readers while a writer waits. Writers
+	// can't starve, but a steady stream of writers starves readers.
+	WriterPreferred Policy = iota
+	// ReaderPreferred admits readers whenever no writer holds the lock.
+	// Readers can't starve, but overlapping readers starve writers.
+	ReaderPreferred
+	// PhaseFair alternates read and write phases: readers arriving while a
+	// writer waits are admitted together as soon as that writer is done,
+	// ahead of the next writer. Neither side can starve.
+	PhaseFair
+)
+
+func (p Policy) String() string {
+	switch p {
+	case WriterPreferred:
+		return "writer-preferred"
+	case ReaderPreferred:
+		return "reader-preferred"
+	case PhaseFair:
+		return "phase-fair"
+	}
+	return "unknown"
+}
+
+// RWMutex is a reader/writer mutex with a configurable Policy. Like
+// sync.RWMutex, a lock is not tied to the goroutine that took it.
+type RWMutex struct {
+	policy Policy
+
+	mu      sync.Mutex
+	readers sync.Cond
+	writers sync.Cond
+
+	active  int    // readers holding the lock
+	writer  bool   // a writer holds the lock
+	wwait   int    // writers waiting
+	rwait   int    // readers waiting for the next read phase
+	rphases uint64 // read phases opened by Unlock
+}
+
+// New returns an unlocked RWMutex using policy.
+func New(policy Policy) *RWMutex {
+	if policy < WriterPreferred || policy > PhaseFair {
+		panic("rwmutex: New called with unknown policy")
+	}
+	m := &RWMutex{policy: policy}
+	m.readers.L = &m.mu
+	m.writers.L = &m.mu
+	return m
+}
+
+// Policy returns the policy m was created with.
+func (m *RWMutex) Policy() Policy { return m.policy }
+
+// RLock locks m for reading.
+func (m *RWMutex) RLock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.admitsReader() {
+		m.active++
+		return
+	}
+	// Wait for the next read phase; the writer ending the current write
+	// phase counts us in.
+	m.rwait++
+	for phase := m.rphases; m.rphases == phase; {
+		m.readers.Wait()
+	}
+}
+
+// admitsReader reports whether a reader may enter without waiting; mu must be held.
+func (m *RWMutex) admitsReader() bool {
+	return !m.writer && (m.policy == ReaderPreferred || m.wwait == 0)
+}
+
+// TryRLock locks m for reading if the policy would admit a reader without
+// waiting, and reports whether it did.
+func (m *RWMutex) TryRLock() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.admitsReader() {
+		return false
+	}
+	m.active++
+	return true
+}
+
+// RUnlock undoes a single RLock.
+func (m *RWMutex) RUnlock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active <= 0 {
+		panic("rwmutex: RUnlock of unlocked RWMutex")
+	}
+	m.active--
+	if m.active == 0 && m.wwait > 0 {
+		m.writers.Signal()
+	}
+}
+
+// Lock locks m for writing.
+func (m *RWMutex) Lock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wwait++
+	for m.writer || m.active > 0 {
+		m.writers.Wait()
+	}
+	m.wwait--
+	m.writer = true
+}
+
+// TryLock locks m for writing if it is free and reports whether it did.
+func (m *RWMutex) TryLock() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writer || m.active > 0 {
+		return false
+	}
+	m.writer = true
+	return true
+}
+
+// Unlock unlocks m for writing.
+func (m *RWMutex) Unlock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.writer {
+		panic("rwmutex: Unlock of unlocked RWMutex")
+	}
+	m.writer = false
+
+	switch {
+	case m.wwait > 0 && (m.policy == WriterPreferred || m.rwait == 0):
+		m.writers.Signal()
+	case m.rwait > 0:
+		// Open a read phase for every reader that queued behind this writer.
+		m.active += m.rwait
+		m.rwait = 0
+		m.rphases++
+		m.readers.Broadcast()
+	}
+}
+
+// RLocker returns a sync.Locker that locks m for reading.
+func (m *RWMutex) RLocker() sync.Locker { return rlocker{m} }
+
+type rlocker struct{ m *RWMutex }
+
+func (r rlocker) Lock()   { r.m.RLock() }
+func (r rlocker) Unlock() { r.m.RUnlock() }
@@ -0,0 +1,170 @@
+package rwmutex
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var policies = []Policy{WriterPreferred, ReaderPreferred, PhaseFair}
+
+func TestExclusion(t *testing.T) {
+	for _, p := range policies {
+		t.Run(p.String(), func(t *testing.T) {
+			m := New(p)
+			var (
+				wg              sync.WaitGroup
+				readers, writer int
+				mu              sync.Mutex // guards the counters only
+			)
+			check := func() {
+				if writer > 1 || writer == 1 && readers > 0 {
+					t.Errorf("%d writers with %d readers", writer, readers)
+				}
+			}
+			enter := func(w bool, d int) {
+				mu.Lock()
+				if w {
+					writer += d
+				} else {
+					readers += d
+				}
+				check()
+				mu.Unlock()
+			}
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						if (i+j)%4 == 0 {
+							m.Lock()
+							enter(true, 1)
+							enter(true, -1)
+							m.Unlock()
+						} else {
+							m.RLock()
+							enter(false, 1)
+							enter(false, -1)
+							m.RUnlock()
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// waitingWriter starts a writer behind a held read lock and waits until it
+// is queued.
+func waitingWriter(m *RWMutex, onLock func()) {
+	go func() {
+		m.Lock()
+		onLock()
+		m.Unlock()
+	}()
+	for {
+		m.mu.Lock()
+		n := m.wwait
+		m.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReaderAdmission(t *testing.T) {
+	for _, tc := range []struct {
+		p    Policy
+		want bool
+	}{{WriterPreferred, false}, {ReaderPreferred, true}, {PhaseFair, false}} {
+		m := New(tc.p)
+		m.RLock()
+		done := make(chan struct{})
+		waitingWriter(m, func() { close(done) })
+		got := m.TryRLock()
+		if got != tc.want {
+			t.Errorf("%v: TryRLock with a waiting writer = %v, want %v", tc.p, got, tc.want)
+		}
+		if got {
+			m.RUnlock()
+		}
+		m.RUnlock()
+		<-done
+	}
+}
+
+func TestPhaseFair(t *testing.T) {
+	m := New(PhaseFair)
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	record := func(s string) {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+	}
+	waitReaders := func(n int) {
+		for {
+			m.mu.Lock()
+			k := m.rwait
+			m.mu.Unlock()
+			if k == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	m.RLock()
+	wg.Add(3)
+	holding, release := make(chan struct{}), make(chan struct{})
+	waitingWriter(m, func() {
+		record("w1")
+		close(holding)
+		<-release
+		wg.Done()
+	})
+	go func() {
+		m.RLock()
+		record("r2")
+		m.RUnlock()
+		wg.Done()
+	}()
+	waitReaders(1)
+	m.RUnlock()
+
+	// w1 holds the lock; queue another writer behind the waiting reader.
+	<-holding
+	waitingWriter(m, func() { record("w2"); wg.Done() })
+	close(release)
+	wg.Wait()
+
+	if got := fmt.Sprint(order); got != "[w1 r2 w2]" {
+		t.Fatalf("order %s, want [w1 r2 w2]", got)
+	}
+}
+
+func BenchmarkReadMostly(b *testing.B) {
+	for _, p := range policies {
+		b.Run(p.String(), func(b *testing.B) {
+			m := New(p)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%16 == 0 {
+						m.Lock()
+						m.Unlock()
+					} else {
+						m.RLock()
+						m.RUnlock()
+					}
+				}
+			})
+		})
+	}
+}
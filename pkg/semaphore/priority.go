@@ -0,0 +1,137 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Priority is a weighted semaphore whose waiters are granted by priority class,
+// highest first, and in arrival order within a class. As with Semaphore, the
+// request next in line holds back the rest until it fits.
+//
+// With aging, a waiter is promoted one class for every aging period it has
+// waited, so a steady stream of high-class requests can't starve low classes forever.
+type Priority struct {
+	size  int64
+	aging time.Duration
+
+	mu      sync.Mutex
+	cur     int64
+	waiters []*pwaiter // in arrival order
+}
+
+type pwaiter struct {
+	n     int64
+	class int
+	since time.Time
+	ready chan struct{} // closed when granted
+}
+
+// NewPriority returns a priority semaphore of the given size.
+// A zero aging period disables aging.
+func NewPriority(size int64, aging time.Duration) *Priority {
+	if size <= 0 {
+		panic("semaphore: NewPriority called with non-positive size")
+	}
+	return &Priority{size: size, aging: aging}
+}
+
+// Acquire takes n tokens for a request of the given class, waiting until it is
+// next in line and fits, or ctx is done. On failure it returns ctx.Err() and takes nothing.
+func (p *Priority) Acquire(ctx context.Context, class int, n int64) error {
+	if n > p.size {
+		return ErrTooLarge
+	}
+
+	p.mu.Lock()
+	if len(p.waiters) == 0 && p.size-p.cur >= n {
+		p.cur += n
+		p.mu.Unlock()
+		return nil
+	}
+	w := &pwaiter{n: n, class: class, since: time.Now(), ready: make(chan struct{})}
+	p.waiters = append(p.waiters, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	select {
+	case <-w.ready:
+		// Granted while giving up: hand the tokens on.
+		p.cur -= n
+	default:
+		p.remove(w)
+	}
+	p.grant()
+	p.mu.Unlock()
+	return ctx.Err()
+}
+
+// TryAcquire takes n tokens if they are free and nobody is waiting, without blocking.
+func (p *Priority) TryAcquire(n int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.waiters) == 0 && p.size-p.cur >= n {
+		p.cur += n
+		return true
+	}
+	return false
+}
+
+// Release returns n tokens and grants waiting requests that now fit, by priority.
+func (p *Priority) Release(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cur -= n
+	if p.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	p.grant()
+}
+
+// grant wakes waiters in priority order while they fit; mu must be held.
+func (p *Priority) grant() {
+	now := time.Now()
+	for len(p.waiters) > 0 {
+		w := p.next(now)
+		if p.size-p.cur < w.n {
+			return
+		}
+		p.cur += w.n
+		p.remove(w)
+		close(w.ready)
+	}
+}
+
+// next returns the waiter with the highest effective class, the oldest on ties.
+func (p *Priority) next(now time.Time) *pwaiter {
+	var best *pwaiter
+	bestClass := 0
+	for _, w := range p.waiters {
+		c := w.class
+		if p.aging > 0 {
+			c += int(now.Sub(w.since) / p.aging)
+		}
+		if best == nil || c > bestClass {
+			best, bestClass = w, c
+		}
+	}
+	return best
+}
+
+func (p *Priority) remove(w *pwaiter) {
+	for i, x := range p.waiters {
+		if x == w {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return
+		}
+	}
+}
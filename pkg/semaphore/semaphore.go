@@ -0,0 +1,114 @@
+// Package semaphore provides a weighted semaphore that grants requests
+// strictly in arrival order.
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTooLarge is returned by Acquire for a request larger than the semaphore.
+var ErrTooLarge = errors.New("semaphore: request exceeds capacity")
+
+// Semaphore is a weighted semaphore with FIFO granting: a request that does not
+// fit yet holds back every request behind it, however small, so large requests
+// can't be starved by a stream of small ones. Each grant wakes exactly the
+// waiters it satisfies.
+type Semaphore struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List // of *waiter
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // closed when granted
+}
+
+func New(size int64) *Semaphore {
+	if size <= 0 {
+		panic("semaphore: New called with non-positive size")
+	}
+	return &Semaphore{size: size}
+}
+
+// Acquire takes n tokens, waiting behind earlier requests until they fit or
+// ctx is done. On failure it returns ctx.Err() and takes nothing.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	if s.waiters.Len() == 0 && s.size-s.cur >= n {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &waiter{n: n, ready: make(chan struct{})}
+	e := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	select {
+	case <-w.ready:
+		// Granted while giving up: hand the tokens on.
+		s.cur -= n
+	default:
+		s.waiters.Remove(e)
+	}
+	s.grant()
+	s.mu.Unlock()
+	return ctx.Err()
+}
+
+// TryAcquire takes n tokens if they are free and nobody is waiting, without blocking.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiters.Len() == 0 && s.size-s.cur >= n {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release returns n tokens and grants waiting requests that now fit, in order.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.grant()
+}
+
+// grant wakes waiters from the front while they fit; mu must be held.
+func (s *Semaphore) grant() {
+	for {
+		e := s.waiters.Front()
+		if e == nil {
+			return
+		}
+		w := e.Value.(*waiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(e)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,199 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFIFO(t *testing.T) {
+	s := New(10)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []int64
+		wg    sync.WaitGroup
+	)
+	// A large request queued first must not be overtaken by small ones that would fit.
+	for _, n := range []int64{5, 1, 1} {
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			if err := s.Acquire(ctx, n); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, n)
+			mu.Unlock()
+		}(n)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire jumped the queue")
+	}
+
+	mu.Lock()
+	if len(order) != 0 {
+		t.Fatalf("granted %v while a larger request waits", order)
+	}
+	mu.Unlock()
+
+	s.Release(3) // room for exactly the large request
+	time.Sleep(5 * time.Millisecond)
+	mu.Lock()
+	if len(order) != 1 || order[0] != 5 {
+		t.Fatalf("granted %v, want only the large request", order)
+	}
+	mu.Unlock()
+
+	s.Release(5)
+	wg.Wait()
+}
+
+func TestCancel(t *testing.T) {
+	s := New(2)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- s.Acquire(ctx, 1) }()
+	time.Sleep(5 * time.Millisecond)
+
+	// A canceled request at the front unblocks the ones behind it.
+	if err := s.Acquire(cctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v, want deadline exceeded", err)
+	}
+	s.Release(1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Acquire(ctx, 3); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Acquire over capacity = %v", err)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		aging time.Duration
+		want  string
+	}{
+		{"classes", 0, "[high low-old low-new]"},
+		{"aging", 50 * time.Millisecond, "[low-old high low-new]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPriority(1, tc.aging)
+			ctx := context.Background()
+			if err := p.Acquire(ctx, 0, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				mu    sync.Mutex
+				order []string
+				wg    sync.WaitGroup
+			)
+			queue := func(name string, class int) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := p.Acquire(ctx, class, 1); err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+					p.Release(1)
+				}()
+				time.Sleep(5 * time.Millisecond)
+			}
+			queue("low-old", 0)
+			time.Sleep(3 * tc.aging) // with aging, low-old outranks a fresh class-1 request
+			queue("low-new", 0)
+			queue("high", 1)
+
+			p.Release(1)
+			wg.Wait()
+			if got := fmt.Sprint(order); got != tc.want {
+				t.Fatalf("grant order %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTicketedOrder(t *testing.T) {
+	s := NewTicketed(3)
+	ctx := context.Background()
+	a, _ := s.Reserve(2)
+	big, _ := s.Reserve(3)
+	small, _ := s.Reserve(1)
+
+	if !s.Granted(a) {
+		t.Fatal("first ticket not granted before Acquire")
+	}
+	if s.Granted(small) {
+		t.Fatal("small ticket overtook an earlier one that doesn't fit yet")
+	}
+
+	// Blocking in reverse issue order changes nothing.
+	done := make(chan *Ticket, 2)
+	for _, tk := range []*Ticket{small, big} {
+		go func() {
+			if err := s.Acquire(ctx, tk); err != nil {
+				t.Error(err)
+			}
+			done <- tk
+		}()
+	}
+	if err := s.Acquire(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	s.Release(a)
+	if got := <-done; got != big {
+		t.Fatal("tickets granted out of issue order")
+	}
+	s.Release(big)
+	<-done
+	s.Release(small)
+
+	if _, err := s.Reserve(4); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Reserve over capacity = %v", err)
+	}
+}
+
+func TestTicketedCancel(t *testing.T) {
+	s := NewTicketed(1)
+	a, _ := s.Reserve(1)
+	b, _ := s.Reserve(1)
+	c, _ := s.Reserve(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v, want deadline exceeded", err)
+	}
+	if err := s.Acquire(context.Background(), b); err != ErrTicketDone {
+		t.Fatalf("Acquire of a given-up ticket = %v, want ErrTicketDone", err)
+	}
+
+	// A canceled granted ticket hands its tokens to the next live one.
+	s.Cancel(a)
+	if !s.Granted(c) || s.Waiting() != 0 {
+		t.Fatal("ticket behind canceled ones not granted")
+	}
+	s.Cancel(a)
+	s.Release(c)
+}
@@ -0,0 +1,150 @@
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTicketDone is returned by Acquire for a ticket that was canceled,
+// released or given up by an earlier Acquire.
+var ErrTicketDone = errors.New("semaphore: ticket already used")
+
+// Ticketed is a weighted semaphore whose requests take their place in line
+// when they reserve, not when they block: Reserve issues a ticket, and
+// tickets are granted strictly in issue order, each once every earlier one
+// has been granted or canceled and its weight fits. Acquire then only waits
+// for the grant, so where a request queues and where it blocks can be
+// different points of the program, as with an ordermutex's GetTicket and Lock.
+//
+// A ticket is granted as soon as its turn comes and it fits, whether or not
+// its holder has called Acquire yet; its tokens are taken from then on.
+type Ticketed struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	pending list.List // of *Ticket, in issue order
+}
+
+// Ticket is a place in a Ticketed semaphore's line for n tokens.
+type Ticket struct {
+	n     int64
+	el    *list.Element // in pending until granted or canceled; guarded by mu
+	state ticketState   // guarded by mu
+	ready chan struct{} // closed when granted
+}
+
+type ticketState int
+
+const (
+	ticketPending ticketState = iota
+	ticketGranted
+	ticketDone
+)
+
+// NewTicketed returns a ticketed semaphore of the given size.
+func NewTicketed(size int64) *Ticketed {
+	if size <= 0 {
+		panic("semaphore: NewTicketed called with non-positive size")
+	}
+	return &Ticketed{size: size}
+}
+
+// Reserve issues a ticket for n tokens at the end of the line.
+func (s *Ticketed) Reserve(n int64) (*Ticket, error) {
+	if n > s.size {
+		return nil, ErrTooLarge
+	}
+	t := &Ticket{n: n, ready: make(chan struct{})}
+	s.mu.Lock()
+	t.el = s.pending.PushBack(t)
+	s.grant()
+	s.mu.Unlock()
+	return t, nil
+}
+
+// Acquire waits until t is granted or ctx is done. On failure it returns
+// ctx.Err() and gives t up, handing its place, or its tokens if it was
+// granted meanwhile, to the tickets behind it.
+func (s *Ticketed) Acquire(ctx context.Context, t *Ticket) error {
+	s.mu.Lock()
+	done := t.state == ticketDone
+	s.mu.Unlock()
+	if done {
+		return ErrTicketDone
+	}
+
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.Cancel(t)
+	return ctx.Err()
+}
+
+// Granted reports whether t has been granted and not yet released or canceled.
+func (s *Ticketed) Granted(t *Ticket) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return t.state == ticketGranted
+}
+
+// Release returns t's tokens and grants the tickets that now fit, in order.
+// It panics if t is not granted.
+func (s *Ticketed) Release(t *Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.state != ticketGranted {
+		panic("semaphore: release of a ticket that is not granted")
+	}
+	t.state = ticketDone
+	s.cur -= t.n
+	s.grant()
+}
+
+// Cancel gives t up: a pending ticket leaves the line, and a granted one
+// returns its tokens. Canceling a ticket that is already done is a no-op.
+func (s *Ticketed) Cancel(t *Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch t.state {
+	case ticketPending:
+		s.pending.Remove(t.el)
+		t.el = nil
+	case ticketGranted:
+		s.cur -= t.n
+	case ticketDone:
+		return
+	}
+	t.state = ticketDone
+	s.grant()
+}
+
+// Waiting returns the number of tickets issued and not yet granted or canceled.
+func (s *Ticketed) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending.Len()
+}
+
+// grant grants tickets from the front of the line while they fit; mu must be held.
+func (s *Ticketed) grant() {
+	for {
+		e := s.pending.Front()
+		if e == nil {
+			return
+		}
+		t := e.Value.(*Ticket)
+		if s.size-s.cur < t.n {
+			return
+		}
+		s.cur += t.n
+		s.pending.Remove(e)
+		t.el = nil
+		t.state = ticketGranted
+		close(t.ready)
+	}
+}
@@ -0,0 +1,68 @@
+// Package seqlock provides a sequence lock for small, read-mostly values:
+// readers copy the value without writing shared memory and retry if a write
+// overlapped the copy.
+package seqlock
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SeqLock holds a value of type T. The zero value holds the zero T.
+//
+// Load copies the value racily and validates the copy against the sequence
+// number, so T should be small: a torn copy costs a retry, and a write-heavy
+// workload can keep readers retrying. Writers are serialized.
+//
+// In race-detector builds reads take the writers' lock instead, since the
+// detector can't see that torn copies are discarded.
+type SeqLock[T any] struct {
+	seq atomic.Uint64 // odd while a write is in progress
+	mu  sync.Mutex
+	v   T
+}
+
+// Load returns a consistent copy of the value.
+func (s *SeqLock[T]) Load() T {
+	if raceEnabled {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.v
+	}
+	for {
+		seq := s.seq.Load()
+		if seq&1 != 0 {
+			runtime.Gosched()
+			continue
+		}
+		v := s.v
+		if s.seq.Load() == seq {
+			return v
+		}
+	}
+}
+
+// Store replaces the value.
+func (s *SeqLock[T]) Store(v T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(v)
+}
+
+// Update replaces the value with the result of applying fn to a copy of it.
+func (s *SeqLock[T]) Update(fn func(*T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.v
+	fn(&v)
+	s.store(v)
+}
+
+// store publishes v; mu must be held.
+func (s *SeqLock[T]) store(v T) {
+	s.seq.Add(1)
+	s.v = v
+	s.seq.Add(1)
+}
@@ -0,0 +1,59 @@
+package seqlock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type snapshot struct {
+	count, sum, min, max int64 // all equal
+}
+
+func TestSeqLock(t *testing.T) {
+	var (
+		s    SeqLock[snapshot]
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				v := s.Load()
+				if v.count != v.sum || v.sum != v.min || v.min != v.max {
+					t.Errorf("torn read %+v", v)
+					return
+				}
+			}
+		}()
+	}
+	for i := int64(1); i <= 10000; i++ {
+		if i%2 == 0 {
+			s.Store(snapshot{i, i, i, i})
+		} else {
+			s.Update(func(v *snapshot) {
+				v.count++
+				v.sum++
+				v.min++
+				v.max++
+			})
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if v := s.Load(); v.count != 10000 {
+		t.Fatalf("final %+v", v)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	var s SeqLock[snapshot]
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = s.Load()
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package sequencer
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrCorrupt is returned by a store whose saved mark fails its checksum.
+var ErrCorrupt = errors.New("sequencer: corrupt mark")
+
+// FileStore keeps the mark in a file, replaced atomically on each save: the
+// new mark is written and synced to a temporary file that is then renamed
+// over the old one, so a crash leaves either the old or the new mark.
+type FileStore struct {
+	Path string
+}
+
+func (f FileStore) Load() (uint64, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return decodeMark(b)
+}
+
+func (f FileStore) Save(mark uint64) error {
+	tmp := f.Path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(encodeMark(mark))
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.Path); err != nil {
+		return err
+	}
+	// Sync the directory so the rename itself survives a crash.
+	dir, err := os.Open(filepath.Dir(f.Path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Marks are stored as the big-endian value followed by its CRC-32.
+const markSize = 8 + 4
+
+func encodeMark(mark uint64) []byte {
+	b := make([]byte, markSize)
+	binary.BigEndian.PutUint64(b, mark)
+	binary.BigEndian.PutUint32(b[8:], crc32.ChecksumIEEE(b[:8]))
+	return b
+}
+
+func decodeMark(b []byte) (uint64, error) {
+	if len(b) != markSize || binary.BigEndian.Uint32(b[8:]) != crc32.ChecksumIEEE(b[:8]) {
+		return 0, ErrCorrupt
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package sequencer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMmapStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seq")
+	store, err := OpenMmapStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := New(store, 4)
+	for i := 0; i < 10; i++ {
+		s.Next()
+	}
+	store.Close()
+
+	store, err = OpenMmapStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if mark, err := store.Load(); err != nil || mark != 12 {
+		t.Fatalf("Load = %d, %v; want 12", mark, err)
+	}
+	// Tear the newest slot: Load falls back to the older mark.
+	store.data[(1-store.slot)*markSize] ^= 0xff
+	if mark, err := store.Load(); err != nil || mark != 8 {
+		t.Fatalf("Load with a torn slot = %d, %v; want 8", mark, err)
+	}
+}
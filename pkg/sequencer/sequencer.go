@@ -0,0 +1,71 @@
+// Package sequencer hands out sequence numbers that stay monotonic across
+// process restarts and crashes.
+package sequencer
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrExhausted is returned by Next once the uint64 range is used up.
+var ErrExhausted = errors.New("sequencer: sequence exhausted")
+
+// Store persists a sequencer's high-water mark.
+type Store interface {
+	// Load returns the saved mark, or 0 if none was saved.
+	Load() (uint64, error)
+	// Save durably records mark; once it returns, a crash must not lose it.
+	Save(mark uint64) error
+}
+
+// Sequencer issues increasing numbers, reserving them from its store a block
+// at a time: the mark saved is always past every number handed out, so a
+// restart resumes after the reserved block. A crash skips the unused rest of
+// that block but never repeats a number.
+type Sequencer struct {
+	store Store
+	block uint64
+
+	mu    sync.Mutex
+	next  uint64
+	limit uint64 // end of the reserved block; numbers below it are safe to issue
+}
+
+// New returns a sequencer resuming from store's mark, reserving block numbers
+// per save.
+func New(store Store, block uint64) (*Sequencer, error) {
+	if block == 0 {
+		panic("sequencer: New called with zero block")
+	}
+	mark, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Sequencer{store: store, block: block, next: mark, limit: mark}, nil
+}
+
+// Next returns the next number. It saves a new mark once per block; if that
+// fails, it returns the error and issues nothing.
+func (s *Sequencer) Next() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next == s.limit {
+		if s.limit > ^uint64(0)-s.block {
+			return 0, ErrExhausted
+		}
+		if err := s.store.Save(s.limit + s.block); err != nil {
+			return 0, err
+		}
+		s.limit += s.block
+	}
+	n := s.next
+	s.next++
+	return n, nil
+}
+
+// Peek returns the number Next would return, without issuing it.
+func (s *Sequencer) Peek() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next
+}
@@ -0,0 +1,66 @@
+package sequencer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingStore records saves over another store.
+type countingStore struct {
+	Store
+	saves int
+	fail  bool
+}
+
+func (c *countingStore) Save(mark uint64) error {
+	if c.fail {
+		return errors.New("disk full")
+	}
+	c.saves++
+	return c.Store.Save(mark)
+}
+
+func TestRestart(t *testing.T) {
+	store := &countingStore{Store: FileStore{Path: filepath.Join(t.TempDir(), "seq")}}
+	s, err := New(store, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := uint64(0); want < 25; want++ {
+		if n, err := s.Next(); n != want || err != nil {
+			t.Fatalf("Next = %d, %v; want %d", n, err, want)
+		}
+	}
+	if store.saves != 3 {
+		t.Fatalf("%d saves for 25 numbers in blocks of 10", store.saves)
+	}
+
+	// A restart resumes past the reserved block.
+	s, err = New(store, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Next(); n != 30 {
+		t.Fatalf("Next after restart = %d, want 30", n)
+	}
+
+	store.fail = true
+	for i := 0; i < 9; i++ {
+		s.Next()
+	}
+	if _, err := s.Next(); err == nil {
+		t.Fatal("Next issued a number it could not reserve")
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seq")
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(FileStore{Path: path}, 1); err != ErrCorrupt {
+		t.Fatalf("New on corrupt file = %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Package shutdown stops an application's components in the reverse of the
+// order they were registered in.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrShutdown is returned by registrations after Shutdown has started.
+	ErrShutdown = errors.New("shutdown: already shutting down")
+	// ErrDuplicate is returned when registering a name already in use.
+	ErrDuplicate = errors.New("shutdown: duplicate component name")
+	// ErrUnknownDependency is returned when registering a component before
+	// one it depends on.
+	ErrUnknownDependency = errors.New("shutdown: dependency not registered")
+)
+
+// Component is something to stop at shutdown.
+type Component struct {
+	// Name identifies the component in errors and to DependsOn; it may be
+	// empty for components nothing depends on.
+	Name string
+	// DependsOn names components that must stay up until this one has
+	// stopped. They must already be registered.
+	DependsOn []string
+	// Stop stops the component. Its context is done when the component's
+	// timeout expires or Shutdown's context is done.
+	Stop func(ctx context.Context) error
+	// Timeout, if positive, overrides the coordinator's default for this component.
+	Timeout time.Duration
+}
+
+// StopError is the failure of one component's Stop.
+type StopError struct {
+	Name string
+	Err  error
+}
+
+func (e *StopError) Error() string { return fmt.Sprintf("shutdown: stopping %q: %v", e.Name, e.Err) }
+
+func (e *StopError) Unwrap() error { return e.Err }
+
+// Coordinator stops registered components one at a time, last registered
+// first, so every component outlives those registered after it.
+type Coordinator struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	slots  []*Slot
+	names  map[string]bool
+	closed bool
+
+	once sync.Once
+	err  error
+}
+
+// New returns a coordinator giving each component timeout to stop, or as
+// long as Shutdown's context allows if timeout is not positive.
+func New(timeout time.Duration) *Coordinator {
+	return &Coordinator{timeout: timeout, names: make(map[string]bool)}
+}
+
+// Slot is a place in the shutdown order reserved ahead of its component.
+type Slot struct {
+	c    *Coordinator
+	comp *Component // guarded by c.mu
+}
+
+// Register adds comp at the end of the registration order, to be stopped
+// before everything registered so far.
+func (c *Coordinator) Register(comp Component) error {
+	s, err := c.Reserve()
+	if err != nil {
+		return err
+	}
+	return s.Register(comp)
+}
+
+// Reserve takes the next place in the registration order for a component
+// registered later with Slot.Register, such as one constructed
+// concurrently with others. Slots never filled are skipped at shutdown.
+func (c *Coordinator) Reserve() (*Slot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrShutdown
+	}
+	s := &Slot{c: c}
+	c.slots = append(c.slots, s)
+	return s, nil
+}
+
+// Register fills the slot with comp. A component's dependencies must be
+// registered in earlier slots. Register panics if the slot is already filled.
+func (s *Slot) Register(comp Component) error {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.comp != nil {
+		panic("shutdown: Register called twice on a slot")
+	}
+	if c.closed {
+		return ErrShutdown
+	}
+	if comp.Name != "" && c.names[comp.Name] {
+		return fmt.Errorf("%w: %q", ErrDuplicate, comp.Name)
+	}
+	for _, dep := range comp.DependsOn {
+		if !c.registeredBefore(dep, s) {
+			return fmt.Errorf("%w: %q needs %q", ErrUnknownDependency, comp.Name, dep)
+		}
+	}
+	if comp.Name != "" {
+		c.names[comp.Name] = true
+	}
+	s.comp = &comp
+	return nil
+}
+
+// registeredBefore reports whether a component named name fills a slot
+// ahead of s; mu must be held.
+func (c *Coordinator) registeredBefore(name string, s *Slot) bool {
+	for _, prev := range c.slots {
+		if prev == s {
+			return false
+		}
+		if prev.comp != nil && prev.comp.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown stops every registered component in reverse registration order,
+// waiting for each to stop, or for its timeout, before moving to the next.
+// A component still running when its timeout expires is left behind and
+// reported with context.DeadlineExceeded. If ctx is done, the remaining
+// components are stopped with an already-done context.
+//
+// Shutdown returns the joined *StopErrors of every failed component. Only
+// the first call does the work; later ones wait for it and return the same.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		slots := c.slots
+		c.mu.Unlock()
+
+		var errs []error
+		for i := len(slots) - 1; i >= 0; i-- {
+			c.mu.Lock()
+			comp := slots[i].comp
+			c.mu.Unlock()
+			if comp == nil || comp.Stop == nil {
+				continue
+			}
+			if err := c.stop(ctx, comp); err != nil {
+				errs = append(errs, &StopError{Name: comp.Name, Err: err})
+			}
+		}
+		c.err = errors.Join(errs...)
+	})
+	return c.err
+}
+
+// stop runs comp.Stop under its timeout.
+func (c *Coordinator) stop(ctx context.Context, comp *Component) error {
+	timeout := c.timeout
+	if comp.Timeout > 0 {
+		timeout = comp.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- comp.Stop(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,93 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReverseOrder(t *testing.T) {
+	c := New(0)
+	var stopped []string
+	stop := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		}
+	}
+
+	late, err := c.Reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	must(t, c.Register(Component{Name: "db", Stop: stop("db")}))
+	must(t, c.Register(Component{Name: "http", DependsOn: []string{"db"}, Stop: stop("http")}))
+	must(t, late.Register(Component{Name: "cache", Stop: stop("cache")}))
+	unused, _ := c.Reserve()
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http", "db", "cache"}; !slices.Equal(stopped, want) {
+		t.Fatalf("stopped %v, want %v", stopped, want)
+	}
+	if err := unused.Register(Component{Name: "x"}); err != ErrShutdown {
+		t.Fatalf("Register after Shutdown = %v, want ErrShutdown", err)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	c := New(0)
+	if err := c.Register(Component{Name: "http", DependsOn: []string{"db"}}); !errors.Is(err, ErrUnknownDependency) {
+		t.Fatalf("Register = %v, want ErrUnknownDependency", err)
+	}
+
+	// A dependency in a later slot doesn't count, even if filled first.
+	s, _ := c.Reserve()
+	must(t, c.Register(Component{Name: "db"}))
+	must(t, c.Register(Component{Name: "db2"}))
+	if err := s.Register(Component{Name: "http", DependsOn: []string{"db"}}); !errors.Is(err, ErrUnknownDependency) {
+		t.Fatalf("Register = %v, want ErrUnknownDependency", err)
+	}
+	if err := c.Register(Component{Name: "db"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Register = %v, want ErrDuplicate", err)
+	}
+}
+
+func TestErrorsAndTimeouts(t *testing.T) {
+	c := New(time.Second)
+	boom := errors.New("boom")
+	var ranLast bool
+	must(t, c.Register(Component{Name: "last", Stop: func(context.Context) error {
+		ranLast = true
+		return nil
+	}}))
+	must(t, c.Register(Component{Name: "hung", Timeout: 10 * time.Millisecond, Stop: func(context.Context) error {
+		select {} // ignores its context
+	}}))
+	must(t, c.Register(Component{Name: "failing", Stop: func(context.Context) error { return boom }}))
+
+	err := c.Shutdown(context.Background())
+	if !errors.Is(err, boom) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want boom and a deadline", err)
+	}
+	var se *StopError
+	if !errors.As(err, &se) || se.Name != "failing" {
+		t.Fatalf("first StopError %+v, want the failing component", se)
+	}
+	if !ranLast {
+		t.Fatal("component behind the failures was not stopped")
+	}
+	if again := c.Shutdown(context.Background()); again != err {
+		t.Fatalf("second Shutdown = %v, want %v", again, err)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package singleflight
+
+import (
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Queue serializes calls per key instead of coalescing them: every call runs
+// its own function, one at a time per key, in arrival order. Calls for
+// different keys run concurrently. The zero value is ready to use.
+type Queue[K comparable, V any] struct {
+	mu     sync.Mutex
+	queues map[K]*queue
+}
+
+type queue struct {
+	refs int // guarded by Queue.mu
+	m    *ordermutex.Mutex
+}
+
+// Do waits for the calls for key that arrived earlier, then runs fn.
+func (q *Queue[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	q.mu.Lock()
+	if q.queues == nil {
+		q.queues = make(map[K]*queue)
+	}
+	e, ok := q.queues[key]
+	if !ok {
+		e = &queue{m: ordermutex.NewMutex()}
+		q.queues[key] = e
+	}
+	e.refs++
+	t := e.m.GetTicket() // taken under mu, so ticket order is arrival order
+	q.mu.Unlock()
+
+	e.m.Lock(t)
+	defer func() {
+		e.m.Unlock(t)
+		q.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(q.queues, key)
+		}
+		q.mu.Unlock()
+	}()
+	return fn()
+}
@@ -0,0 +1,114 @@
+// Package singleflight suppresses duplicate concurrent calls for the same key,
+// optionally keeping a successful result for a while so calls arriving just
+// after the flight lands reuse it too.
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sawdustofmind/adv-sync/pkg/timingwheel"
+)
+
+// ErrPanicked is returned to callers that shared a flight whose function panicked.
+// The caller that ran it gets the panic.
+var ErrPanicked = errors.New("singleflight: function panicked")
+
+// evictions drops retained results once they expire, so keys that are never
+// asked for again don't pin them. Freshness itself is checked exactly in Do.
+var evictions = timingwheel.New(100*time.Millisecond, 1024)
+
+// Group runs at most one flight per key at a time. The zero value runs
+// flights without retaining their results.
+type Group[K comparable, V any] struct {
+	// TTL is how long a successful result is reused after its flight ends.
+	// Failed flights are never retained.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	expires time.Time // zero while in flight
+	evict   *timingwheel.Timer
+}
+
+// Do runs fn for key unless a flight for key is in progress or a retained
+// result is still fresh, in which case it returns that flight's result instead.
+// shared reports whether the result was given to more than one caller.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	if c, ok := g.calls[key]; ok {
+		if c.expires.IsZero() || time.Now().Before(c.expires) {
+			g.mu.Unlock()
+			<-c.done
+			return c.val, c.err, true
+		}
+		g.remove(key, c)
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	normal := false
+	defer func() {
+		if !normal {
+			c.err = ErrPanicked
+			g.land(key, c)
+		}
+	}()
+	c.val, c.err = fn()
+	normal = true
+	g.land(key, c)
+	return c.val, c.err, false
+}
+
+// land publishes c's result and retains it for TTL if it succeeded.
+func (g *Group[K, V]) land(key K, c *call[V]) {
+	g.mu.Lock()
+	if g.calls[key] == c { // not forgotten meanwhile
+		if g.TTL > 0 && c.err == nil {
+			c.expires = time.Now().Add(g.TTL)
+			c.evict = evictions.AfterFunc(g.TTL, func() { g.drop(key, c) })
+		} else {
+			delete(g.calls, key)
+		}
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// Forget drops key's flight or retained result: the next Do for key runs fn
+// anew, while callers already waiting on an in-progress flight still get its result.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.remove(key, c)
+	}
+	g.mu.Unlock()
+}
+
+// drop removes c if it is still key's entry.
+func (g *Group[K, V]) drop(key K, c *call[V]) {
+	g.mu.Lock()
+	if g.calls[key] == c {
+		g.remove(key, c)
+	}
+	g.mu.Unlock()
+}
+
+// remove deletes key's entry c and its pending eviction; mu must be held.
+func (g *Group[K, V]) remove(key K, c *call[V]) {
+	delete(g.calls, key)
+	if c.evict != nil {
+		c.evict.Stop()
+	}
+}
@@ -0,0 +1,140 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var (
+		g     Group[string, int]
+		calls atomic.Int32
+		wg    sync.WaitGroup
+	)
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var sharedN atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("k", fn)
+			if v != 42 || err != nil {
+				t.Errorf("Do = %d, %v", v, err)
+			}
+			if shared {
+				sharedN.Add(1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn ran %d times, want 1", n)
+	}
+	if n := sharedN.Load(); n != 9 {
+		t.Fatalf("%d callers shared, want 9", n)
+	}
+	// Without a TTL the result is dropped once the flight lands.
+	g.Do("k", func() (int, error) { calls.Add(1); return 0, nil })
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("fn ran %d times, want 2", n)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	g := Group[string, int]{TTL: 30 * time.Millisecond}
+	calls := 0
+	fn := func() (int, error) { calls++; return calls, nil }
+
+	g.Do("k", fn)
+	if v, _, shared := g.Do("k", fn); v != 1 || !shared {
+		t.Fatalf("fresh result not reused: %d, %v", v, shared)
+	}
+	g.Forget("k")
+	if v, _, _ := g.Do("k", fn); v != 2 {
+		t.Fatalf("Forget did not drop the result: got %d", v)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if v, _, _ := g.Do("k", fn); v != 3 {
+		t.Fatalf("expired result reused: got %d", v)
+	}
+
+	errFail := errors.New("fail")
+	g.Do("e", func() (int, error) { return 0, errFail })
+	if _, err, _ := g.Do("e", func() (int, error) { return 1, nil }); err != nil {
+		t.Fatal("failed flight was retained")
+	}
+
+	// Expired results are evicted even if their key is never asked for again.
+	time.Sleep(250 * time.Millisecond)
+	g.mu.Lock()
+	n := len(g.calls)
+	g.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d expired results still retained", n)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var g Group[string, int]
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic not propagated")
+			}
+		}()
+		g.Do("k", func() (int, error) { panic("boom") })
+	}()
+	if v, err, _ := g.Do("k", func() (int, error) { return 1, nil }); v != 1 || err != nil {
+		t.Fatalf("Do after panic = %d, %v", v, err)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var (
+		q       Queue[string, int]
+		mu      sync.Mutex
+		order   []int
+		running atomic.Int32
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q.Do("k", func() (int, error) {
+				if running.Add(1) != 1 {
+					t.Error("calls for one key overlapped")
+				}
+				time.Sleep(2 * time.Millisecond)
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				running.Add(-1)
+				return i, nil
+			})
+		}(i)
+		time.Sleep(time.Millisecond) // arrive in order
+	}
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("ran in order %v, want arrival order", order)
+		}
+	}
+	if n := len(q.queues); n != 0 {
+		t.Fatalf("%d idle queues kept", n)
+	}
+}
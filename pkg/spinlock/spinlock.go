@@ -0,0 +1,89 @@
+// Package spinlock provides a test-and-test-and-set spinlock with exponential
+// backoff, for critical sections so short that parking costs more than waiting.
+package spinlock
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Backoff tunes how a waiting Lock spins. Zero fields take the defaults.
+type Backoff struct {
+	// Min and Max bound the number of pause iterations between attempts;
+	// the pause doubles after each failed attempt. Defaults 4 and 1024.
+	Min, Max int
+	// YieldAfter is how many failed attempts pass before each further attempt
+	// yields the processor first; negative never yields. Default 16.
+	YieldAfter int
+}
+
+// Mutex is a spinlock. It never parks, so it must only guard critical
+// sections much shorter than a scheduler quantum. The zero value is an
+// unlocked spinlock with the default backoff.
+type Mutex struct {
+	locked atomic.Bool
+	cfg    Backoff
+}
+
+var _ sync.Locker = (*Mutex)(nil)
+
+// New returns an unlocked spinlock with the given backoff.
+func New(cfg Backoff) *Mutex {
+	return &Mutex{cfg: cfg}
+}
+
+// Lock spins until it acquires m.
+func (m *Mutex) Lock() {
+	if m.locked.CompareAndSwap(false, true) {
+		return
+	}
+	m.lockSlow()
+}
+
+func (m *Mutex) lockSlow() {
+	lo, hi, yieldAfter := m.cfg.Min, m.cfg.Max, m.cfg.YieldAfter
+	if lo <= 0 {
+		lo = 4
+	}
+	if hi <= 0 {
+		hi = 1024
+	}
+	if yieldAfter == 0 {
+		yieldAfter = 16
+	}
+
+	pause := lo
+	for attempt := 1; ; attempt++ {
+		if yieldAfter > 0 && attempt > yieldAfter {
+			runtime.Gosched()
+		} else {
+			for i := 0; i < pause; i++ {
+				spinPause()
+			}
+			pause = min(pause*2, hi)
+		}
+		// Read before writing, so waiters spin on a shared cache line.
+		if !m.locked.Load() && m.locked.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+// TryLock acquires m if it is free and reports whether it did.
+func (m *Mutex) TryLock() bool {
+	return m.locked.CompareAndSwap(false, true)
+}
+
+// Unlock releases m.
+func (m *Mutex) Unlock() {
+	if !m.locked.Swap(false) {
+		panic("spinlock: unlock of unlocked mutex")
+	}
+}
+
+// spinPause burns a few cycles between attempts; it is kept out of line so
+// the pause loop isn't optimized away.
+//
+//go:noinline
+func spinPause() {}
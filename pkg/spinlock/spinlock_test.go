@@ -0,0 +1,58 @@
+package spinlock
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMutualExclusion(t *testing.T) {
+	for _, m := range []*Mutex{new(Mutex), New(Backoff{Min: 1, Max: 2, YieldAfter: -1}), New(Backoff{YieldAfter: 1})} {
+		var (
+			wg      sync.WaitGroup
+			counter int
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					m.Lock()
+					counter++
+					m.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if counter != 8000 {
+			t.Fatalf("counter %d with %+v", counter, m.cfg)
+		}
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	var m Mutex
+	if !m.TryLock() || m.TryLock() {
+		t.Fatal("TryLock")
+	}
+	m.Unlock()
+}
+
+func BenchmarkSpinlock(b *testing.B) {
+	var m Mutex
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Lock()
+			m.Unlock()
+		}
+	})
+}
+
+func BenchmarkStdMutex(b *testing.B) {
+	var m sync.Mutex
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Lock()
+			m.Unlock()
+		}
+	})
+}
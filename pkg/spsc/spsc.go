@@ -0,0 +1,162 @@
+// Package spsc provides a bounded single-producer single-consumer ring for
+// hand-offs where a channel's locking is too slow.
+package spsc
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+// spinTries is how many times a blocking call retries, yielding in between,
+// before it parks.
+const spinTries = 64
+
+// pad keeps fields written by different sides on separate cache lines.
+type pad [64]byte
+
+// Ring is a bounded SPSC queue. Push and TryPush may be called from one
+// goroutine at a time, Pop and TryPop from one other.
+type Ring[T any] struct {
+	buf  []T
+	mask uint64
+
+	_         pad
+	tail      atomic.Uint64 // next slot to write, advanced by the producer
+	headCache uint64        // producer's last view of head
+	_         pad
+	head      atomic.Uint64 // next slot to read, advanced by the consumer
+	tailCache uint64        // consumer's last view of tail
+	_         pad
+
+	pwait, cwait      atomic.Bool // a side is parked or about to park
+	notFull, notEmpty chan struct{}
+}
+
+// New returns a ring holding at least size elements, rounded up to a power
+// of two.
+func New[T any](size int) *Ring[T] {
+	if size <= 0 {
+		panic("spsc: New called with non-positive size")
+	}
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &Ring[T]{
+		buf:      make([]T, n),
+		mask:     uint64(n - 1),
+		notFull:  make(chan struct{}, 1),
+		notEmpty: make(chan struct{}, 1),
+	}
+}
+
+// TryPush appends v and reports true, or reports false if the ring is full.
+func (r *Ring[T]) TryPush(v T) bool {
+	t := r.tail.Load()
+	if t-r.headCache == uint64(len(r.buf)) {
+		r.headCache = r.head.Load()
+		if t-r.headCache == uint64(len(r.buf)) {
+			return false
+		}
+	}
+	r.buf[t&r.mask] = v
+	r.tail.Store(t + 1)
+	if r.cwait.Load() {
+		wake(r.notEmpty)
+	}
+	return true
+}
+
+// TryPop removes and returns the oldest element, or reports false if the
+// ring is empty.
+func (r *Ring[T]) TryPop() (T, bool) {
+	var zero T
+	h := r.head.Load()
+	if h == r.tailCache {
+		r.tailCache = r.tail.Load()
+		if h == r.tailCache {
+			return zero, false
+		}
+	}
+	i := h & r.mask
+	v := r.buf[i]
+	r.buf[i] = zero // don't pin popped values
+	r.head.Store(h + 1)
+	if r.pwait.Load() {
+		wake(r.notFull)
+	}
+	return v, true
+}
+
+// Push appends v, spinning briefly and then parking while the ring is full,
+// until there is room or ctx is done.
+func (r *Ring[T]) Push(ctx context.Context, v T) error {
+	for i := 0; ; i++ {
+		if r.TryPush(v) {
+			return nil
+		}
+		if i < spinTries {
+			runtime.Gosched()
+			continue
+		}
+		if err := park(ctx, &r.pwait, r.notFull, func() bool { return !r.full() }); err != nil {
+			return err
+		}
+	}
+}
+
+// Pop removes the oldest element, spinning briefly and then parking while
+// the ring is empty, until there is one or ctx is done.
+func (r *Ring[T]) Pop(ctx context.Context) (T, error) {
+	for i := 0; ; i++ {
+		if v, ok := r.TryPop(); ok {
+			return v, nil
+		}
+		if i < spinTries {
+			runtime.Gosched()
+			continue
+		}
+		if err := park(ctx, &r.cwait, r.notEmpty, func() bool { return r.Len() > 0 }); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+}
+
+// park blocks on ch until the other side wakes it or ctx is done. The flag
+// is raised before ready is rechecked, so a wake-up can't slip in between;
+// stale wake-ups only cost the caller another try.
+func park(ctx context.Context, flag *atomic.Bool, ch chan struct{}, ready func() bool) error {
+	flag.Store(true)
+	defer flag.Store(false)
+	if ready() {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Ring[T]) full() bool {
+	return r.tail.Load()-r.head.Load() == uint64(len(r.buf))
+}
+
+// Len returns the number of elements in the ring.
+func (r *Ring[T]) Len() int {
+	h := r.head.Load()
+	return int(r.tail.Load() - h)
+}
+
+// Cap returns the ring's capacity.
+func (r *Ring[T]) Cap() int { return len(r.buf) }
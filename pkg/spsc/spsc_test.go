@@ -0,0 +1,91 @@
+package spsc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTry(t *testing.T) {
+	r := New[int](3)
+	if r.Cap() != 4 {
+		t.Fatalf("Cap = %d, want 4", r.Cap())
+	}
+	for i := 0; i < 4; i++ {
+		if !r.TryPush(i) {
+			t.Fatalf("TryPush %d failed", i)
+		}
+	}
+	if r.TryPush(4) || r.Len() != 4 {
+		t.Fatalf("TryPush into full ring, len %d", r.Len())
+	}
+	for i := 0; i < 4; i++ {
+		if v, ok := r.TryPop(); !ok || v != i {
+			t.Fatalf("TryPop = %d, %v; want %d", v, ok, i)
+		}
+	}
+	if _, ok := r.TryPop(); ok {
+		t.Fatal("TryPop from empty ring")
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	const n = 10000
+	r := New[int](8)
+	ctx := context.Background()
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := r.Push(ctx, i); err != nil {
+				t.Error(err)
+				return
+			}
+			if i%1000 == 0 {
+				time.Sleep(time.Millisecond) // let the consumer park
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		v, err := r.Pop(ctx)
+		if err != nil || v != i {
+			t.Fatalf("Pop = %d, %v; want %d", v, err, i)
+		}
+	}
+
+	short, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := r.Pop(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Pop from empty ring = %v", err)
+	}
+}
+
+func BenchmarkRing(b *testing.B) {
+	r := New[int](1024)
+	ctx := context.Background()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			r.Pop(ctx)
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		r.Push(ctx, i)
+	}
+	<-done
+}
+
+func BenchmarkChannel(b *testing.B) {
+	ch := make(chan int, 1024)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			<-ch
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		ch <- i
+	}
+	<-done
+}
@@ -0,0 +1,243 @@
+// Package startup initializes an application's components concurrently,
+// each after the ones it depends on, and rolls back on failure.
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrDuplicate is returned when adding a name already in use.
+	ErrDuplicate = errors.New("startup: duplicate component name")
+	// ErrUnknownDependency is returned by Start when a component names a
+	// dependency that was never added.
+	ErrUnknownDependency = errors.New("startup: unknown dependency")
+	// ErrCycle is returned by Start when dependencies form a cycle.
+	ErrCycle = errors.New("startup: dependency cycle")
+	// ErrStarted is returned when adding to or starting an orchestrator
+	// that has already been started.
+	ErrStarted = errors.New("startup: already started")
+)
+
+// Component is something to initialize at startup.
+type Component struct {
+	Name string
+	// After names components whose Init must succeed before this one's starts.
+	After []string
+	// Init initializes the component.
+	Init func(ctx context.Context) error
+	// Rollback, if set, undoes a successful Init when startup fails.
+	Rollback func(ctx context.Context) error
+}
+
+// InitError is the failure of one component's Init or Rollback.
+type InitError struct {
+	Name     string
+	Rollback bool
+	Err      error
+}
+
+func (e *InitError) Error() string {
+	op := "initializing"
+	if e.Rollback {
+		op = "rolling back"
+	}
+	return fmt.Sprintf("startup: %s %q: %v", op, e.Name, e.Err)
+}
+
+func (e *InitError) Unwrap() error { return e.Err }
+
+// Orchestrator runs the Init functions of its components, as many at once
+// as their ordering allows.
+type Orchestrator struct {
+	mu      sync.Mutex
+	comps   []Component
+	index   map[string]int
+	started bool
+	done    []string
+}
+
+// Add adds comp to the components to initialize.
+func (o *Orchestrator) Add(comp Component) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.started {
+		return ErrStarted
+	}
+	if _, dup := o.index[comp.Name]; dup {
+		return fmt.Errorf("%w: %q", ErrDuplicate, comp.Name)
+	}
+	if o.index == nil {
+		o.index = make(map[string]int)
+	}
+	o.index[comp.Name] = len(o.comps)
+	o.comps = append(o.comps, comp)
+	return nil
+}
+
+// Start initializes every component, each as soon as the components it
+// comes after have succeeded. It checks the ordering first and returns
+// ErrUnknownDependency or ErrCycle without initializing anything.
+//
+// If an Init fails or ctx is done, no further Inits start, the running ones
+// are waited for, and every component initialized so far is rolled back, in
+// the reverse of the order their Inits returned. Rollbacks run under ctx
+// without its cancellation. Start then returns the joined *InitErrors, or
+// ctx.Err() if ctx ended startup.
+func (o *Orchestrator) Start(ctx context.Context) error {
+	o.mu.Lock()
+	if o.started {
+		o.mu.Unlock()
+		return ErrStarted
+	}
+	o.started = true
+	o.mu.Unlock()
+
+	// Nothing else touches comps or index once started.
+	deps, dependents, err := o.graph()
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result)
+	initCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := func(i int) {
+		go func() {
+			var err error
+			if init := o.comps[i].Init; init != nil {
+				err = init(initCtx)
+			}
+			results <- result{i, err}
+		}()
+	}
+	running := 0
+	for i, n := range deps {
+		if n == 0 {
+			run(i)
+			running++
+		}
+	}
+
+	var (
+		done   []int
+		errs   []error
+		failed bool
+	)
+	for running > 0 {
+		var r result
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			failed = true
+			r = <-results
+		}
+		running--
+		if r.err != nil {
+			errs = append(errs, &InitError{Name: o.comps[r.i].Name, Err: r.err})
+			failed = true
+			cancel()
+			continue
+		}
+		done = append(done, r.i)
+		if failed {
+			continue
+		}
+		for _, d := range dependents[r.i] {
+			if deps[d]--; deps[d] == 0 {
+				run(d)
+				running++
+			}
+		}
+	}
+	if !failed && ctx.Err() != nil {
+		failed = true
+	}
+
+	if !failed {
+		o.mu.Lock()
+		for _, i := range done {
+			o.done = append(o.done, o.comps[i].Name)
+		}
+		o.mu.Unlock()
+		return nil
+	}
+
+	rctx := context.WithoutCancel(ctx)
+	for j := len(done) - 1; j >= 0; j-- {
+		c := o.comps[done[j]]
+		if c.Rollback == nil {
+			continue
+		}
+		if err := c.Rollback(rctx); err != nil {
+			errs = append(errs, &InitError{Name: c.Name, Rollback: true, Err: err})
+		}
+	}
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// graph returns each component's number of dependencies and the components
+// depending on it, checking that the dependencies exist and are acyclic.
+func (o *Orchestrator) graph() (deps []int, dependents [][]int, err error) {
+	deps = make([]int, len(o.comps))
+	dependents = make([][]int, len(o.comps))
+	for i, c := range o.comps {
+		for _, name := range c.After {
+			j, ok := o.index[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: %q needs %q", ErrUnknownDependency, c.Name, name)
+			}
+			deps[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	// Kahn's algorithm: anything never freed is on or behind a cycle.
+	left := append([]int(nil), deps...)
+	var ready []int
+	for i, n := range left {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+	seen := 0
+	for len(ready) > 0 {
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		seen++
+		for _, d := range dependents[i] {
+			if left[d]--; left[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if seen < len(o.comps) {
+		for i, n := range left {
+			if n > 0 {
+				return nil, nil, fmt.Errorf("%w through %q", ErrCycle, o.comps[i].Name)
+			}
+		}
+	}
+	return deps, dependents, nil
+}
+
+// Started returns the names of the components initialized by a successful
+// Start, in the order their Inits returned. Stopping them in reverse, for
+// example by registering them with a shutdown coordinator in this order,
+// keeps every component up while those initialized after it run.
+func (o *Orchestrator) Started() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.done...)
+}
@@ -0,0 +1,121 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrdering(t *testing.T) {
+	var (
+		o   Orchestrator
+		mu  sync.Mutex
+		log []string
+		cur atomic.Int32
+		max atomic.Int32
+	)
+	init := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			n := cur.Add(1)
+			for {
+				m := max.Load()
+				if n <= m || max.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			cur.Add(-1)
+			mu.Lock()
+			log = append(log, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	must(t, o.Add(Component{Name: "app", After: []string{"db", "cache"}, Init: init("app")}))
+	must(t, o.Add(Component{Name: "db", After: []string{"config"}, Init: init("db")}))
+	must(t, o.Add(Component{Name: "cache", After: []string{"config"}, Init: init("cache")}))
+	must(t, o.Add(Component{Name: "config", Init: init("config")}))
+
+	if err := o.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pos := func(name string) int { return slices.Index(log, name) }
+	if pos("config") != 0 || pos("app") != 3 {
+		t.Fatalf("init order %v breaks dependencies", log)
+	}
+	if max.Load() < 2 {
+		t.Fatal("independent components were not initialized concurrently")
+	}
+	if got := o.Started(); !slices.Equal(got, log) {
+		t.Fatalf("Started() = %v, want %v", got, log)
+	}
+	if err := o.Start(context.Background()); err != ErrStarted {
+		t.Fatalf("second Start = %v, want ErrStarted", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	var (
+		o          Orchestrator
+		mu         sync.Mutex
+		rolledBack []string
+	)
+	boom := errors.New("boom")
+	rollback := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			rolledBack = append(rolledBack, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	ok := func(context.Context) error { return nil }
+	must(t, o.Add(Component{Name: "a", Init: ok, Rollback: rollback("a")}))
+	must(t, o.Add(Component{Name: "b", After: []string{"a"}, Init: ok, Rollback: rollback("b")}))
+	must(t, o.Add(Component{Name: "c", After: []string{"b"}, Init: func(context.Context) error { return boom }}))
+	must(t, o.Add(Component{Name: "d", After: []string{"c"}, Init: func(context.Context) error {
+		t.Error("component after a failure was initialized")
+		return nil
+	}}))
+
+	err := o.Start(context.Background())
+	var ie *InitError
+	if !errors.Is(err, boom) || !errors.As(err, &ie) || ie.Name != "c" {
+		t.Fatalf("Start = %v, want c's failure", err)
+	}
+	if want := []string{"b", "a"}; !slices.Equal(rolledBack, want) {
+		t.Fatalf("rolled back %v, want %v", rolledBack, want)
+	}
+	if len(o.Started()) != 0 {
+		t.Fatal("Started() not empty after a failed Start")
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	var o Orchestrator
+	must(t, o.Add(Component{Name: "a", After: []string{"b"}}))
+	must(t, o.Add(Component{Name: "b", After: []string{"a"}}))
+	if err := o.Add(Component{Name: "a"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Add = %v, want ErrDuplicate", err)
+	}
+	if err := o.Start(context.Background()); !errors.Is(err, ErrCycle) {
+		t.Fatalf("Start = %v, want ErrCycle", err)
+	}
+
+	var o2 Orchestrator
+	must(t, o2.Add(Component{Name: "a", After: []string{"missing"}}))
+	if err := o2.Start(context.Background()); !errors.Is(err, ErrUnknownDependency) {
+		t.Fatalf("Start = %v, want ErrUnknownDependency", err)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
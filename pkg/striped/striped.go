@@ -0,0 +1,84 @@
+// Package striped maps an unbounded key space onto a fixed set of locks, so
+// memory stays bounded however many keys there are. Keys sharing a stripe
+// contend with each other; size the set for the expected concurrency.
+package striped
+
+import (
+	"hash/maphash"
+	"slices"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/ordermutex"
+)
+
+// Mutex is a striped set of mutexes for keys of type K.
+type Mutex[K comparable] struct {
+	seed    maphash.Seed
+	stripes []paddedMutex
+}
+
+// paddedMutex keeps neighbouring stripes on separate cache lines.
+type paddedMutex struct {
+	sync.Mutex
+	_ [64 - 8]byte
+}
+
+// New returns a set of n stripes.
+func New[K comparable](n int) *Mutex[K] {
+	if n <= 0 {
+		panic("striped: New called with non-positive n")
+	}
+	return &Mutex[K]{seed: maphash.MakeSeed(), stripes: make([]paddedMutex, n)}
+}
+
+// For returns the lock of key's stripe. Equal keys always get the same lock.
+func (m *Mutex[K]) For(key K) *sync.Mutex {
+	return &m.stripes[stripe(m.seed, key, len(m.stripes))].Mutex
+}
+
+// LockAll locks the stripes of all keys, each once and in stripe order, so
+// concurrent LockAll calls can't deadlock; it returns a func unlocking them.
+func (m *Mutex[K]) LockAll(keys ...K) (unlock func()) {
+	idx := make([]int, len(keys))
+	for i, k := range keys {
+		idx[i] = stripe(m.seed, k, len(m.stripes))
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	for _, i := range idx {
+		m.stripes[i].Lock()
+	}
+	return func() {
+		for _, i := range slices.Backward(idx) {
+			m.stripes[i].Unlock()
+		}
+	}
+}
+
+// Ordered is a striped set of ordered mutexes: callers working on keys that
+// share a stripe are served in the order they took their tickets.
+type Ordered[K comparable] struct {
+	seed    maphash.Seed
+	stripes []*ordermutex.Mutex
+}
+
+// NewOrdered returns a set of n ordered stripes built with opts.
+func NewOrdered[K comparable](n int, opts ...ordermutex.Option) *Ordered[K] {
+	if n <= 0 {
+		panic("striped: NewOrdered called with non-positive n")
+	}
+	o := &Ordered[K]{seed: maphash.MakeSeed(), stripes: make([]*ordermutex.Mutex, n)}
+	for i := range o.stripes {
+		o.stripes[i] = ordermutex.NewMutex(opts...)
+	}
+	return o
+}
+
+// For returns the ordered mutex of key's stripe.
+func (o *Ordered[K]) For(key K) *ordermutex.Mutex {
+	return o.stripes[stripe(o.seed, key, len(o.stripes))]
+}
+
+func stripe[K comparable](seed maphash.Seed, key K, n int) int {
+	return int(maphash.Comparable(seed, key) % uint64(n))
+}
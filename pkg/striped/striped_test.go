@@ -0,0 +1,77 @@
+package striped
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	m := New[string](8)
+	if m.For("a") != m.For("a") {
+		t.Fatal("equal keys got different stripes")
+	}
+
+	type key struct {
+		tenant string
+		id     int
+	}
+	km := New[key](4)
+	var (
+		counts [2]int
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l := km.For(key{"t", id})
+				l.Lock()
+				counts[id]++
+				l.Unlock()
+			}
+		}(i % 2)
+	}
+	wg.Wait()
+	if counts != [2]int{1600, 1600} {
+		t.Fatalf("counts %v", counts)
+	}
+}
+
+func TestLockAll(t *testing.T) {
+	m := New[int](4)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// Opposite key orders would deadlock without stripe ordering.
+				keys := []int{i, i + 1, i + 2, i}
+				if i%2 == 1 {
+					keys = []int{i + 2, i + 1, i}
+				}
+				unlock := m.LockAll(keys...)
+				unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestOrdered(t *testing.T) {
+	o := NewOrdered[string](4)
+	m := o.For("k")
+	t0 := m.GetTicket()
+	t1 := m.GetTicket()
+
+	done := make(chan struct{})
+	go func() {
+		m.Lock(t1)
+		m.Unlock(t1)
+		close(done)
+	}()
+	m.Lock(t0)
+	m.Unlock(t0)
+	<-done
+}
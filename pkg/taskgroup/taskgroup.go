@@ -0,0 +1,130 @@
+// Package taskgroup runs tasks concurrently and collects their results and
+// errors in submission order.
+package taskgroup
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Config configures a Group. The zero value runs every task at once and
+// never cancels.
+type Config struct {
+	// Limit, if positive, caps the tasks running at once; Go blocks while
+	// the group is full.
+	Limit int
+	// CancelOnError cancels the group's context when a task fails. Tasks not
+	// started by then are skipped and report the context's error.
+	CancelOnError bool
+}
+
+// Group is a collection of tasks producing values of type T.
+type Group[T any] struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	cfg    Config
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	results []T
+	errs    Errors
+}
+
+// New returns a group and a context derived from ctx, canceled when a task
+// fails under CancelOnError or when Wait returns.
+func New[T any](ctx context.Context, cfg Config) (*Group[T], context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Group[T]{ctx: ctx, cancel: cancel, cfg: cfg}
+	if cfg.Limit > 0 {
+		g.sem = make(chan struct{}, cfg.Limit)
+	}
+	return g, ctx
+}
+
+// Go runs f in a new goroutine; its result and error are stored at the
+// index of this call. Go must not be called after Wait.
+func (g *Group[T]) Go(f func() (T, error)) {
+	g.mu.Lock()
+	i := len(g.results)
+	var zero T
+	g.results = append(g.results, zero)
+	g.errs = append(g.errs, nil)
+	g.mu.Unlock()
+
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.skip():
+			g.store(i, zero, context.Cause(g.ctx))
+			return
+		}
+	}
+	if g.cfg.CancelOnError && g.ctx.Err() != nil {
+		g.release()
+		g.store(i, zero, context.Cause(g.ctx))
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer g.release()
+		v, err := f()
+		g.store(i, v, err)
+		if err != nil && g.cfg.CancelOnError {
+			g.cancel(err)
+		}
+	}()
+}
+
+// skip returns a channel closed when pending tasks should be skipped.
+func (g *Group[T]) skip() <-chan struct{} {
+	if g.cfg.CancelOnError {
+		return g.ctx.Done()
+	}
+	return nil
+}
+
+func (g *Group[T]) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
+
+func (g *Group[T]) store(i int, v T, err error) {
+	g.mu.Lock()
+	g.results[i] = v
+	g.errs[i] = err
+	g.mu.Unlock()
+}
+
+// Wait waits for every task and returns their results and errors, each
+// indexed by submission order. A failed task's result is whatever it returned.
+func (g *Group[T]) Wait() ([]T, Errors) {
+	g.wg.Wait()
+	g.cancel(context.Canceled)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.results, g.errs
+}
+
+// Errors holds one error per task, nil for tasks that succeeded.
+type Errors []error
+
+// Err joins the non-nil errors, or returns nil if every task succeeded.
+func (e Errors) Err() error {
+	return errors.Join(e...)
+}
+
+// First returns the index and error of the first failed task in submission
+// order, or -1 and nil.
+func (e Errors) First() (int, error) {
+	for i, err := range e {
+		if err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
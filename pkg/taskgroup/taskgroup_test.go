@@ -0,0 +1,75 @@
+package taskgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	g, _ := New[int](context.Background(), Config{})
+	boom := errors.New("boom")
+	for i := 0; i < 5; i++ {
+		g.Go(func() (int, error) {
+			// Later tasks finish first.
+			time.Sleep(time.Duration(5-i) * time.Millisecond)
+			if i == 3 {
+				return -1, boom
+			}
+			return i * i, nil
+		})
+	}
+	res, errs := g.Wait()
+	if got := fmt.Sprint(res); got != "[0 1 4 -1 16]" {
+		t.Fatalf("results %s", got)
+	}
+	if i, err := errs.First(); i != 3 || err != boom {
+		t.Fatalf("First = %d, %v", i, err)
+	}
+	if !errors.Is(errs.Err(), boom) {
+		t.Fatalf("Err = %v", errs.Err())
+	}
+}
+
+func TestLimit(t *testing.T) {
+	g, _ := New[struct{}](context.Background(), Config{Limit: 2})
+	var running, peak atomic.Int32
+	for i := 0; i < 8; i++ {
+		g.Go(func() (struct{}, error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			running.Add(-1)
+			return struct{}{}, nil
+		})
+	}
+	_, errs := g.Wait()
+	if errs.Err() != nil || peak.Load() != 2 {
+		t.Fatalf("peak %d, err %v", peak.Load(), errs.Err())
+	}
+}
+
+func TestCancelOnError(t *testing.T) {
+	g, ctx := New[int](context.Background(), Config{Limit: 1, CancelOnError: true})
+	boom := errors.New("boom")
+	g.Go(func() (int, error) { return 0, boom })
+	g.Go(func() (int, error) {
+		t.Error("task ran after the group was canceled")
+		return 0, nil
+	})
+	_, errs := g.Wait()
+	if errs[0] != boom || !errors.Is(errs[1], boom) {
+		t.Fatalf("errors %v", errs)
+	}
+	if context.Cause(ctx) != boom {
+		t.Fatalf("cause %v", context.Cause(ctx))
+	}
+}
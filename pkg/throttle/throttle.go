@@ -0,0 +1,149 @@
+// Package throttle limits a function to one invocation per interval,
+// folding the calls in between into a trailing invocation.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Option configures a Throttler.
+type Option[T any] func(*Throttler[T])
+
+// WithLeading sets whether the first call of a quiet period invokes the
+// target at once; default true.
+func WithLeading[T any](on bool) Option[T] {
+	return func(t *Throttler[T]) { t.leading = on }
+}
+
+// WithTrailing sets whether calls made during an interval invoke the target
+// once it ends; default true. Without it those calls are dropped.
+func WithTrailing[T any](on bool) Option[T] {
+	return func(t *Throttler[T]) { t.trailing = on }
+}
+
+// WithMerge sets how a call's value is folded into the pending trailing
+// value; the default keeps the latest.
+func WithMerge[T any](merge func(pending, v T) T) Option[T] {
+	return func(t *Throttler[T]) { t.merge = merge }
+}
+
+// Throttler invokes its target at most once per interval. Target calls never
+// overlap.
+type Throttler[T any] struct {
+	interval          time.Duration
+	fn                func(T)
+	leading, trailing bool
+	merge             func(pending, v T) T
+
+	mu      sync.Mutex
+	open    bool // an interval is running
+	pending bool
+	v       T
+	timer   *time.Timer
+	gen     uint64 // identifies the live timer
+
+	callMu sync.Mutex // serializes fn
+}
+
+// New returns a throttler invoking fn at most once per interval.
+func New[T any](interval time.Duration, fn func(T), opts ...Option[T]) *Throttler[T] {
+	if interval <= 0 {
+		panic("throttle: New called with non-positive interval")
+	}
+	t := &Throttler[T]{interval: interval, fn: fn, leading: true, trailing: true}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Call invokes the target with v now if allowed, or folds v into the
+// trailing invocation.
+func (t *Throttler[T]) Call(v T) {
+	t.mu.Lock()
+	if !t.open {
+		t.start()
+		if t.leading {
+			t.invoke(v)
+			return
+		}
+	}
+	if t.pending && t.merge != nil {
+		v = t.merge(t.v, v)
+	}
+	t.v, t.pending = v, true
+	t.mu.Unlock()
+}
+
+// start opens an interval; mu must be held.
+func (t *Throttler[T]) start() {
+	t.open = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.gen++
+	gen := t.gen
+	t.timer = time.AfterFunc(t.interval, func() { t.tick(gen) })
+}
+
+// invoke calls the target with v, releasing mu first.
+func (t *Throttler[T]) invoke(v T) {
+	// Take callMu before dropping mu so invocations keep their order.
+	t.callMu.Lock()
+	t.mu.Unlock()
+	defer t.callMu.Unlock()
+	t.fn(v)
+}
+
+// tick ends an interval, invoking the trailing call, which opens the next one.
+func (t *Throttler[T]) tick(gen uint64) {
+	t.mu.Lock()
+	if !t.open || gen != t.gen {
+		t.mu.Unlock()
+		return
+	}
+	if !t.pending || !t.trailing {
+		t.open = false
+		t.clear()
+		t.mu.Unlock()
+		return
+	}
+	v := t.clear()
+	t.start()
+	t.invoke(v)
+}
+
+// clear drops the pending value and returns it; mu must be held.
+func (t *Throttler[T]) clear() T {
+	v := t.v
+	var zero T
+	t.v, t.pending = zero, false
+	return v
+}
+
+// Flush invokes the target now with the pending value, if any, and reports
+// whether there was one. The next interval starts from the flush.
+func (t *Throttler[T]) Flush() bool {
+	t.mu.Lock()
+	if !t.pending {
+		t.mu.Unlock()
+		return false
+	}
+	v := t.clear()
+	t.start()
+	t.invoke(v)
+	return true
+}
+
+// Cancel drops the pending value and ends the current interval.
+func (t *Throttler[T]) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.gen++
+	t.open = false
+	t.clear()
+}
@@ -0,0 +1,84 @@
+package throttle
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (r *recorder) fn(v int) {
+	r.mu.Lock()
+	r.got = append(r.got, v)
+	r.mu.Unlock()
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.got)
+}
+
+func TestLeadingTrailing(t *testing.T) {
+	var r recorder
+	th := New(20*time.Millisecond, r.fn, WithMerge(func(sum, v int) int { return sum + v }))
+	for i := 1; i <= 4; i++ {
+		th.Call(i)
+	}
+	if r.String() != "[1]" {
+		t.Fatalf("leading call: %s", r.String())
+	}
+	// 2+3+4 are summarized into the trailing call.
+	time.Sleep(30 * time.Millisecond)
+	if r.String() != "[1 9]" {
+		t.Fatalf("trailing call: %s", r.String())
+	}
+	// The trailing call opened an interval; a quiet one then closes it.
+	time.Sleep(30 * time.Millisecond)
+	th.Call(5)
+	if r.String() != "[1 9 5]" {
+		t.Fatalf("after quiet interval: %s", r.String())
+	}
+	th.Cancel()
+}
+
+func TestNoLeading(t *testing.T) {
+	var r recorder
+	th := New(10*time.Millisecond, r.fn, WithLeading[int](false))
+	th.Call(1)
+	th.Call(2)
+	if r.String() != "[]" {
+		t.Fatalf("called without leading: %s", r.String())
+	}
+	time.Sleep(20 * time.Millisecond)
+	if r.String() != "[2]" {
+		t.Fatalf("trailing call: %s", r.String())
+	}
+}
+
+func TestNoTrailingFlush(t *testing.T) {
+	var r recorder
+	th := New(10*time.Millisecond, r.fn, WithTrailing[int](false))
+	th.Call(1)
+	th.Call(2)
+	time.Sleep(20 * time.Millisecond)
+	if r.String() != "[1]" {
+		t.Fatalf("calls %s, want the second dropped", r.String())
+	}
+
+	th = New(time.Hour, r.fn)
+	th.Call(3)
+	th.Call(4)
+	if !th.Flush() || th.Flush() {
+		t.Fatal("Flush reported the wrong pending state")
+	}
+	if r.String() != "[1 3 4]" {
+		t.Fatalf("calls %s", r.String())
+	}
+	th.Cancel()
+}
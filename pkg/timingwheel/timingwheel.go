@@ -0,0 +1,147 @@
+// Package timingwheel provides a hashed timing wheel for large numbers of
+// coarse timeouts, such as idle connections and lease expiries.
+package timingwheel
+
+import (
+	"sync"
+	"time"
+)
+
+// Wheel schedules callbacks on a ring of slots advanced once per tick.
+// Scheduling and stopping a timer are O(1) whatever the number pending;
+// a timer fires on a tick boundary, within one tick of its delay. The wheel's goroutine runs only while timers are pending.
+type Wheel struct {
+	tick time.Duration
+
+	mu      sync.Mutex
+	slots   []Timer // sentinels of circular lists
+	cursor  int
+	n       int
+	running bool
+}
+
+// Timer is a callback scheduled on a Wheel.
+type Timer struct {
+	w          *Wheel
+	f          func()
+	slot       int
+	rounds     int // full turns of the wheel left before firing
+	prev, next *Timer
+}
+
+// New returns a wheel of slots slots advancing every tick; a timer of delay
+// d costs a pass per d/(tick*slots) turns, so slots should cover the
+// common delays.
+func New(tick time.Duration, slots int) *Wheel {
+	if tick <= 0 || slots <= 0 {
+		panic("timingwheel: New called with non-positive tick or slots")
+	}
+	w := &Wheel{tick: tick, slots: make([]Timer, slots)}
+	for i := range w.slots {
+		s := &w.slots[i]
+		s.prev, s.next = s, s
+	}
+	return w
+}
+
+// AfterFunc schedules f to run in its own goroutine after d.
+func (w *Wheel) AfterFunc(d time.Duration, f func()) *Timer {
+	t := &Timer{w: w, f: f}
+	w.mu.Lock()
+	w.add(t, d)
+	w.mu.Unlock()
+	return t
+}
+
+// add links t to fire after d; mu must be held.
+func (w *Wheel) add(t *Timer, d time.Duration) {
+	ticks := max(int((d+w.tick-1)/w.tick), 1)
+	t.slot = (w.cursor + ticks) % len(w.slots)
+	t.rounds = (ticks - 1) / len(w.slots)
+
+	s := &w.slots[t.slot]
+	t.prev, t.next = s.prev, s
+	s.prev.next = t
+	s.prev = t
+
+	w.n++
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+}
+
+// unlink removes t from its slot; mu must be held.
+func (w *Wheel) unlink(t *Timer) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next = nil, nil
+	w.n--
+}
+
+// Stop cancels t and reports whether it was pending.
+func (t *Timer) Stop() bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.next == nil {
+		return false
+	}
+	w.unlink(t)
+	return true
+}
+
+// Reset reschedules t to fire after d and reports whether it was pending.
+func (t *Timer) Reset(d time.Duration) bool {
+	w := t.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := t.next != nil
+	if pending {
+		w.unlink(t)
+	}
+	w.add(t, d)
+	return pending
+}
+
+// Len returns the number of pending timers.
+func (w *Wheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.n
+}
+
+func (w *Wheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	var due []func()
+	for range ticker.C {
+		w.mu.Lock()
+		w.cursor = (w.cursor + 1) % len(w.slots)
+		s := &w.slots[w.cursor]
+		for t := s.next; t != s; {
+			next := t.next
+			if t.rounds > 0 {
+				t.rounds--
+			} else {
+				w.unlink(t)
+				due = append(due, t.f)
+			}
+			t = next
+		}
+		idle := w.n == 0
+		if idle {
+			w.running = false
+		}
+		w.mu.Unlock()
+
+		for i, f := range due {
+			go f()
+			due[i] = nil
+		}
+		due = due[:0]
+		if idle {
+			return
+		}
+	}
+}
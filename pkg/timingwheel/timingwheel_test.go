@@ -0,0 +1,63 @@
+package timingwheel
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFire(t *testing.T) {
+	w := New(time.Millisecond, 8)
+	fired := make(chan time.Duration, 2)
+	start := time.Now()
+	// 20 ticks is more than two turns of the wheel.
+	w.AfterFunc(20*time.Millisecond, func() { fired <- time.Since(start) })
+	w.AfterFunc(3*time.Millisecond, func() { fired <- time.Since(start) })
+
+	if d := <-fired; d >= 20*time.Millisecond {
+		t.Fatalf("short timer fired after %v", d)
+	}
+	if d := <-fired; d < 20*time.Millisecond {
+		t.Fatalf("long timer fired early, after %v", d)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if w.Len() != 0 {
+		t.Fatalf("Len = %d after firing", w.Len())
+	}
+}
+
+func TestStopReset(t *testing.T) {
+	w := New(time.Millisecond, 16)
+	var fired atomic.Int32
+	a := w.AfterFunc(5*time.Millisecond, func() { fired.Add(1) })
+	b := w.AfterFunc(5*time.Millisecond, func() { fired.Add(10) })
+	if !a.Stop() || a.Stop() {
+		t.Fatal("Stop of a pending timer")
+	}
+	if !b.Reset(30 * time.Millisecond) {
+		t.Fatal("Reset of a pending timer reported it idle")
+	}
+	time.Sleep(15 * time.Millisecond)
+	if n := fired.Load(); n != 0 {
+		t.Fatalf("fired %d after Stop/Reset", n)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if n := fired.Load(); n != 10 {
+		t.Fatalf("fired %d, want only the reset timer", n)
+	}
+	// A fired timer can be rescheduled, restarting the idle wheel.
+	if b.Reset(time.Millisecond) {
+		t.Fatal("Reset of a fired timer reported it pending")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := fired.Load(); n != 20 {
+		t.Fatalf("fired %d, want 20", n)
+	}
+}
+
+func BenchmarkAfterFuncStop(b *testing.B) {
+	w := New(time.Second, 4096)
+	for i := 0; i < b.N; i++ {
+		w.AfterFunc(time.Duration(i%3600)*time.Second, func() {}).Stop()
+	}
+}
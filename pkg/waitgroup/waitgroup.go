@@ -0,0 +1,72 @@
+// Package waitgroup provides a sync.WaitGroup whose Wait honors a context.
+package waitgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// WaitGroup waits for a collection of goroutines to finish, like
+// sync.WaitGroup, but Wait gives up when its context is done and the counter
+// can be read. Unlike sync.WaitGroup, Add may race with Wait freely.
+// The zero value is ready to use.
+type WaitGroup struct {
+	mu   sync.Mutex
+	n    int
+	zero chan struct{} // closed while n is zero; nil means closed
+}
+
+// Add adds delta, which may be negative, to the counter. It panics if the
+// counter goes negative.
+func (wg *WaitGroup) Add(delta int) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	was := wg.n
+	wg.n += delta
+	switch {
+	case wg.n < 0:
+		wg.n = was
+		panic("waitgroup: negative counter")
+	case was == 0 && wg.n > 0:
+		wg.zero = make(chan struct{})
+	case was > 0 && wg.n == 0:
+		close(wg.zero)
+		wg.zero = nil
+	}
+}
+
+// Done decrements the counter by one.
+func (wg *WaitGroup) Done() { wg.Add(-1) }
+
+// Go runs f in a new goroutine, counted in the group until it returns.
+func (wg *WaitGroup) Go(f func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+// Wait blocks until the counter is zero or ctx is done, returning ctx.Err()
+// in the latter case.
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	wg.mu.Lock()
+	zero := wg.zero
+	wg.mu.Unlock()
+	if zero == nil {
+		return nil
+	}
+	select {
+	case <-zero:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Count returns the current counter.
+func (wg *WaitGroup) Count() int {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	return wg.n
+}
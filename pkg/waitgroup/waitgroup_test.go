@@ -0,0 +1,57 @@
+package waitgroup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	var wg WaitGroup
+	if err := wg.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait on empty group = %v", err)
+	}
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		wg.Go(func() { <-release })
+	}
+	if wg.Count() != 3 {
+		t.Fatalf("Count = %d, want 3", wg.Count())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := wg.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait with running tasks = %v", err)
+	}
+
+	close(release)
+	if err := wg.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if wg.Count() != 0 {
+		t.Fatalf("Count = %d, want 0", wg.Count())
+	}
+
+	// The group can be reused.
+	wg.Add(1)
+	go wg.Done()
+	if err := wg.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNegative(t *testing.T) {
+	var wg WaitGroup
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Done on empty group did not panic")
+		}
+		if wg.Count() != 0 {
+			t.Fatalf("Count = %d after panic", wg.Count())
+		}
+	}()
+	wg.Done()
+}
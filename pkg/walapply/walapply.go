@@ -0,0 +1,172 @@
+// Package walapply applies write-ahead-log records from concurrent producers
+// strictly in log sequence number (LSN) order.
+package walapply
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sawdustofmind/adv-sync/pkg/notify"
+)
+
+// ErrDuplicate is returned by Submit for a record at an LSN already buffered.
+var ErrDuplicate = errors.New("walapply: duplicate LSN")
+
+// Record is a log record.
+type Record[T any] struct {
+	LSN uint64
+	// Size is how far the record advances the LSN, for logs addressed by
+	// byte offset; zero means 1, for logs numbering records consecutively.
+	Size  uint64
+	Value T
+}
+
+func (r Record[T]) next() uint64 {
+	if r.Size == 0 {
+		return r.LSN + 1
+	}
+	return r.LSN + r.Size
+}
+
+// Config configures an Applier.
+type Config struct {
+	// Start is the first LSN to apply, typically the watermark saved before
+	// a restart. Records below it are taken as already applied.
+	Start uint64
+	// Window bounds how far ahead of the watermark a record may be
+	// submitted; Submit waits for records beyond it. Zero means no bound.
+	Window uint64
+	// OnWatermark, if set, is called with the new watermark after each
+	// record applied, in order, from the applying goroutine.
+	OnWatermark func(lsn uint64)
+}
+
+// Applier buffers records that arrive early and applies each as soon as
+// every record before it has been applied.
+type Applier[T any] struct {
+	cfg   Config
+	apply func(Record[T]) error
+
+	mu       sync.Mutex
+	next     uint64 // watermark: every LSN below it is applied
+	buf      map[uint64]Record[T]
+	applying bool
+	err      error
+	progress notify.Notifier
+}
+
+// New returns an applier calling apply for each record in LSN order. If
+// apply fails, nothing more is applied and every later call returns the error.
+func New[T any](cfg Config, apply func(Record[T]) error) *Applier[T] {
+	return &Applier[T]{cfg: cfg, apply: apply, next: cfg.Start, buf: make(map[uint64]Record[T])}
+}
+
+// Submit hands r over for applying, waiting while it is beyond the window
+// until the watermark catches up or ctx is done. The caller whose record
+// fills a gap applies it, along with every buffered record it unblocks.
+// A record below the watermark is ignored, so a log can be replayed from
+// before Start.
+func (a *Applier[T]) Submit(ctx context.Context, r Record[T]) error {
+	a.mu.Lock()
+	for a.err == nil && a.cfg.Window > 0 && r.LSN >= a.next+a.cfg.Window {
+		version := a.progress.Version()
+		a.mu.Unlock()
+		if _, err := a.progress.Wait(ctx, version); err != nil {
+			return err
+		}
+		a.mu.Lock()
+	}
+	switch {
+	case a.err != nil:
+		err := a.err
+		a.mu.Unlock()
+		return err
+	case r.LSN < a.next:
+		a.mu.Unlock()
+		return nil
+	}
+	if _, ok := a.buf[r.LSN]; ok {
+		a.mu.Unlock()
+		return ErrDuplicate
+	}
+	a.buf[r.LSN] = r
+	if a.applying {
+		a.mu.Unlock()
+		return nil
+	}
+	a.applying = true
+	return a.drain()
+}
+
+// drain applies buffered records while the next one is present; mu must be
+// held, and is released.
+func (a *Applier[T]) drain() error {
+	for {
+		r, ok := a.buf[a.next]
+		if !ok || a.err != nil {
+			a.applying = false
+			err := a.err
+			a.mu.Unlock()
+			return err
+		}
+		delete(a.buf, a.next)
+		a.mu.Unlock()
+
+		err := a.apply(r)
+
+		a.mu.Lock()
+		if err != nil {
+			a.err = err
+			clear(a.buf)
+		} else {
+			a.next = r.next()
+		}
+		a.progress.Broadcast()
+		if err == nil && a.cfg.OnWatermark != nil {
+			// Only one goroutine drains at a time, so calls stay in order.
+			next := a.next
+			a.mu.Unlock()
+			a.cfg.OnWatermark(next)
+			a.mu.Lock()
+		}
+	}
+}
+
+// Watermark returns the LSN after the last record applied: every record
+// below it has been applied.
+func (a *Applier[T]) Watermark() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.next
+}
+
+// Buffered returns the number of records waiting for an earlier one.
+func (a *Applier[T]) Buffered() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.buf)
+}
+
+// Wait blocks until the watermark passes lsn, that is, the record at lsn and
+// everything before it is applied, or until applying fails or ctx is done.
+func (a *Applier[T]) Wait(ctx context.Context, lsn uint64) error {
+	a.mu.Lock()
+	for a.err == nil && a.next <= lsn {
+		version := a.progress.Version()
+		a.mu.Unlock()
+		if _, err := a.progress.Wait(ctx, version); err != nil {
+			return err
+		}
+		a.mu.Lock()
+	}
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// Err returns the error that stopped applying, if any.
+func (a *Applier[T]) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
@@ -0,0 +1,106 @@
+package walapply
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	var (
+		got   []uint64
+		marks []uint64
+	)
+	a := New(Config{Start: 100, OnWatermark: func(lsn uint64) { marks = append(marks, lsn) }},
+		func(r Record[int]) error {
+			got = append(got, r.LSN)
+			return nil
+		})
+
+	// Producers submit byte-addressed records of varying size concurrently.
+	var recs []Record[int]
+	for lsn := uint64(100); len(recs) < 200; {
+		size := uint64(1 + len(recs)%3)
+		recs = append(recs, Record[int]{LSN: lsn, Size: size})
+		lsn += size
+	}
+	rand.Shuffle(len(recs), func(i, j int) { recs[i], recs[j] = recs[j], recs[i] })
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(recs); i += 4 {
+				if err := a.Submit(context.Background(), recs[i]); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(got) != 200 || len(marks) != 200 {
+		t.Fatalf("applied %d records, %d watermarks", len(got), len(marks))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] || marks[i-1] != got[i] {
+			t.Fatalf("applied out of order at %d: %v", i, got)
+		}
+	}
+	end := marks[len(marks)-1]
+	if a.Watermark() != end || a.Buffered() != 0 {
+		t.Fatalf("watermark %d, buffered %d", a.Watermark(), a.Buffered())
+	}
+	// Replayed records below the watermark are ignored.
+	if err := a.Submit(context.Background(), Record[int]{LSN: 100}); err != nil || len(got) != 200 {
+		t.Fatalf("replayed record applied: %v", err)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	a := New(Config{Window: 4}, func(r Record[string]) error { return nil })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := a.Submit(ctx, Record[string]{LSN: 4}); err != context.DeadlineExceeded {
+		t.Fatalf("Submit beyond the window = %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- a.Submit(context.Background(), Record[string]{LSN: 4}) }()
+	go func() { done <- a.Wait(context.Background(), 4) }()
+	for lsn := uint64(0); lsn < 4; lsn++ {
+		a.Submit(context.Background(), Record[string]{LSN: lsn})
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Watermark() != 5 {
+		t.Fatalf("watermark %d, want 5", a.Watermark())
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	errBad := errors.New("corrupt record")
+	a := New(Config{}, func(r Record[int]) error {
+		if r.LSN == 1 {
+			return errBad
+		}
+		return nil
+	})
+	a.Submit(context.Background(), Record[int]{LSN: 2})
+	if err := a.Submit(context.Background(), Record[int]{LSN: 2}); err != ErrDuplicate {
+		t.Fatalf("duplicate Submit = %v", err)
+	}
+	a.Submit(context.Background(), Record[int]{LSN: 0})
+	if err := a.Submit(context.Background(), Record[int]{LSN: 1}); err != errBad {
+		t.Fatalf("Submit = %v", err)
+	}
+	if a.Watermark() != 1 || a.Wait(context.Background(), 5) != errBad || a.Buffered() != 0 {
+		t.Fatal("applying went on after an error")
+	}
+}